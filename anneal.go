@@ -0,0 +1,179 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Annealer is an experimental Solver using simulated annealing.  Each box is
+// filled with its missing values, then pairs of non-given cells within a box
+// are swapped to minimize the number of repeated values in rows and columns.
+// It is included for education and comparison rather than speed, and may
+// fail on puzzles backtracking solves easily.
+type Annealer struct {
+	// Temperature is the starting temperature, in units of conflicts
+	Temperature float64
+	// Cooling multiplies the temperature after every move
+	Cooling float64
+	// Iterations is the number of moves before restarting from a new fill
+	Iterations int
+	// Restarts is the number of fresh fills tried before giving up
+	Restarts int
+	// Seed for the random number generator, the current time if zero
+	Seed int64
+}
+
+// solverNames lists the names accepted by -algo
+func solverNames() string {
+	var names []string
+	for name := range solvers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// Solve completes g with simulated annealing.  The board is only changed if a
+// solution is found.
+func (a *Annealer) Solve(g *Game, stats *Stats) bool {
+	seed := a.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	rng := rand.New(rand.NewSource(seed))
+	for restart := 0; restart <= a.Restarts && !stats.TimedOut; restart++ {
+		if restart > 0 {
+			stats.Restarts++
+		}
+		grid := a.fill(g, rng)
+		if a.anneal(g, grid, rng, stats) {
+			for ri := range grid {
+				for ci, val := range grid[ri] {
+					g.MakeMove(ri, ci, val)
+				}
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// fill copies the board, filling each box with its missing values in a random
+// order
+func (a *Annealer) fill(g *Game, rng *rand.Rand) [][]int {
+	grid := make([][]int, DIM)
+	for ri := range grid {
+		grid[ri] = append([]int(nil), g.board[ri]...)
+	}
+	for b := 0; b < DIM; b++ {
+		box := units[b*3+2]
+		var present [DIM + 1]bool
+		for _, c := range box {
+			present[grid[c.Row][c.Col]] = true
+		}
+		var missing []int
+		for val := 1; val <= DIM; val++ {
+			if !present[val] {
+				missing = append(missing, val)
+			}
+		}
+		rng.Shuffle(len(missing), func(i, j int) { missing[i], missing[j] = missing[j], missing[i] })
+		for _, c := range box {
+			if grid[c.Row][c.Col] == 0 {
+				grid[c.Row][c.Col] = missing[0]
+				missing = missing[1:]
+			}
+		}
+	}
+	return grid
+}
+
+// rowConflicts counts the repeated values in a row of grid
+func rowConflicts(grid [][]int, row int) int {
+	var seen [DIM + 1]bool
+	result := 0
+	for _, val := range grid[row] {
+		if seen[val] {
+			result++
+		}
+		seen[val] = true
+	}
+	return result
+}
+
+// colConflicts counts the repeated values in a column of grid
+func colConflicts(grid [][]int, col int) int {
+	var seen [DIM + 1]bool
+	result := 0
+	for row := range grid {
+		val := grid[row][col]
+		if seen[val] {
+			result++
+		}
+		seen[val] = true
+	}
+	return result
+}
+
+// rowColConflicts counts the conflicts in the rows and columns of cells x and
+// y, counting a row or column they share once
+func rowColConflicts(grid [][]int, x, y Cell) int {
+	result := rowConflicts(grid, x.Row) + colConflicts(grid, x.Col)
+	if y.Row != x.Row {
+		result += rowConflicts(grid, y.Row)
+	}
+	if y.Col != x.Col {
+		result += colConflicts(grid, y.Col)
+	}
+	return result
+}
+
+// anneal swaps cells of grid until it has no conflicts, true if it succeeded
+// within the iteration limit
+func (a *Annealer) anneal(g *Game, grid [][]int, rng *rand.Rand, stats *Stats) bool {
+	// Cells which may be swapped, grouped by box
+	var free [DIM][]Cell
+	for b := 0; b < DIM; b++ {
+		for _, c := range units[b*3+2] {
+			if g.board[c.Row][c.Col] == 0 {
+				free[b] = append(free[b], c)
+			}
+		}
+	}
+	total := 0
+	for i := 0; i < DIM; i++ {
+		total += rowConflicts(grid, i) + colConflicts(grid, i)
+	}
+	temp := a.Temperature
+	for i := 0; i < a.Iterations; i++ {
+		if total == 0 {
+			return true
+		}
+		if stats.expired() {
+			return false
+		}
+		stats.Iterations++
+		cells := free[rng.Intn(DIM)]
+		if len(cells) < 2 {
+			continue
+		}
+		x := cells[rng.Intn(len(cells))]
+		y := cells[rng.Intn(len(cells))]
+		if x == y {
+			continue
+		}
+		before := rowColConflicts(grid, x, y)
+		grid[x.Row][x.Col], grid[y.Row][y.Col] = grid[y.Row][y.Col], grid[x.Row][x.Col]
+		delta := rowColConflicts(grid, x, y) - before
+		if delta <= 0 || rng.Float64() < math.Exp(-float64(delta)/temp) {
+			total += delta
+		} else {
+			grid[x.Row][x.Col], grid[y.Row][y.Col] = grid[y.Row][y.Col], grid[x.Row][x.Col]
+		}
+		temp *= a.Cooling
+	}
+	return total == 0
+}
@@ -0,0 +1,511 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Stats measures the work done to solve a single puzzle
+type Stats struct {
+	Backtracks int `json:"backtracks"`
+	// Iterations and Restarts count the moves tried by stochastic solvers
+	Iterations int `json:"iterations,omitempty"`
+	Restarts   int `json:"restarts,omitempty"`
+	// Steps and Techniques count the deductions made by the strategy engine
+	Steps      int            `json:"steps"`
+	Techniques map[string]int `json:"techniques"`
+	// FirstUse is the 1 based step at which each of Techniques was first
+	// used
+	FirstUse map[string]int `json:"first_use,omitempty"`
+	Millis   float64        `json:"millis"`
+	// Deadline stops the solver early when set, marking the puzzle TimedOut
+	Deadline time.Time `json:"-"`
+	TimedOut bool      `json:"timed_out,omitempty"`
+	checks   int
+}
+
+// expiredCheckInterval is how many calls to expired pass between reading the
+// clock
+const expiredCheckInterval = 1024
+
+// expired is true once the deadline has passed, for solvers to poll
+func (s *Stats) expired() bool {
+	if s == nil || s.Deadline.IsZero() {
+		return false
+	}
+	if !s.TimedOut {
+		s.checks++
+		if s.checks%expiredCheckInterval == 0 && time.Now().After(s.Deadline) {
+			s.TimedOut = true
+		}
+	}
+	return s.TimedOut
+}
+
+// Result records the outcome of solving one puzzle of a batch
+type Result struct {
+	// Line is the 1 based line number of the puzzle in the batch file
+	Line     int    `json:"line"`
+	Input    string `json:"input"`
+	Solution string `json:"solution,omitempty"`
+	// Checksum is the Checksum of the solution
+	Checksum   string `json:"checksum,omitempty"`
+	Solved     bool   `json:"solved"`
+	Difficulty string `json:"difficulty"`
+	Stats      Stats  `json:"stats"`
+}
+
+// solvePuzzle grades a copy of the board with the strategy engine, and then
+// completes the board itself with solver, measuring the work done.  The solver
+// gives up after timeout unless it is zero.
+func solvePuzzle(g *Game, solver Solver, timeout time.Duration) Result {
+	start := time.Now()
+	r := Result{Input: g.Line(), Stats: Stats{Techniques: make(map[string]int), FirstUse: make(map[string]int)}}
+	s := NewStrategist(g.Clone())
+	s.Solve()
+	r.Difficulty = s.Difficulty().String()
+	if timeout > 0 {
+		r.Stats.Deadline = time.Now().Add(timeout)
+	}
+	r.Solved = solver.Solve(g, &r.Stats)
+	if r.Solved {
+		r.Solution = g.Line()
+		r.Checksum = g.Checksum()
+	}
+	r.Stats.Millis = float64(time.Since(start).Microseconds()) / 1000
+	r.Stats.Steps = len(s.Steps)
+	for i, step := range s.Steps {
+		if r.Stats.Techniques[step.Technique] == 0 {
+			r.Stats.FirstUse[step.Technique] = i + 1
+		}
+		r.Stats.Techniques[step.Technique]++
+	}
+	return r
+}
+
+// readResults reads the results of a JSON Lines report, calling fn with
+// each in turn
+func readResults(r io.Reader, fn func(Result)) error {
+	dec := json.NewDecoder(r)
+	for {
+		var result Result
+		err := dec.Decode(&result)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fn(result)
+	}
+}
+
+// csvHeader lists the columns written by -stats-csv, with one count column
+// per technique
+func csvHeader() []string {
+	header := []string{"line", "millis", "backtracks", "steps"}
+	header = append(header, techniqueIDs()...)
+	return append(header, "difficulty")
+}
+
+// csvRecord formats the statistics of r to match csvHeader
+func csvRecord(r Result) []string {
+	record := []string{
+		strconv.Itoa(r.Line),
+		strconv.FormatFloat(r.Stats.Millis, 'f', 3, 64),
+		strconv.Itoa(r.Stats.Backtracks),
+		strconv.Itoa(r.Stats.Steps),
+	}
+	for _, t := range techniques {
+		record = append(record, strconv.Itoa(r.Stats.Techniques[t.name]))
+	}
+	return append(record, r.Difficulty)
+}
+
+// backtrackBuckets are the lower bounds of each backtrack histogram bucket
+var backtrackBuckets = []int{0, 1, 10, 100, 1000, 10000}
+
+// techniqueTally accumulates how a batch used one technique
+type techniqueTally struct {
+	// puzzles is the number of puzzles needing the technique and uses the
+	// number of steps taken with it
+	puzzles, uses int
+	// firsts counts the puzzles whose first use is known, firstStep and
+	// firstFraction sum the step of that use and its fraction of the steps
+	firsts        int
+	firstStep     int
+	firstFraction float64
+}
+
+// summary accumulates the distribution of results over a batch
+type summary struct {
+	total, solved int
+	// timedOut lists the lines of puzzles which ran out of time
+	timedOut   []int
+	grades     map[string]int
+	backtracks []int
+	techniques map[string]*techniqueTally
+}
+
+func newSummary() *summary {
+	return &summary{grades: make(map[string]int), backtracks: make([]int, len(backtrackBuckets)),
+		techniques: make(map[string]*techniqueTally)}
+}
+
+func (s *summary) add(r Result) {
+	s.total++
+	if r.Solved {
+		s.solved++
+	}
+	if r.Stats.TimedOut {
+		s.timedOut = append(s.timedOut, r.Line)
+	}
+	s.grades[r.Difficulty]++
+	for i := len(backtrackBuckets) - 1; i >= 0; i-- {
+		if r.Stats.Backtracks >= backtrackBuckets[i] {
+			s.backtracks[i]++
+			break
+		}
+	}
+	for name, uses := range r.Stats.Techniques {
+		t := s.techniques[name]
+		if t == nil {
+			t = &techniqueTally{}
+			s.techniques[name] = t
+		}
+		t.puzzles++
+		t.uses += uses
+		if first, ok := r.Stats.FirstUse[name]; ok && r.Stats.Steps > 0 {
+			t.firsts++
+			t.firstStep += first
+			t.firstFraction += float64(first) / float64(r.Stats.Steps)
+		}
+	}
+}
+
+// techniqueHeader lists the columns of the technique breakdown written by
+// -technique-csv
+var techniqueHeader = []string{"technique", "puzzles", "percent", "uses", "mean_first_step", "mean_first_percent"}
+
+// techniqueRows formats the technique breakdown to match techniqueHeader, a
+// row for each technique of the catalog in order, easiest first
+func (s *summary) techniqueRows() [][]string {
+	var rows [][]string
+	for _, tech := range techniques {
+		t := s.techniques[tech.name]
+		if t == nil {
+			t = &techniqueTally{}
+		}
+		percent := 0.0
+		if s.total > 0 {
+			percent = 100 * float64(t.puzzles) / float64(s.total)
+		}
+		// The first use columns are empty for techniques never used
+		firstStep, firstPercent := "", ""
+		if t.firsts > 0 {
+			firstStep = strconv.FormatFloat(float64(t.firstStep)/float64(t.firsts), 'f', 1, 64)
+			firstPercent = strconv.FormatFloat(100*t.firstFraction/float64(t.firsts), 'f', 1, 64)
+		}
+		rows = append(rows, []string{
+			tech.id,
+			strconv.Itoa(t.puzzles),
+			strconv.FormatFloat(percent, 'f', 1, 64),
+			strconv.Itoa(t.uses),
+			firstStep,
+			firstPercent,
+		})
+	}
+	return rows
+}
+
+// writeTechniqueCSV writes the technique breakdown as CSV
+func (s *summary) writeTechniqueCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write(techniqueHeader)
+	cw.WriteAll(s.techniqueRows())
+	return cw.Error()
+}
+
+// histogramWidth is the length of the longest histogram bar
+const histogramWidth = 40
+
+// String formats the summary with histograms of grades and backtracks
+func (s *summary) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Solved %v of %v puzzles\n", s.solved, s.total)
+	if len(s.timedOut) > 0 {
+		fmt.Fprintf(&sb, "Timed out on %v puzzles, lines: %v\n",
+			len(s.timedOut), strings.Trim(fmt.Sprint(s.timedOut), "[]"))
+	}
+	sb.WriteString("\nDifficulty:\n")
+	for d := Easy; d <= RequiresGuessing; d++ {
+		writeBar(&sb, d.String(), s.grades[d.String()], s.total)
+	}
+	sb.WriteString("\nBacktracks:\n")
+	for i, low := range backtrackBuckets {
+		label := fmt.Sprintf("%v+", low)
+		if i+1 < len(backtrackBuckets) {
+			if high := backtrackBuckets[i+1] - 1; high == low {
+				label = fmt.Sprint(low)
+			} else {
+				label = fmt.Sprintf("%v-%v", low, high)
+			}
+		}
+		writeBar(&sb, label, s.backtracks[i], s.total)
+	}
+	// The first step columns show how far into the solve each technique
+	// first becomes necessary
+	sb.WriteString("\nTechniques:\n")
+	fmt.Fprintf(&sb, "  %-18v %7v %7v %7v %11v %9v\n", "", "puzzles", "percent", "uses", "first step", "first at")
+	for _, row := range s.techniqueRows() {
+		firstStep, firstPercent := "-", "-"
+		if row[4] != "" {
+			firstStep, firstPercent = row[4], row[5]+"%"
+		}
+		fmt.Fprintf(&sb, "  %-18v %7v %6v%% %7v %11v %9v\n", row[0], row[1], row[2], row[3], firstStep, firstPercent)
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+func writeBar(sb *strings.Builder, label string, count, total int) {
+	width := 0
+	if total > 0 {
+		width = count * histogramWidth / total
+	}
+	bar := fmt.Sprintf("  %-18v %6v %v", label, count, strings.Repeat("#", width))
+	sb.WriteString(strings.TrimRight(bar, " ") + "\n")
+}
+
+// batchCommand handles: batch [flags] <puzzle file>, solving every puzzle of a
+// file as read by Decoder
+func batchCommand(args []string) error {
+	fs := flag.NewFlagSet("batch", flag.ExitOnError)
+	report := fs.String("report", "", "write a JSON Lines report of each puzzle to `file`")
+	statsCSV := fs.String("stats-csv", "", "write a CSV row of statistics for each puzzle to `file`")
+	techniqueCSV := fs.String("technique-csv", "", "write the breakdown of the techniques used by the batch as CSV to `file`")
+	algo := fs.String("algo", "backtrack", "solving algorithm: "+solverNames())
+	verify := fs.Bool("verify", false, "cross check each puzzle with two independent solvers, stopping if they disagree")
+	timeout := fs.Duration("puzzle-timeout", 0, "give up on a puzzle after `duration`, such as 5s, 0 for no limit")
+	progress := fs.Bool("progress", true, "show a progress bar when stderr is a terminal")
+	tag := fs.String("tag", "", "only solve puzzles matching the comma separated tag `filters`, see query")
+	stateFile := fs.String("state", "", "save progress to `file` so an interrupted run can be resumed, "+
+		"by default the -report file with .state appended")
+	resume := fs.Bool("resume", false, "continue the run saved by -state, appending to its report and CSV")
+	shardFlag := fs.String("shard", "", "only solve shard `i/n` of the input, every nth puzzle starting from the ith, "+
+		"for splitting a batch across machines, see merge-reports")
+	fs.Parse(args)
+	solver, ok := solvers[*algo]
+	if !ok {
+		return fmt.Errorf("Unknown algorithm %q", *algo)
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("Usage: batch [flags] <puzzle file>")
+	}
+	part, err := parseShard(*shardFlag)
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if *stateFile == "" && *report != "" {
+		*stateFile = *report + ".state"
+	}
+	state := &batchState{Input: fs.Arg(0), Shard: *shardFlag}
+	if *resume {
+		if *stateFile == "" {
+			return fmt.Errorf("-resume needs the -state or -report of the run")
+		}
+		if state, err = readBatchState(*stateFile); err != nil {
+			return err
+		}
+		if state.Input != fs.Arg(0) {
+			return fmt.Errorf("%v is the state of a batch of %v, not %v", *stateFile, state.Input, fs.Arg(0))
+		}
+		if state.Shard != *shardFlag {
+			return fmt.Errorf("%v is the state of shard %q, not %q", *stateFile, state.Shard, *shardFlag)
+		}
+	}
+
+	sum := newSummary()
+	var enc *json.Encoder
+	var reportOut *os.File
+	var reportBuf *bufio.Writer
+	if *report != "" {
+		if reportOut, err = openOutput(*report, *resume, state.Report); err != nil {
+			return err
+		}
+		defer reportOut.Close()
+		// The summary covers the results from before the interruption too
+		if *resume {
+			if err := readResults(io.NewSectionReader(reportOut, 0, state.Report), sum.add); err != nil {
+				return fmt.Errorf("%v: %v", *report, err)
+			}
+		}
+		reportBuf = bufio.NewWriter(reportOut)
+		defer reportBuf.Flush()
+		enc = json.NewEncoder(reportBuf)
+	}
+	var stats *csv.Writer
+	var statsOut *os.File
+	if *statsCSV != "" {
+		if statsOut, err = openOutput(*statsCSV, *resume, state.StatsCSV); err != nil {
+			return err
+		}
+		defer statsOut.Close()
+		stats = csv.NewWriter(statsOut)
+		defer stats.Flush()
+		if !*resume {
+			if err := stats.Write(csvHeader()); err != nil {
+				return err
+			}
+		}
+	}
+
+	// checkpoint saves the state with the outputs flushed to match it
+	checkpoint := func() error {
+		if *stateFile == "" {
+			return nil
+		}
+		if reportBuf != nil {
+			if err := reportBuf.Flush(); err != nil {
+				return err
+			}
+			if state.Report, err = reportOut.Seek(0, io.SeekCurrent); err != nil {
+				return err
+			}
+		}
+		if stats != nil {
+			if stats.Flush(); stats.Error() != nil {
+				return stats.Error()
+			}
+			if state.StatsCSV, err = statsOut.Seek(0, io.SeekCurrent); err != nil {
+				return err
+			}
+		}
+		return state.save(*stateFile)
+	}
+	if err := checkpoint(); err != nil {
+		return err
+	}
+	// With a state to save, an interrupt stops after the current puzzle
+	// rather than killing the run.  The default handler is restored as soon
+	// as it arrives, so a second one kills the run even in a slow puzzle.
+	var interrupted atomic.Bool
+	if *stateFile != "" {
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt)
+		defer signal.Stop(interrupt)
+		go func() {
+			<-interrupt
+			signal.Stop(interrupt)
+			interrupted.Store(true)
+		}()
+	}
+
+	var bar *progressBar
+	if info, err := file.Stat(); err == nil && *progress {
+		bar = newProgressBar(info.Size())
+	}
+	// The bar shares the terminal with the results unless they're redirected
+	shared := bar != nil && isTerminal(os.Stdout)
+	defer bar.clear()
+	input := &countingReader{r: file}
+
+	dec := NewDecoder(input)
+	for unsaved, index := 0, 0; ; index++ {
+		board, err := dec.Next()
+		if err == io.EOF {
+			break
+		}
+		lineNum := dec.Line()
+		if err != nil {
+			return fmt.Errorf("Line %v: %v", lineNum, err)
+		}
+		if lineNum <= state.Line || !part.has(index) {
+			continue
+		}
+		state.Line = lineNum
+		if *tag != "" {
+			source := fmt.Sprintf("%v:%v", fs.Arg(0), lineNum)
+			if !gradeFacets(board, source, dec.Metadata()).matchesAll(*tag) {
+				continue
+			}
+		}
+		if *verify {
+			if err := Verify(board); err != nil {
+				return fmt.Errorf("Line %v: %v", lineNum, err)
+			}
+		}
+		r := solvePuzzle(board, solver, *timeout)
+		r.Line = lineNum
+		sum.add(r)
+		if shared {
+			bar.clear()
+		}
+		if r.Stats.TimedOut {
+			fmt.Printf("%v: timed out, difficulty: %v, backtracks: %v\n",
+				lineNum, r.Difficulty, r.Stats.Backtracks)
+		} else {
+			fmt.Printf("%v: solved? %v, difficulty: %v, backtracks: %v\n",
+				lineNum, r.Solved, r.Difficulty, r.Stats.Backtracks)
+		}
+		bar.update(sum.total, input.n)
+		if enc != nil {
+			if err := enc.Encode(r); err != nil {
+				return err
+			}
+		}
+		if stats != nil {
+			if err := stats.Write(csvRecord(r)); err != nil {
+				return err
+			}
+		}
+		if interrupted.Load() {
+			if err := checkpoint(); err != nil {
+				return err
+			}
+			bar.clear()
+			fmt.Printf("\nInterrupted after line %v, continue with -resume\n\n%v\n", lineNum, sum)
+			return nil
+		}
+		if unsaved++; unsaved == batchStateInterval {
+			if err := checkpoint(); err != nil {
+				return err
+			}
+			unsaved = 0
+		}
+	}
+	bar.clear()
+	if *stateFile != "" {
+		if err := os.Remove(*stateFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	fmt.Printf("\n%v\n", sum)
+	if *techniqueCSV != "" {
+		out, err := os.Create(*techniqueCSV)
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+		if err := sum.writeTechniqueCSV(out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
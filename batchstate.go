@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// batchStateInterval is how many puzzles a batch solves between saving its
+// state, bounding the work repeated after a crash
+const batchStateInterval = 100
+
+// batchState is the progress of a batch saved by -state, so an interrupted
+// run can be resumed where it stopped
+type batchState struct {
+	Input string `json:"input"`
+	// Shard is the -shard of the run, empty for the whole input
+	Shard string `json:"shard,omitempty"`
+	// Line is the last line of the input finished
+	Line int `json:"line"`
+	// Report and StatsCSV are the sizes of the output files when Line was
+	// finished, resuming cuts them back to drop any later results
+	Report   int64 `json:"report"`
+	StatsCSV int64 `json:"stats_csv"`
+}
+
+// readBatchState reads the state saved in the file called name
+func readBatchState(name string) (*batchState, error) {
+	data, err := os.ReadFile(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("No batch to resume, %v doesn't exist", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s := &batchState{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("Invalid batch state %v: %v", name, err)
+	}
+	return s, nil
+}
+
+// save writes the state to the file called name, replacing it in one step
+// so that a crash while saving leaves the previous state
+func (s *batchState) save(name string) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	tmp := name + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, name)
+}
+
+// openOutput creates the output file called name, or when resuming opens it
+// cut back to size and positioned at its end
+func openOutput(name string, resume bool, size int64) (*os.File, error) {
+	if !resume {
+		return os.Create(name)
+	}
+	f, err := os.OpenFile(name, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.Truncate(size); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if _, err := f.Seek(size, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
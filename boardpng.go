@@ -0,0 +1,75 @@
+package main
+
+import (
+	"image"
+	"image/draw"
+	"image/png"
+	"io"
+)
+
+// digitGlyphs are 5x7 bitmaps of the digits 1 through 9, so boards can be
+// drawn without a font
+var digitGlyphs = [DIM][7]string{
+	{"..#..", ".##..", "..#..", "..#..", "..#..", "..#..", ".###."},
+	{".###.", "#...#", "....#", "...#.", "..#..", ".#...", "#####"},
+	{"#####", "...#.", "..#..", "...#.", "....#", "#...#", ".###."},
+	{"...#.", "..##.", ".#.#.", "#..#.", "#####", "...#.", "...#."},
+	{"#####", "#....", "####.", "....#", "....#", "#...#", ".###."},
+	{"..##.", ".#...", "#....", "####.", "#...#", "#...#", ".###."},
+	{"#####", "....#", "...#.", "..#..", ".#...", ".#...", ".#..."},
+	{".###.", "#...#", "#...#", ".###.", "#...#", "#...#", ".###."},
+	{".###.", "#...#", "#...#", ".####", "....#", "...#.", ".##.."},
+}
+
+// pngMargin is the space around PNG boards in pixels
+const pngMargin = 8
+
+// WriteBoardPNG renders the board as a PNG image in style, with the givens
+// colored style.Given and other values style.Filled
+func WriteBoardPNG(w io.Writer, g *Game, style BoardStyle) error {
+	cell := style.Cell
+	// scale is the size of each dot of a digit glyph
+	scale := cell / 12
+	size := DIM*cell + 2*pngMargin + 1
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(img, img.Bounds(), image.NewUniform(style.Background), image.Point{}, draw.Src)
+	for i := 0; i <= DIM; i++ {
+		width := style.Line
+		if i%3 == 0 {
+			width = style.BoxLine
+		}
+		pos := pngMargin + i*cell - width/2
+		for t := 0; t < width; t++ {
+			for j := pngMargin - style.BoxLine/2; j <= pngMargin+DIM*cell+style.BoxLine/2; j++ {
+				img.SetRGBA(pos+t, j, style.Grid)
+				img.SetRGBA(j, pos+t, style.Grid)
+			}
+		}
+	}
+	for ri, row := range g.board {
+		for ci, val := range row {
+			if val == 0 {
+				continue
+			}
+			ink := style.Filled
+			if g.given[ri][ci] {
+				ink = style.Given
+			}
+			left := pngMargin + ci*cell + (cell-5*scale)/2
+			top := pngMargin + ri*cell + (cell-7*scale)/2
+			for gy, line := range digitGlyphs[val-1] {
+				for gx, dot := range line {
+					if dot != '#' {
+						continue
+					}
+					for y := 0; y < scale; y++ {
+						for x := 0; x < scale; x++ {
+							img.SetRGBA(left+gx*scale+x, top+gy*scale+y, ink)
+						}
+					}
+				}
+			}
+		}
+	}
+	return png.Encode(w, img)
+}
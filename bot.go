@@ -0,0 +1,258 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// slackMaxSkew is how old a signed Slack request may be, to stop replays
+const slackMaxSkew = 5 * time.Minute
+
+// botSolveTimeout keeps solving within the few seconds chat services wait for
+// a reply
+const botSolveTimeout = 2 * time.Second
+
+// botUsage is the reply to help or an unknown command
+const botUsage = "Usage: /sudoku solve <81 digits>, /sudoku hint <81 digits>, " +
+	"/sudoku daily [difficulty], or /sudoku generate"
+
+// bot answers slash commands from chat services
+type bot struct {
+	pack *Pack
+	// base prefixes share links in replies, if set
+	base        string
+	slackSecret string
+	discordKey  ed25519.PublicKey
+	difficulty  Difficulty
+}
+
+// reply runs a slash command such as "solve 4050...", returning the message
+// to post back
+func (b *bot) reply(text string) string {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return botUsage
+	}
+	rest := strings.Join(fields[1:], " ")
+	switch strings.ToLower(fields[0]) {
+	case "solve", "hint":
+		// Puzzles may be split into rows by spaces
+		g, err := ParseLine(strings.Join(fields[1:], ""))
+		if err != nil {
+			return fmt.Sprintf("Invalid puzzle: %v", err)
+		}
+		if strings.ToLower(fields[0]) == "hint" {
+			if step := Hint(g); step != nil {
+				return step.String()
+			}
+			return "The strategy engine is stuck, no hint found"
+		}
+		r := solvePuzzle(g, solvers["backtrack"], botSolveTimeout)
+		switch {
+		case r.Stats.TimedOut:
+			return "Gave up solving the puzzle, it took too long"
+		case !r.Solved:
+			return "Puzzle has no solution"
+		}
+		return fmt.Sprintf("```\n%v```\nDifficulty: %v, Checksum: %v", g.GridString(), r.Difficulty, r.Checksum)
+	case "daily":
+		d := b.difficulty
+		if rest != "" {
+			var err error
+			if d, err = ParseDifficulty(rest); err != nil {
+				return err.Error()
+			}
+		}
+		p, err := newDailyPost(b.pack, d, time.Now().UTC().Format(time.DateOnly), b.base, activeBoardStyle())
+		if err != nil {
+			return err.Error()
+		}
+		return p.text
+	case "generate":
+		g, err := Generate(time.Now().UnixNano(), "rotational", 0)
+		if err != nil {
+			return err.Error()
+		}
+		text := fmt.Sprintf("```\n%v```", g.GridString())
+		if b.base != "" {
+			text += fmt.Sprintf("\n%v#%v", b.base, EncodeShare(g))
+		}
+		return text
+	}
+	return botUsage
+}
+
+// readBody reads a request body of up to maxRequestBytes
+func readBody(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	return io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+}
+
+// verifySlack checks the signature Slack makes of each request with the
+// app's signing secret
+func (b *bot) verifySlack(r *http.Request, body []byte) error {
+	ts := r.Header.Get("X-Slack-Request-Timestamp")
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return fmt.Errorf("Missing Slack timestamp")
+	}
+	if skew := time.Since(time.Unix(sec, 0)); skew > slackMaxSkew || skew < -slackMaxSkew {
+		return fmt.Errorf("Slack timestamp is too old")
+	}
+	mac := hmac.New(sha256.New, []byte(b.slackSecret))
+	fmt.Fprintf(mac, "v0:%v:%s", ts, body)
+	want := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(want), []byte(r.Header.Get("X-Slack-Signature"))) {
+		return fmt.Errorf("Invalid Slack signature")
+	}
+	return nil
+}
+
+// handleSlack serves POST /slack, the request URL of a Slack slash command
+func (b *bot) handleSlack(w http.ResponseWriter, r *http.Request) {
+	body, err := readBody(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if b.slackSecret != "" {
+		if err := b.verifySlack(r, body); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	slog.Debug("slack command", "user", form.Get("user_name"), "text", form.Get("text"))
+	writeJSON(w, map[string]string{"response_type": "in_channel", "text": b.reply(form.Get("text"))})
+}
+
+// discordInteraction is the part of a Discord interaction the bot reads
+type discordInteraction struct {
+	Type int `json:"type"`
+	Data struct {
+		Options []discordOption `json:"options"`
+	} `json:"data"`
+}
+
+// discordOption is a subcommand or argument of a Discord slash command
+type discordOption struct {
+	Name    string          `json:"name"`
+	Value   json.RawMessage `json:"value"`
+	Options []discordOption `json:"options"`
+}
+
+// Discord interaction and response types
+const (
+	discordPing           = 1
+	discordCommand        = 2
+	discordPong           = 1
+	discordChannelMessage = 4
+)
+
+// commandText flattens the options of a /sudoku command, such as the solve
+// subcommand and its puzzle argument, into the text of a Slack command
+func commandText(options []discordOption) string {
+	var parts []string
+	for _, o := range options {
+		if o.Value == nil {
+			parts = append(parts, o.Name, commandText(o.Options))
+			continue
+		}
+		var s string
+		if json.Unmarshal(o.Value, &s) != nil {
+			s = string(o.Value)
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, " ")
+}
+
+// handleDiscord serves POST /discord, the interactions endpoint of a Discord
+// application, which must verify every request with the application's public
+// key
+func (b *bot) handleDiscord(w http.ResponseWriter, r *http.Request) {
+	body, err := readBody(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	sig, err := hex.DecodeString(r.Header.Get("X-Signature-Ed25519"))
+	msg := append([]byte(r.Header.Get("X-Signature-Timestamp")), body...)
+	if err != nil || !ed25519.Verify(b.discordKey, msg, sig) {
+		http.Error(w, "Invalid Discord signature", http.StatusUnauthorized)
+		return
+	}
+	var in discordInteraction
+	if err := json.Unmarshal(body, &in); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid interaction: %v", err), http.StatusBadRequest)
+		return
+	}
+	switch in.Type {
+	case discordPing:
+		writeJSON(w, map[string]int{"type": discordPong})
+	case discordCommand:
+		text := commandText(in.Data.Options)
+		slog.Debug("discord command", "text", text)
+		writeJSON(w, map[string]interface{}{
+			"type": discordChannelMessage,
+			"data": map[string]string{"content": b.reply(text)},
+		})
+	default:
+		http.Error(w, fmt.Sprintf("Unsupported interaction type %v", in.Type), http.StatusBadRequest)
+	}
+}
+
+// botCommand handles: bot [flags], answering /sudoku slash commands from
+// Slack and Discord over HTTP
+func botCommand(args []string) error {
+	fs := flag.NewFlagSet("bot", flag.ExitOnError)
+	addr := fs.String("addr", ":8081", "listen on `address`")
+	packFile := fs.String("pack", "pack.json", "serve the daily puzzle from the pack in `file`")
+	daily := fs.String("daily-difficulty", Medium.String(), "difficulty of the daily puzzle")
+	base := fs.String("url", "", "add share links prefixed with `url` to puzzles")
+	slackSecret := fs.String("slack-secret", "", "verify Slack requests with the signing `secret`")
+	discordKey := fs.String("discord-key", "", "enable Discord with the application's public `key` in hex")
+	fs.Parse(args)
+	if fs.NArg() != 0 {
+		return fmt.Errorf("Usage: bot [flags]")
+	}
+	d, err := ParseDifficulty(*daily)
+	if err != nil {
+		return err
+	}
+	pack, err := readPack(*packFile)
+	if err != nil {
+		return err
+	}
+	b := &bot{pack: pack, base: *base, slackSecret: *slackSecret, difficulty: d}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/slack", onlyMethod(http.MethodPost, b.handleSlack))
+	if *slackSecret == "" {
+		slog.Warn("Slack requests are not verified without -slack-secret")
+	}
+	if *discordKey != "" {
+		key, err := hex.DecodeString(*discordKey)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return fmt.Errorf("Invalid Discord public key, expected %v hex digits", 2*ed25519.PublicKeySize)
+		}
+		b.discordKey = key
+		mux.HandleFunc("/discord", onlyMethod(http.MethodPost, b.handleDiscord))
+	}
+	slog.Info("bot listening", "addr", *addr, "discord", *discordKey != "")
+	return http.ListenAndServe(*addr, logRequests(mux))
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// brailleLetters holds the patterns of the letters a through z, the first
+// ten of which also stand for the digits 1 through 9 and 0
+var brailleLetters = []rune("⠁⠃⠉⠙⠑⠋⠛⠓⠊⠚⠅⠇⠍⠝⠕⠏⠟⠗⠎⠞⠥⠧⠺⠭⠽⠵")
+
+const (
+	// brailleEmpty marks an empty cell, dots 3 and 6
+	brailleEmpty = '⠤'
+	// brailleSpace is the pattern without dots, used between cells
+	brailleSpace = '⠀'
+)
+
+// brailleValue returns the pattern for val, using the letter of its symbol
+// if the board has symbols and it is one
+func (g *Game) brailleValue(val int) rune {
+	if val == 0 {
+		return brailleEmpty
+	}
+	if g.symbols != nil {
+		if r := unicode.ToLower(g.symbols[val-1]); 'a' <= r && r <= 'z' {
+			return brailleLetters[r-'a']
+		}
+	}
+	return brailleLetters[val-1]
+}
+
+// BrailleString maps the board to Unicode braille patterns for braille
+// displays, one row per line.  Values are written as the patterns of the
+// digits without number signs, with spacing blank cells between them and one
+// more between boxes.
+func (g *Game) BrailleString(spacing int) string {
+	gap := strings.Repeat(string(brailleSpace), max(spacing, 0))
+	var sb strings.Builder
+	for ri, row := range g.board {
+		if ri > 0 {
+			sb.WriteByte('\n')
+		}
+		for ci, val := range row {
+			if ci > 0 {
+				sb.WriteString(gap)
+				if ci%3 == 0 {
+					sb.WriteRune(brailleSpace)
+				}
+			}
+			sb.WriteRune(g.brailleValue(val))
+		}
+	}
+	return sb.String()
+}
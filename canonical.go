@@ -0,0 +1,135 @@
+package main
+
+// bandOrders lists the 6 orders of the 3 bands, or 3 stacks, of the board,
+// and also of the 3 lines within a band
+var bandOrders = [][3]int{{0, 1, 2}, {0, 2, 1}, {1, 0, 2}, {1, 2, 0}, {2, 0, 1}, {2, 1, 0}}
+
+// lineOrders returns the 1296 orders of the rows, or columns, which keep
+// each band together: each order of the bands, with the lines of every band
+// in any order
+func lineOrders() [][DIM]int {
+	var result [][DIM]int
+	for _, bands := range bandOrders {
+		for _, first := range bandOrders {
+			for _, second := range bandOrders {
+				for _, third := range bandOrders {
+					var order [DIM]int
+					for i, lines := range [3][3]int{first, second, third} {
+						for j, line := range lines {
+							order[i*3+j] = bands[i]*3 + line
+						}
+					}
+					result = append(result, order)
+				}
+			}
+		}
+	}
+	return result
+}
+
+// canonicalSearch finds the least line of a board over the orders of its
+// rows, for one order of its columns
+type canonicalSearch struct {
+	// board has the column order applied
+	board [DIM][DIM]int
+	cur   [DIM * DIM]byte
+	best  []byte
+	// found counts the times best was replaced
+	found int
+}
+
+// relabelRow writes row of the board to out, numbering digits not in
+// relabel yet from next on, and returns the numbering extended by the row
+func (s *canonicalSearch) relabelRow(row int, relabel [DIM + 1]byte, next byte, out []byte) ([DIM + 1]byte, byte) {
+	for ci, val := range s.board[row] {
+		ch := byte('0')
+		if val != 0 {
+			if relabel[val] == 0 {
+				relabel[val] = next
+				next++
+			}
+			ch = relabel[val]
+		}
+		out[ci] = ch
+	}
+	return relabel, next
+}
+
+// rows places the rows of the board from pos on, used having a bit for each
+// row placed so far.  Only the rows giving the least line at pos can lead to
+// the least board, so the others are skipped, as is any line greater than
+// best while the lines so far equal it.
+func (s *canonicalSearch) rows(pos int, used uint16, relabel [DIM + 1]byte, next byte, equal bool) {
+	if pos == DIM {
+		s.best = append(s.best[:0], s.cur[:]...)
+		s.found++
+		return
+	}
+	var least, line [DIM]byte
+	var ties [DIM]int
+	n := 0
+	for row := 0; row < DIM; row++ {
+		band := row / 3
+		if used&(1<<uint(row)) != 0 {
+			continue
+		}
+		// A new band starts every third line, otherwise the band continues
+		if pos%3 == 0 && used&(7<<uint(3*band)) != 0 {
+			continue
+		}
+		if pos%3 != 0 && used&(7<<uint(3*band)) == 0 {
+			continue
+		}
+		s.relabelRow(row, relabel, next, line[:])
+		switch {
+		case n == 0 || string(line[:]) < string(least[:]):
+			least, ties[0], n = line, row, 1
+		case line == least:
+			ties[n] = row
+			n++
+		}
+	}
+	if equal {
+		if cmp := string(least[:]); cmp > string(s.best[pos*DIM:(pos+1)*DIM]) {
+			return
+		} else if cmp < string(s.best[pos*DIM:(pos+1)*DIM]) {
+			equal = false
+		}
+	}
+	copy(s.cur[pos*DIM:], least[:])
+	for _, row := range ties[:n] {
+		found := s.found
+		r, nx := s.relabelRow(row, relabel, next, line[:])
+		s.rows(pos+1, used|1<<uint(row), r, nx, equal)
+		// A new best shares the lines so far
+		if s.found != found {
+			equal = true
+		}
+	}
+}
+
+// Canonical returns a line format form of the puzzle shared by every puzzle
+// equivalent to it under relabeling of the digits, transposition, and
+// reordering of the bands, stacks, and the rows and columns within them,
+// which together include rotation and reflection, for finding duplicates in
+// disguise.  It is the least line over all 2·1296² such transformations,
+// with digits numbered in order of first appearance.  Rows are chosen one at
+// a time for each column order, pruning those which can't be least.
+func Canonical(g *Game) string {
+	s := &canonicalSearch{}
+	for _, transpose := range []bool{false, true} {
+		for _, cols := range lineOrders() {
+			for ri := 0; ri < DIM; ri++ {
+				for ci, c := range cols {
+					if transpose {
+						s.board[ri][ci] = g.board[c][ri]
+					} else {
+						s.board[ri][ci] = g.board[ri][c]
+					}
+				}
+			}
+			s.rows(0, 0, [DIM + 1]byte{}, '1', s.best != nil)
+		}
+	}
+	return string(s.best)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// transformed returns g under a random transformation of those Canonical
+// ignores, permuting the rows and columns within their bands and stacks
+func transformed(g *Game, rng *rand.Rand) *Game {
+	order := func() (result [DIM]int) {
+		for i, band := range rng.Perm(3) {
+			for j, line := range rng.Perm(3) {
+				result[i*3+j] = band*3 + line
+			}
+		}
+		return result
+	}
+	rows, cols := order(), order()
+	transpose := rng.Intn(2) == 1
+	digits := rng.Perm(DIM)
+	result := NewGame()
+	for ri := 0; ri < DIM; ri++ {
+		for ci := 0; ci < DIM; ci++ {
+			r, c := rows[ri], cols[ci]
+			if transpose {
+				r, c = c, r
+			}
+			if val := g.board[r][c]; val != 0 {
+				result.MakeMove(ri, ci, digits[val-1]+1)
+			}
+		}
+	}
+	return result
+}
+
+func TestCanonicalTransformed(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, name := range []string{"easy.txt", "hard.txt", "ultra.txt"} {
+		g, err := readGame(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := Canonical(g)
+		for i := 0; i < 20; i++ {
+			h := transformed(g, rng)
+			if got := Canonical(h); got != want {
+				t.Fatalf("%v transformed to %v has canonical form %v, want %v", name, h.Line(), got, want)
+			}
+		}
+	}
+}
+
+// Moving a single given isn't one of the transformations
+func TestCanonicalDistinct(t *testing.T) {
+	g, err := readGame("hard.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := g.Clone()
+	for ci := 0; ci < DIM; ci++ {
+		if val := h.board[0][ci]; val != 0 {
+			h.UnmakeMove(0, ci)
+			break
+		}
+	}
+	if Canonical(g) == Canonical(h) {
+		t.Errorf("%v and %v have the same canonical form", g.Line(), h.Line())
+	}
+}
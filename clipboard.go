@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// clipboardTool is an external command for accessing the system clipboard
+type clipboardTool struct {
+	paste, copy []string
+}
+
+// clipboardTools lists the commands tried on each platform, in order
+func clipboardTools() []clipboardTool {
+	switch runtime.GOOS {
+	case "darwin":
+		return []clipboardTool{{[]string{"pbpaste"}, []string{"pbcopy"}}}
+	case "windows":
+		return []clipboardTool{{
+			[]string{"powershell.exe", "-NoProfile", "-Command", "Get-Clipboard"},
+			[]string{"clip.exe"},
+		}}
+	}
+	return []clipboardTool{
+		{[]string{"wl-paste", "--no-newline"}, []string{"wl-copy"}},
+		{[]string{"xclip", "-selection", "clipboard", "-o"}, []string{"xclip", "-selection", "clipboard"}},
+		{[]string{"xsel", "--clipboard", "--output"}, []string{"xsel", "--clipboard", "--input"}},
+	}
+}
+
+// findClipboardTool returns the first tool whose command is installed
+func findClipboardTool() (clipboardTool, error) {
+	for _, t := range clipboardTools() {
+		if _, err := exec.LookPath(t.paste[0]); err == nil {
+			return t, nil
+		}
+	}
+	return clipboardTool{}, fmt.Errorf("No clipboard command found, install xclip, xsel or wl-clipboard")
+}
+
+// readClipboard returns the text content of the system clipboard
+func readClipboard() (string, error) {
+	t, err := findClipboardTool()
+	if err != nil {
+		return "", err
+	}
+	out, err := exec.Command(t.paste[0], t.paste[1:]...).Output()
+	if err != nil {
+		return "", fmt.Errorf("Reading clipboard: %v", err)
+	}
+	return string(out), nil
+}
+
+// writeClipboard replaces the content of the system clipboard with text
+func writeClipboard(text string) error {
+	t, err := findClipboardTool()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(t.copy[0], t.copy[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("Writing clipboard: %v", err)
+	}
+	return nil
+}
+
+// readClipboardGame reads a puzzle from the clipboard, either as 9 rows or a
+// single line of 81 cells as often copied from websites
+func readClipboardGame() (*Game, error) {
+	text, err := readClipboard()
+	if err != nil {
+		return nil, err
+	}
+	text = strings.TrimSpace(text)
+	format := GridFormat
+	if !strings.Contains(text, "\n") {
+		format = LineFormat
+	}
+	return Parse(strings.NewReader(text), format)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// conflictMaxRemove limits how many givens the conflict analysis removes
+// before giving up, as the search grows quickly with each one
+const conflictMaxRemove = 3
+
+// Conflict explains why a puzzle has no solution
+type Conflict struct {
+	// Core is a minimal set of givens which together have no solution,
+	// removing any one of them leaves the rest solvable
+	Core []Candidate
+	// Remove is a smallest set of givens whose removal makes the whole
+	// puzzle solvable, nil if none was found within conflictMaxRemove
+	Remove []Candidate
+}
+
+// solvable is true if the givens don't conflict and the board can be
+// completed
+func solvable(g *Game) bool {
+	return len(Violations(g, false)) == 0 && g.CountSolutions(1) > 0
+}
+
+// givensOf lists the filled cells of the board
+func givensOf(g *Game) []Candidate {
+	var result []Candidate
+	for ri, row := range g.board {
+		for ci, val := range row {
+			if val != 0 {
+				result = append(result, Candidate{Cell{ri, ci}, val})
+			}
+		}
+	}
+	return result
+}
+
+// boardOf builds a puzzle with just the givens
+func boardOf(givens []Candidate) *Game {
+	g := NewGame()
+	for _, c := range givens {
+		g.MakeMove(c.Row, c.Col, c.Value)
+	}
+	g.MarkGivens()
+	return g
+}
+
+// unsolvableCore shrinks givens, which must have no solution, to a minimal
+// subset without one by dropping each given the rest can do without
+func unsolvableCore(givens []Candidate) []Candidate {
+	core := append([]Candidate(nil), givens...)
+	for i := 0; i < len(core); {
+		rest := append(append([]Candidate(nil), core[:i]...), core[i+1:]...)
+		if !solvable(boardOf(rest)) {
+			core = rest
+		} else {
+			i++
+		}
+	}
+	return core
+}
+
+// repair searches for up to k givens whose removal makes givens solvable.
+// Every such set removes a given from each unsolvable core, so only the
+// givens of one core need to be tried at each level.
+func repair(givens []Candidate, k int) ([]Candidate, bool) {
+	if solvable(boardOf(givens)) {
+		return nil, true
+	}
+	if k == 0 {
+		return nil, false
+	}
+	for _, c := range unsolvableCore(givens) {
+		var rest []Candidate
+		for _, g := range givens {
+			if g != c {
+				rest = append(rest, g)
+			}
+		}
+		if removed, ok := repair(rest, k-1); ok {
+			return append(removed, c), true
+		}
+	}
+	return nil, false
+}
+
+// AnalyzeConflict finds the givens responsible for a puzzle having no
+// solution, returning nil if it has one
+func AnalyzeConflict(g *Game) *Conflict {
+	givens := givensOf(g)
+	if solvable(boardOf(givens)) {
+		return nil
+	}
+	c := &Conflict{Core: unsolvableCore(givens)}
+	// Deepen one removal at a time so the set found is a smallest one
+	for k := 1; k <= conflictMaxRemove && c.Remove == nil; k++ {
+		c.Remove, _ = repair(givens, k)
+	}
+	return c
+}
+
+// givenList formats givens as r1c2=5, separated by spaces
+func givenList(givens []Candidate) string {
+	var parts []string
+	for _, c := range givens {
+		parts = append(parts, fmt.Sprintf("%v=%v", c.Cell, c.Value))
+	}
+	return strings.Join(parts, " ")
+}
+
+// String describes the conflict for the command line
+func (c *Conflict) String() string {
+	s := fmt.Sprintf("These %v givens can't all hold: %v", len(c.Core), givenList(c.Core))
+	if c.Remove == nil {
+		return s + fmt.Sprintf("\nNo set of %v givens or fewer could be removed to make the puzzle solvable",
+			conflictMaxRemove)
+	}
+	return s + fmt.Sprintf("\nRemoving %v makes the puzzle solvable", givenList(c.Remove))
+}
@@ -0,0 +1,609 @@
+package main
+
+import (
+	"math/bits"
+)
+
+// allValues is the candidate bit set holding every value, see candidateMask
+const allValues uint16 = 1<<(DIM+1) - 2
+
+// Constraint is a rule of a variant puzzle, on top of the classic ones
+type Constraint interface {
+	// Cells lists the cells the rule applies to
+	Cells() []Cell
+	// Allowed narrows mask, the candidates of cell c as a bit set, to the
+	// values which leave the rule satisfiable given the rest of the board.
+	// Once every cell of the rule is filled it must only allow the values
+	// which satisfy it.
+	Allowed(g *Game, c Cell, mask uint16) uint16
+}
+
+// Variant is a puzzle with the groups of cells which may not repeat a value,
+// and the constraints beyond them, ready to solve.  Groups need not have DIM
+// cells, a pair of cells which must differ is a group of two.
+type Variant struct {
+	Game *Game
+	// groups holds the rows, columns and boxes, unless it is a Latin square,
+	// followed by any extra groups
+	groups [][]Cell
+	// peers lists the cells sharing a group with each cell
+	peers [DIM][DIM][]Cell
+	// rules lists the constraints applying to each cell
+	rules [DIM][DIM][]Constraint
+}
+
+// NewVariant prepares g for solving under the rules given by the groups whose
+// cells must all differ, see classicGroups, and the constraints
+func NewVariant(g *Game, groups [][]Cell, constraints []Constraint) *Variant {
+	v := &Variant{Game: g, groups: groups}
+	var seen [DIM][DIM][DIM][DIM]bool
+	for _, group := range v.groups {
+		for _, c := range group {
+			for _, p := range group {
+				if p != c && !seen[c.Row][c.Col][p.Row][p.Col] {
+					seen[c.Row][c.Col][p.Row][p.Col] = true
+					v.peers[c.Row][c.Col] = append(v.peers[c.Row][c.Col], p)
+				}
+			}
+		}
+	}
+	for _, rule := range constraints {
+		for _, c := range rule.Cells() {
+			v.rules[c.Row][c.Col] = append(v.rules[c.Row][c.Col], rule)
+		}
+	}
+	return v
+}
+
+// classicGroups returns the rows and columns, and the boxes unless latin is
+// set
+func classicGroups(latin bool) [][]Cell {
+	var result [][]Cell
+	for i, unit := range units {
+		if !latin || i%3 != 2 {
+			result = append(result, unit)
+		}
+	}
+	return result
+}
+
+// Clone returns a copy of the puzzle with its own board, sharing the rules
+func (v *Variant) Clone() *Variant {
+	c := *v
+	c.Game = v.Game.Clone()
+	return &c
+}
+
+// candidates returns the legal moves for cell c as a bit set, taking the
+// extra groups and constraints into account
+func (v *Variant) candidates(c Cell) uint16 {
+	var used uint16
+	for _, p := range v.peers[c.Row][c.Col] {
+		used |= 1 << uint(v.Game.board[p.Row][p.Col])
+	}
+	mask := allValues &^ used
+	for _, rule := range v.rules[c.Row][c.Col] {
+		if mask == 0 {
+			break
+		}
+		mask = rule.Allowed(v.Game, c, mask)
+	}
+	return mask
+}
+
+// nextEmptyCell returns the empty cell with the fewest candidates, and them
+func (v *Variant) nextEmptyCell() (Cell, uint16) {
+	var best Cell
+	var bestMask uint16
+	min := DIM + 1
+	for ri, cols := range v.Game.board {
+		for ci, val := range cols {
+			if val != 0 {
+				continue
+			}
+			c := Cell{ri, ci}
+			mask := v.candidates(c)
+			if n := bits.OnesCount16(mask); n < min {
+				best, bestMask, min = c, mask, n
+				if n == 0 {
+					return best, 0
+				}
+			}
+		}
+	}
+	return best, bestMask
+}
+
+// Valid is true if every filled cell is consistent with the rules, including
+// the givens.  A board failing it has no solution.
+func (v *Variant) Valid() bool {
+	for ri, cols := range v.Game.board {
+		for ci, val := range cols {
+			if val == 0 {
+				continue
+			}
+			c := Cell{ri, ci}
+			v.Game.board[ri][ci] = 0
+			ok := v.candidates(c)&(1<<uint(val)) != 0
+			v.Game.board[ri][ci] = val
+			if !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Solve fills the board by recursive backtracking under the variant rules,
+// giving up if stats has a deadline which expires.  stats may be nil.
+func (v *Variant) Solve(stats *Stats) bool {
+	if !v.Valid() {
+		return false
+	}
+	start := v.Game.backtracks
+	solved := v.search(stats)
+	if stats != nil {
+		stats.Backtracks += v.Game.backtracks - start
+	}
+	return solved
+}
+
+func (v *Variant) search(stats *Stats) bool {
+	if v.Game.ValidSolution() {
+		return true
+	}
+	if stats.expired() {
+		return false
+	}
+	c, candidates := v.nextEmptyCell()
+	for mask := candidates; mask != 0; mask &= mask - 1 {
+		v.Game.MakeMove(c.Row, c.Col, bits.TrailingZeros16(mask))
+		if v.search(stats) {
+			return true
+		}
+		v.Game.UnmakeMove(c.Row, c.Col)
+	}
+	return false
+}
+
+// CountSolutions counts the solutions under the variant rules, stopping once
+// limit have been found.  The board is left unchanged.
+func (v *Variant) CountSolutions(limit int) int {
+	if limit <= 0 || !v.Valid() {
+		return 0
+	}
+	found := 0
+	v.count(limit, &found)
+	return found
+}
+
+func (v *Variant) count(limit int, found *int) {
+	if v.Game.ValidSolution() {
+		*found++
+		return
+	}
+	c, candidates := v.nextEmptyCell()
+	for mask := candidates; mask != 0 && *found < limit; mask &= mask - 1 {
+		v.Game.MakeMove(c.Row, c.Col, bits.TrailingZeros16(mask))
+		v.count(limit, found)
+		v.Game.UnmakeMove(c.Row, c.Col)
+	}
+}
+
+// sees is true if the cells share a group, so may not hold the same value
+func (v *Variant) sees(a, b Cell) bool {
+	for _, p := range v.peers[a.Row][a.Col] {
+		if p == b {
+			return true
+		}
+	}
+	return false
+}
+
+// valueRange returns the bit set of values lo through hi inclusive, empty if
+// the range is
+func valueRange(lo, hi int) uint16 {
+	lo, hi = max(lo, 1), min(hi, DIM)
+	if lo > hi {
+		return 0
+	}
+	return (1<<uint(hi+1) - 1) &^ (1<<uint(lo) - 1)
+}
+
+// Cage is a killer cage: its cells hold distinct values adding up to Sum
+type Cage struct {
+	Sum   int
+	cells []Cell
+}
+
+// Cells lists the cells of the cage
+func (k *Cage) Cells() []Cell { return k.cells }
+
+// Allowed keeps each value for which the empty cells left could still make up
+// the sum with distinct values
+func (k *Cage) Allowed(g *Game, c Cell, mask uint16) uint16 {
+	var used uint16
+	total, empty := 0, 0
+	for _, p := range k.cells {
+		if val := g.board[p.Row][p.Col]; val != 0 {
+			used |= 1 << uint(val)
+			total += val
+		} else if p != c {
+			empty++
+		}
+	}
+	mask &^= used
+	for m := mask; m != 0; m &= m - 1 {
+		val := bits.TrailingZeros16(m)
+		lo, hi := distinctSumRange(allValues&^used&^(1<<uint(val)), empty)
+		if rest := k.Sum - total - val; rest < lo || hi < rest {
+			mask &^= 1 << uint(val)
+		}
+	}
+	return mask
+}
+
+// distinctSumRange returns the smallest and largest sums of n distinct values
+// from the bit set avail.  lo exceeds hi if there aren't enough values.
+func distinctSumRange(avail uint16, n int) (lo, hi int) {
+	if bits.OnesCount16(avail) < n {
+		return 1, 0
+	}
+	for i, m := 0, avail; i < n; i, m = i+1, m&(m-1) {
+		lo += bits.TrailingZeros16(m)
+	}
+	for i, m := 0, avail; i < n; i++ {
+		val := bits.Len16(m) - 1
+		hi += val
+		m &^= 1 << uint(val)
+	}
+	return lo, hi
+}
+
+// Thermo is a thermometer: values strictly increase from the bulb, its first
+// cell, along the rest
+type Thermo struct {
+	cells []Cell
+}
+
+// Cells lists the cells of the thermometer, bulb first
+func (t *Thermo) Cells() []Cell { return t.cells }
+
+// Allowed keeps the values which leave room for the cells between c and the
+// filled cells before and after it
+func (t *Thermo) Allowed(g *Game, c Cell, mask uint16) uint16 {
+	pos := 0
+	for i, p := range t.cells {
+		if p == c {
+			pos = i
+		}
+	}
+	lo, hi := 1+pos, DIM-(len(t.cells)-1-pos)
+	for i, p := range t.cells {
+		val := g.board[p.Row][p.Col]
+		if val == 0 || i == pos {
+			continue
+		}
+		if i < pos {
+			lo = max(lo, val+pos-i)
+		} else {
+			hi = min(hi, val-(i-pos))
+		}
+	}
+	return mask & valueRange(lo, hi)
+}
+
+// Dot is a Kropki dot between two orthogonally adjacent cells: a white dot
+// joins consecutive values, a black one values where one is double the other
+type Dot struct {
+	Black bool
+	cells []Cell
+}
+
+// Cells lists the two cells either side of the dot
+func (d *Dot) Cells() []Cell { return d.cells }
+
+// Allowed keeps the values which have a partner in the other cell
+func (d *Dot) Allowed(g *Game, c Cell, mask uint16) uint16 {
+	other := d.cells[0]
+	if other == c {
+		other = d.cells[1]
+	}
+	partners := allValues
+	if val := g.board[other.Row][other.Col]; val != 0 {
+		partners = 1 << uint(val)
+	}
+	var result uint16
+	for m := mask; m != 0; m &= m - 1 {
+		val := bits.TrailingZeros16(m)
+		var want uint16
+		if d.Black {
+			want = 1 << uint(val*2)
+			if val%2 == 0 {
+				want |= 1 << uint(val/2)
+			}
+		} else {
+			want = 1<<uint(val+1) | 1<<uint(val-1)
+		}
+		if want&partners&allValues != 0 {
+			result |= 1 << uint(val)
+		}
+	}
+	return result
+}
+
+// LittleKiller is a little killer clue, the values along a diagonal add up to
+// Sum and may repeat
+type LittleKiller struct {
+	Sum   int
+	cells []Cell
+}
+
+// Cells lists the cells of the diagonal, starting at the clue
+func (k *LittleKiller) Cells() []Cell { return k.cells }
+
+// Allowed keeps the values for which the empty cells left could still make up
+// the sum
+func (k *LittleKiller) Allowed(g *Game, c Cell, mask uint16) uint16 {
+	total, empty := 0, 0
+	for _, p := range k.cells {
+		if val := g.board[p.Row][p.Col]; val != 0 {
+			total += val
+		} else if p != c {
+			empty++
+		}
+	}
+	rest := k.Sum - total
+	return mask & valueRange(rest-DIM*empty, rest-empty)
+}
+
+// Renban is a line holding a set of consecutive values, in any order
+type Renban struct {
+	cells []Cell
+}
+
+// Cells lists the cells of the line
+func (r *Renban) Cells() []Cell { return r.cells }
+
+// Allowed keeps the values not already on the line which keep the span of its
+// values short enough to be consecutive
+func (r *Renban) Allowed(g *Game, c Cell, mask uint16) uint16 {
+	lo, hi := DIM, 1
+	var used uint16
+	for _, p := range r.cells {
+		if val := g.board[p.Row][p.Col]; val != 0 && p != c {
+			used |= 1 << uint(val)
+			lo, hi = min(lo, val), max(hi, val)
+		}
+	}
+	if used == 0 {
+		return mask
+	}
+	span := len(r.cells) - 1
+	return mask &^ used & valueRange(hi-span, lo+span)
+}
+
+// Whisper is a German whisper line, neighbors on the line differ by at least
+// whisperGap
+type Whisper struct {
+	cells []Cell
+}
+
+const whisperGap = 5
+
+// Cells lists the cells of the line, in order
+func (w *Whisper) Cells() []Cell { return w.cells }
+
+// Allowed keeps the values far enough from each neighbor of c on the line,
+// or with some value far enough for a neighbor left empty
+func (w *Whisper) Allowed(g *Game, c Cell, mask uint16) uint16 {
+	for i, p := range w.cells {
+		if p != c {
+			continue
+		}
+		for _, j := range []int{i - 1, i + 1} {
+			if j < 0 || len(w.cells) <= j {
+				continue
+			}
+			n := w.cells[j]
+			if val := g.board[n.Row][n.Col]; val != 0 {
+				mask &= valueRange(1, val-whisperGap) | valueRange(val+whisperGap, DIM)
+			} else {
+				mask &= valueRange(1, DIM-whisperGap) | valueRange(1+whisperGap, DIM)
+			}
+		}
+	}
+	return mask
+}
+
+// Palindrome is a line whose values read the same from either end
+type Palindrome struct {
+	cells []Cell
+}
+
+// Cells lists the cells of the line, in order
+func (p *Palindrome) Cells() []Cell { return p.cells }
+
+// Allowed keeps only the value of the cell mirroring c, once it is filled
+func (p *Palindrome) Allowed(g *Game, c Cell, mask uint16) uint16 {
+	for i, cell := range p.cells {
+		if cell != c {
+			continue
+		}
+		m := p.cells[len(p.cells)-1-i]
+		if val := g.board[m.Row][m.Col]; val != 0 && m != c {
+			mask &= 1 << uint(val)
+		}
+	}
+	return mask
+}
+
+// Parity restricts shaded cells to the even or the odd values
+type Parity struct {
+	cells []Cell
+	// values is the bit set of values allowed
+	values uint16
+}
+
+// evenValues is the bit set of the even values
+const evenValues uint16 = 1<<2 | 1<<4 | 1<<6 | 1<<8
+
+// Cells lists the shaded cells
+func (p *Parity) Cells() []Cell { return p.cells }
+
+// Allowed keeps the values of the right parity
+func (p *Parity) Allowed(g *Game, c Cell, mask uint16) uint16 {
+	return mask & p.values
+}
+
+// Arrow is an arrow sum: the value in the circle, its first cell, is the sum
+// of the values along the rest, which may repeat
+type Arrow struct {
+	cells []Cell
+}
+
+// Cells lists the circle followed by the cells of the arrow
+func (a *Arrow) Cells() []Cell { return a.cells }
+
+// Allowed keeps the values for which the sum of the arrow can still reach
+// the circle, assuming each empty cell on the arrow may hold any value
+func (a *Arrow) Allowed(g *Game, c Cell, mask uint16) uint16 {
+	total, empty := 0, 0
+	for _, p := range a.cells[1:] {
+		if val := g.board[p.Row][p.Col]; val != 0 {
+			total += val
+		} else if p != c {
+			empty++
+		}
+	}
+	circle := a.cells[0]
+	if c == circle {
+		return mask & valueRange(total+empty, total+DIM*empty)
+	}
+	if sum := g.board[circle.Row][circle.Col]; sum != 0 {
+		return mask & valueRange(sum-total-DIM*empty, sum-total-empty)
+	}
+	return mask & valueRange(1, DIM-total-empty)
+}
+
+// Quadruple is a clue at the corner of four cells, which must contain the
+// values it lists
+type Quadruple struct {
+	cells []Cell
+	// needs counts how many times each value must appear
+	needs [DIM + 1]int
+}
+
+// Cells lists the four cells around the corner
+func (q *Quadruple) Cells() []Cell { return q.cells }
+
+// Allowed keeps the values which leave enough empty cells for the values
+// still missing
+func (q *Quadruple) Allowed(g *Game, c Cell, mask uint16) uint16 {
+	var placed [DIM + 1]int
+	empty := 0
+	for _, p := range q.cells {
+		if val := g.board[p.Row][p.Col]; val != 0 {
+			placed[val]++
+		} else if p != c {
+			empty++
+		}
+	}
+	missing := 0
+	for val, n := range q.needs {
+		missing += max(0, n-placed[val])
+	}
+	for m := mask; m != 0; m &= m - 1 {
+		val := bits.TrailingZeros16(m)
+		after := missing
+		if q.needs[val] > placed[val] {
+			after--
+		}
+		if after > empty {
+			mask &^= 1 << uint(val)
+		}
+	}
+	return mask
+}
+
+// Arithmetic is a KenKen cage, whose values combine to Target with Op, one
+// of + - * or /.  - and / have two cells.
+type Arithmetic struct {
+	Op     byte
+	Target int
+	cells  []Cell
+}
+
+// Cells lists the cells of the cage
+func (a *Arithmetic) Cells() []Cell { return a.cells }
+
+// Allowed keeps the values for which the empty cells left could still reach
+// the target, assuming each may hold any value
+func (a *Arithmetic) Allowed(g *Game, c Cell, mask uint16) uint16 {
+	total, product, empty := 0, 1, 0
+	var other int
+	for _, p := range a.cells {
+		if val := g.board[p.Row][p.Col]; val != 0 {
+			total += val
+			product *= val
+			other = val
+		} else if p != c {
+			empty++
+		}
+	}
+	switch a.Op {
+	case '+':
+		rest := a.Target - total
+		return mask & valueRange(rest-DIM*empty, rest-empty)
+	case '*':
+		for m := mask; m != 0; m &= m - 1 {
+			val := bits.TrailingZeros16(m)
+			if a.Target%(product*val) != 0 || !productReachable(a.Target/(product*val), empty) {
+				mask &^= 1 << uint(val)
+			}
+		}
+		return mask
+	}
+	var result uint16
+	for m := mask; m != 0; m &= m - 1 {
+		val := bits.TrailingZeros16(m)
+		var partners uint16
+		if a.Op == '-' {
+			partners = valueRange(val-a.Target, val-a.Target) | valueRange(val+a.Target, val+a.Target)
+		} else {
+			partners = valueRange(val*a.Target, val*a.Target)
+			if val%a.Target == 0 {
+				partners |= valueRange(val/a.Target, val/a.Target)
+			}
+		}
+		if other != 0 {
+			partners &= 1 << uint(other)
+		}
+		if partners != 0 {
+			result |= 1 << uint(val)
+		}
+	}
+	return result
+}
+
+// productReachable is false if n can't be the product of count values.  It
+// is exact when count is 0, and otherwise only checks that n is small enough
+// and has no prime factor above DIM.
+func productReachable(n, count int) bool {
+	if count == 0 {
+		return n == 1
+	}
+	limit := 1
+	for i := 0; i < count && limit < n; i++ {
+		limit *= DIM
+	}
+	if n > limit {
+		return false
+	}
+	for _, p := range []int{2, 3, 5, 7} {
+		for n%p == 0 {
+			n /= p
+		}
+	}
+	return n == 1
+}
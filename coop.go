@@ -0,0 +1,316 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// coopColors are handed out to the players of a session in turn, so each can
+// see who filled which cell
+var coopColors = []string{"#1565c0", "#c62828", "#2e7d32", "#6a1b9a", "#ef6c00", "#00838f", "#ad1457",
+	"#4e342e"}
+
+// CoopMessage is a JSON message of the co-op WebSocket.  Clients send join
+// with their name, then place to set the value of a cell, 0 to clear it, or
+// marks to set its pencil marks.  Edits carry the version of the cell the
+// client last saw; an edit made against an older version loses to the one
+// already accepted and is answered with conflict holding the current cell.
+// The server sends state to a player who joins, joined and left as players
+// come and go, cell for every accepted edit, and solved once the board is
+// complete and correct.
+type CoopMessage struct {
+	Type    string       `json:"type"`
+	Name    string       `json:"name,omitempty"`
+	Color   string       `json:"color,omitempty"`
+	Session string       `json:"session,omitempty"`
+	ID      int          `json:"id,omitempty"`
+	Puzzle  string       `json:"puzzle,omitempty"`
+	Players []CoopPlayer `json:"players,omitempty"`
+	Cells   []CoopCell   `json:"cells,omitempty"`
+	Cell    *CoopCell    `json:"cell,omitempty"`
+	Error   string       `json:"error,omitempty"`
+}
+
+// CoopPlayer is a player of a session and their color
+type CoopPlayer struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+// CoopCell is the shared state of a cell which isn't a given
+type CoopCell struct {
+	// Cell is in r1c1 notation
+	Cell  string `json:"cell"`
+	Value int    `json:"value"`
+	Marks []int  `json:"marks,omitempty"`
+	// By names the player who made the last edit
+	By    string `json:"by,omitempty"`
+	Color string `json:"color,omitempty"`
+	// Version counts the edits of the cell
+	Version int `json:"version"`
+}
+
+// coopPlayer is a connection taking part in a session
+type coopPlayer struct {
+	conn *wsConn
+	CoopPlayer
+}
+
+// coopSession is a board shared by its players
+type coopSession struct {
+	id     string
+	puzzle PackPuzzle
+	given  *Game
+	// cells holds the state of every cell, givens are never edited
+	cells   [DIM][DIM]CoopCell
+	players map[*coopPlayer]bool
+	solved  bool
+}
+
+// coop holds the sessions of serve mode by ID, a session lasts while it has
+// players
+type coop struct {
+	pack     *Pack
+	mu       sync.Mutex
+	sessions map[string]*coopSession
+}
+
+func newCoop(pack *Pack) *coop {
+	return &coop{pack: pack, sessions: make(map[string]*coopSession)}
+}
+
+// newSessionID returns a random ID for a session the client didn't name
+func newSessionID() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// join adds a player to the session with ID id, starting it on a random
+// puzzle if it doesn't exist, and sends them the state of the board
+func (c *coop) join(p *coopPlayer, id, name string) (*coopSession, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if id == "" {
+		id = newSessionID()
+	}
+	s := c.sessions[id]
+	if s == nil {
+		pz, _ := c.pack.Random(PuzzleQuery{}, nil)
+		g, err := ParseLine(pz.Puzzle)
+		if err != nil {
+			return nil, fmt.Errorf("Starting session: %v", err)
+		}
+		s = &coopSession{id: id, puzzle: pz, given: g, players: make(map[*coopPlayer]bool)}
+		for ri := range s.cells {
+			for ci := range s.cells[ri] {
+				s.cells[ri][ci] = CoopCell{Cell: Cell{ri, ci}.String()}
+			}
+		}
+		c.sessions[id] = s
+		slog.Info("coop session started", "session", id, "id", pz.ID)
+	}
+	if name == "" {
+		name = "player"
+	}
+	p.Name = name
+	for n := 2; s.nameTaken(p.Name); n++ {
+		p.Name = fmt.Sprintf("%v %v", name, n)
+	}
+	p.Color = s.freeColor()
+	s.broadcast(CoopMessage{Type: "joined", Name: p.Name, Color: p.Color})
+	s.players[p] = true
+	p.send(s.stateMessage(p))
+	return s, nil
+}
+
+func (s *coopSession) nameTaken(name string) bool {
+	for p := range s.players {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// freeColor returns the first color no player is using, or cycles through
+// them once every color is taken
+func (s *coopSession) freeColor() string {
+	used := make(map[string]bool)
+	for p := range s.players {
+		used[p.Color] = true
+	}
+	for _, color := range coopColors {
+		if !used[color] {
+			return color
+		}
+	}
+	return coopColors[len(s.players)%len(coopColors)]
+}
+
+// stateMessage describes the whole session for player p
+func (s *coopSession) stateMessage(p *coopPlayer) CoopMessage {
+	msg := CoopMessage{Type: "state", Name: p.Name, Color: p.Color, Session: s.id, ID: s.puzzle.ID,
+		Puzzle: s.puzzle.Puzzle}
+	for q := range s.players {
+		msg.Players = append(msg.Players, q.CoopPlayer)
+	}
+	sort.Slice(msg.Players, func(i, j int) bool { return msg.Players[i].Name < msg.Players[j].Name })
+	for ri, row := range s.cells {
+		for ci, cell := range row {
+			if s.given.board[ri][ci] == 0 && cell.Version > 0 {
+				msg.Cells = append(msg.Cells, cell)
+			}
+		}
+	}
+	return msg
+}
+
+// broadcast sends msg to every player, the coop lock must be held
+func (s *coopSession) broadcast(msg CoopMessage) {
+	data, _ := json.Marshal(msg)
+	for p := range s.players {
+		if err := p.conn.WriteMessage(data); err != nil {
+			slog.Debug("coop write", "name", p.Name, "err", err)
+		}
+	}
+}
+
+// send writes msg to a single player
+func (p *coopPlayer) send(msg CoopMessage) error {
+	data, _ := json.Marshal(msg)
+	return p.conn.WriteMessage(data)
+}
+
+// leave removes a player from their session, ending it when the last one
+// goes
+func (c *coop) leave(s *coopSession, p *coopPlayer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !s.players[p] {
+		return
+	}
+	delete(s.players, p)
+	if len(s.players) == 0 {
+		delete(c.sessions, s.id)
+		slog.Info("coop session ended", "session", s.id)
+		return
+	}
+	s.broadcast(CoopMessage{Type: "left", Name: p.Name, Color: p.Color})
+}
+
+// edit applies a place or marks message from p to the board, sharing the
+// new state of the cell with everyone
+func (c *coop) edit(s *coopSession, p *coopPlayer, kind string, e *CoopCell) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e == nil {
+		return fmt.Errorf("Expected a cell to %v", kind)
+	}
+	cell, err := ParseCell(e.Cell)
+	if err != nil {
+		return err
+	}
+	if s.given.board[cell.Row][cell.Col] != 0 {
+		return fmt.Errorf("%v is a given", cell)
+	}
+	current := &s.cells[cell.Row][cell.Col]
+	if e.Version != current.Version {
+		p.send(CoopMessage{Type: "conflict", Cell: current})
+		return nil
+	}
+	switch kind {
+	case "place":
+		if e.Value < 0 || DIM < e.Value {
+			return fmt.Errorf("Invalid value %v, expected 0 through %v", e.Value, DIM)
+		}
+		current.Value = e.Value
+	case "marks":
+		seen := make(map[int]bool)
+		var marks []int
+		for _, m := range e.Marks {
+			if m < 1 || DIM < m {
+				return fmt.Errorf("Invalid pencil mark %v, expected 1 through %v", m, DIM)
+			}
+			if !seen[m] {
+				seen[m] = true
+				marks = append(marks, m)
+			}
+		}
+		sort.Ints(marks)
+		current.Marks = marks
+	}
+	current.By, current.Color = p.Name, p.Color
+	current.Version++
+	s.broadcast(CoopMessage{Type: "cell", Cell: current})
+	if !s.solved && s.complete() {
+		s.solved = true
+		slog.Info("coop session solved", "session", s.id, "id", s.puzzle.ID)
+		s.broadcast(CoopMessage{Type: "solved", Name: p.Name, ID: s.puzzle.ID})
+	}
+	return nil
+}
+
+// complete is true if every cell holds the value of the solution
+func (s *coopSession) complete() bool {
+	for ri, row := range s.cells {
+		for ci, cell := range row {
+			val := s.given.board[ri][ci]
+			if val == 0 {
+				val = cell.Value
+			}
+			if len(s.puzzle.Solution) != DIM*DIM || val != int(s.puzzle.Solution[ri*DIM+ci]-'0') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// handleCoop serves GET /coop, upgrading to a WebSocket which shares a board
+// using CoopMessage.  The session query parameter names the session to join,
+// a new one is created if it is absent.
+func (c *coop) handleCoop(w http.ResponseWriter, req *http.Request) {
+	conn, err := upgradeWebSocket(w, req, "")
+	if err != nil {
+		slog.Debug("coop upgrade", "err", err)
+		return
+	}
+	defer conn.Close()
+	p := &coopPlayer{conn: conn}
+	var s *coopSession
+	defer func() {
+		if s != nil {
+			c.leave(s, p)
+		}
+	}()
+	for {
+		data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var msg CoopMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			p.send(CoopMessage{Type: "error", Error: fmt.Sprintf("Invalid message: %v", err)})
+			continue
+		}
+		switch {
+		case msg.Type == "join" && s == nil:
+			if s, err = c.join(p, req.URL.Query().Get("session"), msg.Name); err != nil {
+				p.send(CoopMessage{Type: "error", Error: err.Error()})
+			}
+		case (msg.Type == "place" || msg.Type == "marks") && s != nil:
+			if err := c.edit(s, p, msg.Type, msg.Cell); err != nil {
+				p.send(CoopMessage{Type: "error", Error: err.Error()})
+			}
+		default:
+			p.send(CoopMessage{Type: "error", Error: fmt.Sprintf("Unexpected %q message", msg.Type)})
+		}
+	}
+}
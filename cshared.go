@@ -0,0 +1,53 @@
+//go:build cshared
+
+package main
+
+// #include <stdlib.h>
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+// The cshared build tag adds C entry points for use as a shared library:
+//
+//	go build -tags cshared -buildmode=c-shared -o libsudoku.so
+//
+// Boards are passed in the 81 digit line format.  Strings returned are
+// allocated with malloc and must be released with FreeString.  Failures are
+// returned as a message starting with "error: ".
+
+// cResult converts a result for return to C
+func cResult(s string, err error) *C.char {
+	if err != nil {
+		s = "error: " + err.Error()
+	}
+	return C.CString(s)
+}
+
+//export SolveString
+func SolveString(puzzle *C.char) *C.char {
+	g, err := ParseLine(C.GoString(puzzle))
+	if err != nil {
+		return cResult("", err)
+	}
+	if !recursiveSolver(g, nil) {
+		return cResult("", fmt.Errorf("Puzzle has no solution"))
+	}
+	return cResult(g.Line(), nil)
+}
+
+//export GenerateString
+func GenerateString(seed C.longlong, symmetry *C.char, clues C.int) *C.char {
+	g, err := Generate(int64(seed), C.GoString(symmetry), int(clues))
+	if err != nil {
+		return cResult("", err)
+	}
+	return cResult(g.Line(), nil)
+}
+
+//export FreeString
+func FreeString(s *C.char) {
+	C.free(unsafe.Pointer(s))
+}
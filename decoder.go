@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Decoder reads a stream of puzzles, each either a single line of 81 cells or
+// 9 rows in the layout of the example puzzle files, and the two may be mixed.
+// Blank lines, lines starting with # and other lines which aren't rows, such
+// as grid borders or the "Grid 01" headers of Project Euler files, are
+// skipped.  Only the current puzzle is held in memory.
+// Metadata comments ahead of a puzzle, as read by ParsePuzzle, are kept for
+// it.
+type Decoder struct {
+	scanner *bufio.Scanner
+	lineNum int
+	// line is the line number of the start of the last puzzle returned
+	line int
+	// meta holds the metadata of the last puzzle returned, and pending that
+	// read since
+	meta, pending Metadata
+}
+
+// NewDecoder returns a Decoder reading puzzles from r
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{scanner: bufio.NewScanner(r)}
+}
+
+// cellCount counts the characters of a line representing cells, digits or .
+func cellCount(line string) int {
+	n := 0
+	for _, c := range line {
+		if c == '.' || ('0' <= c && c <= '9') {
+			n++
+		}
+	}
+	return n
+}
+
+// isRow is true if the line holds cells and nothing else but the separators
+// of the example files and grid borders
+func isRow(line string) bool {
+	for _, c := range line {
+		if c != '.' && (c < '0' || '9' < c) && !strings.ContainsRune(" \t|-+,", c) {
+			return false
+		}
+	}
+	return cellCount(line) > 0
+}
+
+// Next returns the next puzzle of the stream, or io.EOF when there are none
+// left
+func (d *Decoder) Next() (*Game, error) {
+	var rows []string
+	for d.scanner.Scan() {
+		d.lineNum++
+		line := strings.TrimSpace(d.scanner.Text())
+		if strings.HasPrefix(line, "#") {
+			if len(rows) == 0 {
+				d.pending.parseLine(line)
+			}
+			continue
+		}
+		// A line of a whole puzzle may have other text around its cells
+		n := cellCount(line)
+		if n < DIM*DIM && !isRow(line) {
+			continue
+		}
+		if len(rows) == 0 {
+			d.line = d.lineNum
+			d.meta, d.pending = d.pending, Metadata{}
+			if n >= DIM*DIM {
+				return ParseLine(line)
+			}
+		}
+		rows = append(rows, strings.Replace(line, ".", "0", -1))
+		if len(rows) == DIM {
+			return scanGame(strings.NewReader(strings.Join(rows, "\n")))
+		}
+	}
+	if err := d.scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(rows) > 0 {
+		return nil, fmt.Errorf("EOF while reading row %v", len(rows)+1)
+	}
+	return nil, io.EOF
+}
+
+// Line returns the 1 based line number where the last puzzle returned by Next
+// started
+func (d *Decoder) Line() int {
+	return d.line
+}
+
+// Metadata returns the metadata of the last puzzle returned by Next
+func (d *Decoder) Metadata() Metadata {
+	return d.meta
+}
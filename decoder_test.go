@@ -0,0 +1,53 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+// The headers of the concatenated Project Euler format aren't rows, even
+// though they hold digits
+func TestDecoderGridHeaders(t *testing.T) {
+	input := `Grid 01
+003020600
+900305001
+001806400
+008102900
+700000008
+006708200
+002609500
+800203009
+005010300
+Grid 02
+200080300
+060070084
+030500209
+000105408
+000000000
+402706000
+301007040
+720040060
+004010003
+`
+	want := []string{
+		"003020600900305001001806400008102900700000008006708200002609500800203009005010300",
+		"200080300060070084030500209000105408000000000402706000301007040720040060004010003",
+	}
+	dec := NewDecoder(strings.NewReader(input))
+	for i, line := range want {
+		g, err := dec.Next()
+		if err != nil {
+			t.Fatalf("puzzle %v: %v", i+1, err)
+		}
+		if g.Line() != line {
+			t.Errorf("puzzle %v = %v, want %v", i+1, g.Line(), line)
+		}
+		if got := dec.Line(); got != 2+10*i {
+			t.Errorf("puzzle %v starts at line %v, want %v", i+1, got, 2+10*i)
+		}
+	}
+	if _, err := dec.Next(); err != io.EOF {
+		t.Errorf("got %v after the last puzzle, want EOF", err)
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"html"
+	"io"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+)
+
+// Defaults for the -limit and -samples flags of the commands analysing the
+// solution space, see SolutionDistribution
+const (
+	distributionLimit   = 10000
+	distributionSamples = 200
+)
+
+// Distribution is how the solutions of a puzzle fill each cell, for seeing
+// which parts of an under-constrained grid are settled
+type Distribution struct {
+	// Counts[row][col][digit] is the number of the solutions counted which
+	// place digit in the cell
+	Counts [DIM][DIM][DIM + 1]int
+	// Solutions is the number of solutions counted, every one of them if
+	// Exact and otherwise a random sample
+	Solutions int
+	Exact     bool
+}
+
+// SolutionDistribution counts the digits of every solution of the board if
+// it has at most limit of them, and otherwise of samples solutions chosen by
+// SampleSolution.  The board is left unchanged.
+func (g *Game) SolutionDistribution(limit, samples int, rng *rand.Rand) *Distribution {
+	d := &Distribution{}
+	lines, truncated := g.EnumerateSolutions(limit, 0)
+	if !truncated {
+		d.Exact = true
+		for _, line := range lines {
+			for i := 0; i < DIM*DIM; i++ {
+				d.Counts[i/DIM][i%DIM][line[i]-'0']++
+			}
+			d.Solutions++
+		}
+		return d
+	}
+	for ; d.Solutions < samples; d.Solutions++ {
+		s := g.SampleSolution(rng)
+		for ri, row := range s.board {
+			for ci, val := range row {
+				d.Counts[ri][ci][val]++
+			}
+		}
+	}
+	return d
+}
+
+// Options is the number of digits placed in the cell by the solutions
+// counted, 1 if it is settled
+func (d *Distribution) Options(row, col int) int {
+	n := 0
+	for _, count := range d.Counts[row][col][1:] {
+		if count > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// Probability is the fraction of the solutions counted which place val in
+// the cell
+func (d *Distribution) Probability(row, col, val int) float64 {
+	if d.Solutions == 0 {
+		return 0
+	}
+	return float64(d.Counts[row][col][val]) / float64(d.Solutions)
+}
+
+// title describes the solutions counted
+func (d *Distribution) title() string {
+	if d.Exact {
+		return fmt.Sprintf("Solutions: %v", d.Solutions)
+	}
+	return fmt.Sprintf("Sampled %v solutions", d.Solutions)
+}
+
+// writeText lists the digits each empty cell of puzzle may hold with their
+// probabilities, then how many of the cells are settled
+func (d *Distribution) writeText(w io.Writer, puzzle *Game) {
+	fmt.Fprintln(w, d.title())
+	settled, empty := 0, 0
+	for ri, row := range puzzle.board {
+		for ci, val := range row {
+			if val != 0 {
+				continue
+			}
+			empty++
+			if d.Options(ri, ci) == 1 {
+				settled++
+			}
+			var parts []string
+			for v := 1; v <= DIM; v++ {
+				if d.Counts[ri][ci][v] > 0 {
+					parts = append(parts, fmt.Sprintf("%v:%.0f%%", v, 100*d.Probability(ri, ci, v)))
+				}
+			}
+			fmt.Fprintf(w, "%v  %v digits  %v\n", Cell{ri, ci}, d.Options(ri, ci), strings.Join(parts, " "))
+		}
+	}
+	fmt.Fprintf(w, "Settled: %v of %v empty cells\n", settled, empty)
+}
+
+// writeSVG draws the distribution as a heat map in style.  The givens of
+// puzzle are drawn as on a board, and each empty cell is split into a
+// square for each digit shaded by how likely the cell is to hold it.
+func (d *Distribution) writeSVG(w io.Writer, puzzle *Game, style BoardStyle) {
+	cell := style.Cell
+	margin := overlayMargin + style.BoxLine/2
+	board := DIM*cell + 2*margin
+	fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%v" height="%v" viewBox="0 0 %[1]v %[2]v" `+
+		`font-family="%v">`+"\n", board, board+overlayTitle, html.EscapeString(style.Font))
+	fmt.Fprintf(w, `<rect width="100%%" height="100%%" fill="%v"/>`+"\n", htmlColor(style.Background))
+	fmt.Fprintf(w, `<text x="%v" y="%v" font-size="18" fill="%v">%v</text>`+"\n", margin, overlayTitle-8,
+		htmlColor(style.Given), html.EscapeString(d.title()))
+	fmt.Fprintf(w, `<g transform="translate(%v,%v)">`+"\n", margin, overlayTitle+margin)
+	mini := float64(cell) / 3
+	for ri, row := range puzzle.board {
+		for ci, val := range row {
+			if val != 0 {
+				fmt.Fprintf(w, `<text x="%v" y="%v" font-size="%v" text-anchor="middle" fill="%v" font-weight="bold">%v</text>`+"\n",
+					ci*cell+cell/2, ri*cell+cell*3/4, cell*13/20, htmlColor(style.Given), val)
+				continue
+			}
+			for v := 1; v <= DIM; v++ {
+				p := d.Probability(ri, ci, v)
+				if p == 0 {
+					continue
+				}
+				x := float64(ci*cell) + float64((v-1)%3)*mini
+				y := float64(ri*cell) + float64((v-1)/3)*mini
+				fmt.Fprintf(w, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%v" fill-opacity="%.2f">`+
+					`<title>%v=%v %.0f%%</title></rect>`+"\n", x, y, mini, mini, htmlColor(style.Filled), p, Cell{ri, ci}, v, 100*p)
+				fmt.Fprintf(w, `<text x="%.1f" y="%.1f" font-size="%.1f" text-anchor="middle" fill="%v">%v</text>`+"\n",
+					x+mini/2, y+mini*3/4, mini*3/4, htmlColor(style.Given), v)
+			}
+		}
+	}
+	writeGridLines(w, style)
+	fmt.Fprintln(w, "</g>\n</svg>")
+}
+
+// distributionCommand handles: distribution [flags] <puzzle file>, reporting
+// how the solutions of the puzzle fill each empty cell
+func distributionCommand(args []string) error {
+	fs := flag.NewFlagSet("distribution", flag.ExitOnError)
+	output := fs.String("o", "text", "output `format`: text, svg or html")
+	limit := fs.Int("limit", distributionLimit, "count every solution if there are at most `n`, else sample them")
+	samples := fs.Int("samples", distributionSamples, "number of solutions to sample when there are too many to count")
+	seed := fs.Int64("seed", time.Now().UnixNano(), "random seed, for repeatable samples")
+	styleFlags := addBoardStyleFlags(fs)
+	fs.Parse(args)
+	if fs.NArg() != 1 || *limit < 1 || *samples < 1 {
+		return fmt.Errorf("Usage: distribution [flags] <puzzle file>")
+	}
+	style, err := styleFlags.Style()
+	if err != nil {
+		return err
+	}
+	p, err := readPuzzle(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if !solvable(p.Game) {
+		return fmt.Errorf("Puzzle has no solution")
+	}
+	d := p.Game.SolutionDistribution(*limit, *samples, rand.New(rand.NewSource(*seed)))
+	switch *output {
+	case "text":
+		d.writeText(os.Stdout, p.Game)
+	case "svg":
+		d.writeSVG(os.Stdout, p.Game, style)
+	case "html":
+		title := "Sudoku"
+		if p.Source != "" {
+			title = p.Source
+		}
+		fmt.Printf("<!DOCTYPE html>\n<html>\n<head>\n<title>%v</title>\n", html.EscapeString(title))
+		fmt.Printf("<style>\nbody { background: %v; }\n</style>\n</head>\n<body>\n", htmlColor(style.Background))
+		d.writeSVG(os.Stdout, p.Game, style)
+		fmt.Print("</body>\n</html>\n")
+	default:
+		return fmt.Errorf("Unknown output %q, expected text, svg or html", *output)
+	}
+	return nil
+}
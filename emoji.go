@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// emojiEmpty marks an empty cell unless a set gives its own
+const emojiEmpty = "⬜"
+
+// emojiSets are the built in sets for -o emoji, each the emoji of 1 through
+// 9 followed by the one for empty cells
+var emojiSets = map[string][]string{
+	"animals": {"🐶", "🐱", "🐭", "🐰", "🦊", "🐻", "🐼", "🐸", "🐵", emojiEmpty},
+	"fruit":   {"🍎", "🍊", "🍋", "🍐", "🍉", "🍇", "🍑", "🍒", "🍓", emojiEmpty},
+	"hearts":  {"❤️", "🧡", "💛", "💚", "💙", "💜", "🤎", "🖤", "🤍", "⬛"},
+	"keycaps": {"1️⃣", "2️⃣", "3️⃣", "4️⃣", "5️⃣", "6️⃣", "7️⃣", "8️⃣", "9️⃣", emojiEmpty},
+}
+
+// emojiSetNames lists the built in emoji sets for usage messages
+func emojiSetNames() string {
+	var names []string
+	for name := range emojiSets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// emojiWidth estimates the terminal columns taken by s.  Emoji are two
+// columns wide, including sequences such as keycaps which join several code
+// points, while other symbols take a column each.
+func emojiWidth(s string) int {
+	for _, r := range s {
+		if r == '\uFE0F' || r >= 0x1F000 || 0x2B00 <= r && r <= 0x2BFF {
+			return 2
+		}
+	}
+	return utf8.RuneCountInString(s)
+}
+
+// LookupEmoji returns the emoji set called name, either built in or defined
+// under emoji in the config file, or a comma separated list of the 9 emoji
+// for the values optionally followed by one for empty cells.  Symbols
+// narrower than emoji are padded so the columns line up.
+func LookupEmoji(name string) ([]string, error) {
+	config, err := readConfig()
+	if err != nil {
+		return nil, err
+	}
+	set, ok := config.Emoji[name]
+	if !ok {
+		set, ok = emojiSets[name]
+	}
+	if !ok && strings.Contains(name, ",") {
+		set = strings.Split(name, ",")
+	} else if !ok {
+		var names []string
+		for name := range emojiSets {
+			names = append(names, name)
+		}
+		for name := range config.Emoji {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("Unknown emoji set %q, expected a list of %v emoji or one of: %v", name, DIM,
+			strings.Join(names, ", "))
+	}
+	if len(set) == DIM {
+		set = append(set[:DIM:DIM], emojiEmpty)
+	}
+	if len(set) != DIM+1 {
+		return nil, fmt.Errorf("Emoji set %q has %v emoji, expected %v, or %v with the one for empty cells",
+			name, len(set), DIM, DIM+1)
+	}
+	padded := make([]string, len(set))
+	for i, e := range set {
+		e = strings.TrimSpace(e)
+		padded[i] = e + strings.Repeat(" ", max(2-emojiWidth(e), 0))
+	}
+	return padded, nil
+}
+
+// EmojiString maps the values of the board to the emoji of set, as returned
+// by LookupEmoji, for sharing on social media.  Boxes are separated by a
+// blank the width of an emoji and rows of boxes by a blank line.
+func (g *Game) EmojiString(set []string) string {
+	var sb strings.Builder
+	for ri, row := range g.board {
+		if ri > 0 {
+			sb.WriteByte('\n')
+			if ri%3 == 0 {
+				sb.WriteByte('\n')
+			}
+		}
+		var line strings.Builder
+		for ci, val := range row {
+			if ci > 0 && ci%3 == 0 {
+				line.WriteString("  ")
+			}
+			if val == 0 {
+				line.WriteString(set[DIM])
+			} else {
+				line.WriteString(set[val-1])
+			}
+		}
+		sb.WriteString(strings.TrimRight(line.String(), " "))
+	}
+	return sb.String()
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// solutionSize estimates the memory held by each solution EnumerateSolutions
+// stores: the 81 digit line and its string header
+const solutionSize = DIM*DIM + 16
+
+// EnumerateSolutions returns the solutions of the board in line format, up
+// to limit of them, or all of them if limit is zero.  Storing them may use at
+// most maxBytes of memory, zero for no cap.  truncated is true if either
+// stopped the search early.  The board is left unchanged.
+func (g *Game) EnumerateSolutions(limit int, maxBytes int64) (solutions []string, truncated bool) {
+	c := g.Clone()
+	keep := limit
+	if maxBytes > 0 {
+		if n := max(1, int(maxBytes/solutionSize)); keep == 0 || n < keep {
+			keep = n
+		}
+	}
+	var search func() bool
+	search = func() bool {
+		if c.ValidSolution() {
+			if keep > 0 && len(solutions) == keep {
+				truncated = true
+				return false
+			}
+			solutions = append(solutions, c.Line())
+			return true
+		}
+		row, col := c.NextEmptyCell()
+		for mask := c.candidateMask(row, col); mask != 0; mask &= mask - 1 {
+			c.MakeMove(row, col, bits.TrailingZeros16(mask))
+			more := search()
+			c.UnmakeMove(row, col)
+			if !more {
+				return false
+			}
+		}
+		return true
+	}
+	search()
+	if truncated && maxBytes > 0 && (limit == 0 || keep < limit) {
+		slog.Warn("solution enumeration truncated by memory cap",
+			"solutions", len(solutions), "max_memory", maxBytes)
+	}
+	return solutions, truncated
+}
+
+// parseSize reads a byte count with an optional KB, MB or GB suffix, powers
+// of 1024
+func parseSize(s string) (int64, error) {
+	units := []struct {
+		suffix string
+		scale  int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}}
+	s = strings.ToUpper(strings.TrimSpace(s))
+	scale := int64(1)
+	for _, u := range units {
+		if strings.HasSuffix(s, u.suffix) {
+			s, scale = strings.TrimSpace(strings.TrimSuffix(s, u.suffix)), u.scale
+			break
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("Invalid size %q, expected bytes or a number with KB, MB or GB", s)
+	}
+	return n * scale, nil
+}
+
+// solutionsCommand handles: solutions [flags] <puzzle file>, printing every
+// solution of the puzzle, one per line
+func solutionsCommand(args []string) error {
+	fs := flag.NewFlagSet("solutions", flag.ExitOnError)
+	limit := fs.Int("limit", 1000, "stop after `n` solutions, 0 for all of them")
+	maxMemory := fs.String("max-memory", "64MB", "cap the memory used to store solutions, 0 for no cap")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("Usage: solutions [flags] <puzzle file>")
+	}
+	maxBytes, err := parseSize(*maxMemory)
+	if err != nil {
+		return err
+	}
+	board, err := readGame(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	solutions, truncated := board.EnumerateSolutions(*limit, maxBytes)
+	for _, s := range solutions {
+		fmt.Println(s)
+	}
+	if truncated {
+		fmt.Printf("\nStopped after %v solutions, there are more\n", len(solutions))
+	} else {
+		fmt.Printf("\nSolutions: %v\n", len(solutions))
+	}
+	return nil
+}
@@ -0,0 +1,400 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// cnfVar numbers the boolean variable "cell r, c holds val" for the SAT
+// encoding, from 1 to 729
+func cnfVar(row, col, val int) int {
+	return row*DIM*DIM + col*DIM + val
+}
+
+// writeCNF encodes the puzzle as a SAT instance in DIMACS CNF format: every
+// cell holds exactly one value, every unit holds each value exactly once, and
+// each given is a unit clause
+func writeCNF(w io.Writer, g *Game) error {
+	var clauses [][]int
+	exactlyOne := func(vars []int) {
+		clauses = append(clauses, vars)
+		for i := range vars {
+			for j := i + 1; j < len(vars); j++ {
+				clauses = append(clauses, []int{-vars[i], -vars[j]})
+			}
+		}
+	}
+	for row := 0; row < DIM; row++ {
+		for col := 0; col < DIM; col++ {
+			var vars []int
+			for val := 1; val <= DIM; val++ {
+				vars = append(vars, cnfVar(row, col, val))
+			}
+			exactlyOne(vars)
+		}
+	}
+	for _, unit := range units {
+		for val := 1; val <= DIM; val++ {
+			var vars []int
+			for _, c := range unit {
+				vars = append(vars, cnfVar(c.Row, c.Col, val))
+			}
+			exactlyOne(vars)
+		}
+	}
+	for row, cols := range g.board {
+		for col, val := range cols {
+			if val != 0 {
+				clauses = append(clauses, []int{cnfVar(row, col, val)})
+			}
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "c sudoku puzzle", g.Line())
+	fmt.Fprintln(bw, "c variable row*81 + col*9 + val is true when cell row, col (0 based) holds val")
+	fmt.Fprintf(bw, "p cnf %v %v\n", DIM*DIM*DIM, len(clauses))
+	for _, clause := range clauses {
+		for _, lit := range clause {
+			bw.WriteString(strconv.Itoa(lit))
+			bw.WriteByte(' ')
+		}
+		bw.WriteString("0\n")
+	}
+	return bw.Flush()
+}
+
+// readSATModel decodes a model for the writeCNF encoding, as printed by SAT
+// solvers: positive literals, optionally on lines prefixed with v.  Comment
+// and status lines are ignored.
+func readSATModel(r io.Reader) (*Game, error) {
+	g := NewGame()
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "c", "s", "SAT", "SATISFIABLE":
+			continue
+		case "UNSAT", "UNSATISFIABLE":
+			return nil, fmt.Errorf("Model reports the puzzle is unsatisfiable")
+		case "v":
+			fields = fields[1:]
+		}
+		for _, f := range fields {
+			lit, err := strconv.Atoi(f)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid literal %q in model", f)
+			}
+			if lit <= 0 || DIM*DIM*DIM < lit {
+				continue
+			}
+			lit--
+			row, col, val := lit/(DIM*DIM), lit/DIM%DIM, lit%DIM+1
+			if g.board[row][col] != 0 {
+				return nil, fmt.Errorf("Model assigns more than one value to %v", Cell{row, col})
+			}
+			g.MakeMove(row, col, val)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !g.ValidSolution() {
+		return nil, fmt.Errorf("Model leaves %v cells empty", g.remaining)
+	}
+	return g, nil
+}
+
+// CoverMatrix is the exact cover formulation of a puzzle, as solved by
+// Algorithm X: choose a set of rows which together contain every column
+// exactly once
+type CoverMatrix struct {
+	// Columns names each constraint: r1c1 means the cell is filled, r1#5,
+	// c1#5 and b1#5 mean row, column or box 1 contains a 5
+	Columns []string   `json:"columns"`
+	Rows    []CoverRow `json:"rows"`
+}
+
+// CoverRow is a possible placement, and the indices of the columns it covers
+type CoverRow struct {
+	Name    string `json:"name"`
+	Columns []int  `json:"columns"`
+}
+
+// coverMatrix builds the exact cover matrix of a puzzle.  Each given has a
+// single row, and each empty cell a row for each of its candidates.
+func coverMatrix(g *Game) CoverMatrix {
+	var m CoverMatrix
+	for _, kind := range []string{"cell", "r", "c", "b"} {
+		for i := 0; i < DIM; i++ {
+			for j := 0; j < DIM; j++ {
+				if kind == "cell" {
+					m.Columns = append(m.Columns, Cell{i, j}.String())
+				} else {
+					m.Columns = append(m.Columns, fmt.Sprintf("%v%v#%v", kind, i+1, j+1))
+				}
+			}
+		}
+	}
+	const n = DIM * DIM
+	for row := 0; row < DIM; row++ {
+		for col := 0; col < DIM; col++ {
+			var candidates []bool
+			if g.board[row][col] == 0 {
+				candidates = g.CellCandidates(row, col)
+			}
+			c := Cell{row, col}
+			for val := 1; val <= DIM; val++ {
+				if g.board[row][col] != val && (candidates == nil || !candidates[val]) {
+					continue
+				}
+				m.Rows = append(m.Rows, CoverRow{
+					Name: fmt.Sprintf("%v=%v", c, val),
+					Columns: []int{
+						row*DIM + col,
+						n + row*DIM + val - 1,
+						2*n + col*DIM + val - 1,
+						3*n + c.box()*DIM + val - 1,
+					},
+				})
+			}
+		}
+	}
+	return m
+}
+
+// writeCover writes the exact cover matrix as text.  The first line lists the
+// column names separated by spaces, and each following line is a row: its
+// name, a colon, then the 0 based indices of the columns it covers.
+func writeCover(w io.Writer, g *Game) error {
+	m := coverMatrix(g)
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, strings.Join(m.Columns, " "))
+	for _, r := range m.Rows {
+		fmt.Fprintf(bw, "%v: %v\n", r.Name, strings.Trim(fmt.Sprint(r.Columns), "[]"))
+	}
+	return bw.Flush()
+}
+
+// writeCoverJSON writes the exact cover matrix as a JSON CoverMatrix
+func writeCoverJSON(w io.Writer, g *Game) error {
+	return json.NewEncoder(w).Encode(coverMatrix(g))
+}
+
+// writeMiniZinc writes a MiniZinc constraint model of the puzzle
+func writeMiniZinc(w io.Writer, g *Game) error {
+	return writeMiniZincVariant(w, g, false, nil)
+}
+
+// writeMiniZincVariant writes a MiniZinc constraint model of a variant
+// puzzle, without the boxes if latin and with each of extra holding distinct
+// values
+func writeMiniZincVariant(w io.Writer, g *Game, latin bool, extra [][]Cell) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "% sudoku puzzle", g.Line())
+	fmt.Fprintln(bw, `include "alldifferent.mzn";`)
+	fmt.Fprintln(bw)
+	fmt.Fprintf(bw, "array[1..%v, 1..%v] of 0..%v: givens = [|\n", DIM, DIM, DIM)
+	for ri, row := range g.board {
+		// The last row closes the array with |]
+		end := "|"
+		if ri == DIM-1 {
+			end = "|];"
+		}
+		fmt.Fprintf(bw, "  %v %v\n", strings.Join(strings.Fields(strings.Trim(fmt.Sprint(row), "[]")), ", "), end)
+	}
+	fmt.Fprintf(bw, "array[1..%v, 1..%v] of var 1..%v: x;\n", DIM, DIM, DIM)
+	fmt.Fprintln(bw)
+	fmt.Fprintf(bw, "constraint forall(r, c in 1..%v where givens[r, c] > 0)(x[r, c] = givens[r, c]);\n", DIM)
+	fmt.Fprintf(bw, "constraint forall(r in 1..%v)(alldifferent([x[r, c] | c in 1..%v]));\n", DIM, DIM)
+	fmt.Fprintf(bw, "constraint forall(c in 1..%v)(alldifferent([x[r, c] | r in 1..%v]));\n", DIM, DIM)
+	if !latin {
+		fmt.Fprintln(bw, "constraint forall(br, bc in 0..2)(alldifferent([x[3 * br + i, 3 * bc + j] | i, j in 1..3]));")
+	}
+	for _, group := range extra {
+		vars := make([]string, len(group))
+		for i, c := range group {
+			vars[i] = fmt.Sprintf("x[%v, %v]", c.Row+1, c.Col+1)
+		}
+		fmt.Fprintf(bw, "constraint alldifferent([%v]);\n", strings.Join(vars, ", "))
+	}
+	fmt.Fprintln(bw)
+	fmt.Fprintln(bw, "solve satisfy;")
+	fmt.Fprintf(bw, `output [show(x[r, c]) ++ if c == %v then "\n" else "" endif | r, c in 1..%v];`+"\n", DIM, DIM)
+	return bw.Flush()
+}
+
+// writeLP writes an integer programming model of the puzzle in CPLEX LP
+// format.  Binary variable x_r_c_v (1 based) is 1 when cell r, c holds v.
+func writeLP(w io.Writer, g *Game) error {
+	bw := bufio.NewWriter(w)
+	x := func(row, col, val int) string {
+		return fmt.Sprintf("x_%v_%v_%v", row+1, col+1, val)
+	}
+	sum := func(name string, vars []string) {
+		fmt.Fprintf(bw, " %v: %v = 1\n", name, strings.Join(vars, " + "))
+	}
+	fmt.Fprintln(bw, "\\ sudoku puzzle", g.Line())
+	fmt.Fprintln(bw, "Minimize")
+	fmt.Fprintln(bw, " obj: 0", x(0, 0, 1))
+	fmt.Fprintln(bw, "Subject To")
+	for row := 0; row < DIM; row++ {
+		for col := 0; col < DIM; col++ {
+			var vars []string
+			for val := 1; val <= DIM; val++ {
+				vars = append(vars, x(row, col, val))
+			}
+			sum(fmt.Sprintf("cell_%v_%v", row+1, col+1), vars)
+		}
+	}
+	for i, unit := range units {
+		for val := 1; val <= DIM; val++ {
+			var vars []string
+			for _, c := range unit {
+				vars = append(vars, x(c.Row, c.Col, val))
+			}
+			sum(fmt.Sprintf("%v_%v", strings.Replace(unitName(i), " ", "_", 1), val), vars)
+		}
+	}
+	fmt.Fprintln(bw, "Bounds")
+	for row, cols := range g.board {
+		for col, val := range cols {
+			if val != 0 {
+				fmt.Fprintf(bw, " %v = 1\n", x(row, col, val))
+			}
+		}
+	}
+	fmt.Fprintln(bw, "Binary")
+	for row := 0; row < DIM; row++ {
+		for col := 0; col < DIM; col++ {
+			for val := 1; val <= DIM; val++ {
+				fmt.Fprintln(bw, "", x(row, col, val))
+			}
+		}
+	}
+	fmt.Fprintln(bw, "End")
+	return bw.Flush()
+}
+
+// exporters maps the formats accepted by export -to to their writers
+var exporters = map[string]func(w io.Writer, g *Game) error{
+	"cnf":        writeCNF,
+	"cover":      writeCover,
+	"cover-json": writeCoverJSON,
+	"lp":         writeLP,
+	"minizinc":   writeMiniZinc,
+}
+
+// findTagged returns the first puzzle of the file matching the tag filters
+func findTagged(fname, filters string) (*Puzzle, error) {
+	file, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	dec := NewDecoder(file)
+	for {
+		g, err := dec.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%v: no puzzle matches %v", fname, filters)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%v line %v: %v", fname, dec.Line(), err)
+		}
+		source := fmt.Sprintf("%v:%v", fname, dec.Line())
+		if gradeFacets(g, source, dec.Metadata()).matchesAll(filters) {
+			return &Puzzle{Game: g, Metadata: dec.Metadata()}, nil
+		}
+	}
+}
+
+// exportComments holds the comment prefix of the export formats which can
+// carry the metadata of the puzzle
+var exportComments = map[string]string{
+	"cnf":      "c ",
+	"lp":       "\\ ",
+	"minizinc": "% ",
+}
+
+// exportCommand handles: export -to <format> <puzzle file>, or export -decode
+// <model file> to print the solution found by an external SAT solver
+func exportCommand(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	to := fs.String("to", "cnf", "export `format`: cnf, cover, cover-json, lp or minizinc")
+	tag := fs.String("tag", "", "export the first puzzle of a collection matching the comma separated tag `filters`")
+	decode := fs.Bool("decode", false, "read a SAT solver model instead, printing the solution")
+	variant := fs.Bool("variant", false, "read a variant description file instead of a puzzle, for -to minizinc")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("Usage: export -to <format> <puzzle file>, or export -decode <model file>")
+	}
+	if *variant {
+		if *to != "minizinc" {
+			return fmt.Errorf("Variant descriptions can only be exported -to minizinc")
+		}
+		return exportVariant(os.Stdout, fs.Arg(0))
+	}
+	if *decode {
+		file, err := os.Open(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		g, err := readSATModel(file)
+		if err != nil {
+			return err
+		}
+		fmt.Print(g.GridString())
+		return nil
+	}
+	export, ok := exporters[*to]
+	if !ok {
+		return fmt.Errorf("Unknown export format %q", *to)
+	}
+	var p *Puzzle
+	var err error
+	if *tag != "" {
+		p, err = findTagged(fs.Arg(0), *tag)
+	} else {
+		p, err = readPuzzle(fs.Arg(0))
+	}
+	if err != nil {
+		return err
+	}
+	if prefix, ok := exportComments[*to]; ok {
+		if err := p.Metadata.write(os.Stdout, prefix); err != nil {
+			return err
+		}
+	}
+	return export(os.Stdout, p.Game)
+}
+
+// exportVariant writes a MiniZinc model of the variant described by the file
+// fname.  Only the rules requiring groups of cells to hold distinct values
+// are supported: latin_square, diagonals, disjoint_groups, windoku and
+// anti_knight.
+func exportVariant(w io.Writer, fname string) error {
+	spec, err := readVariantSpec(fname)
+	if err != nil {
+		return err
+	}
+	b := spec.build()
+	if len(b.problems) > 0 {
+		return fmt.Errorf("%v: %v", fname, b.problems[0])
+	}
+	if len(b.rules) > 0 {
+		return fmt.Errorf("%v: %v isn't supported by the MiniZinc export", fname, b.paths[0])
+	}
+	// The groups of the variant rules follow those of the classic ones
+	extra := b.groups[len(classicGroups(spec.LatinSquare)):]
+	return writeMiniZincVariant(w, b.game, spec.LatinSquare, extra)
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// feedDays is the number of daily entries in the feed
+const feedDays = 10
+
+// atomFeed is an Atom feed document, RFC 4287
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Link    *atomLink   `xml:"link,omitempty"`
+	Summary string      `xml:"summary"`
+	Content atomContent `xml:"content"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// feedPuzzle is the puzzle generated for a day of the feed
+type feedPuzzle struct {
+	game       *Game
+	difficulty Difficulty
+}
+
+// feed generates a puzzle for each day, seeded by the date so every request
+// and every server agree on the entries
+type feed struct {
+	// base links entries to the web UI with the share code, if set
+	base    string
+	mu      sync.Mutex
+	puzzles map[string]feedPuzzle
+}
+
+func newFeed(base string) *feed {
+	return &feed{base: base, puzzles: make(map[string]feedPuzzle)}
+}
+
+// puzzle returns the puzzle of date as YYYY-MM-DD, generating it the first
+// time, the lock must be held
+func (f *feed) puzzle(date string) (feedPuzzle, error) {
+	if p, ok := f.puzzles[date]; ok {
+		return p, nil
+	}
+	h := fnv.New64a()
+	h.Write([]byte("feed/" + date))
+	g, err := Generate(int64(h.Sum64()), "rotational", 0)
+	if err != nil {
+		return feedPuzzle{}, err
+	}
+	s := NewStrategist(g.Clone())
+	s.Solve()
+	p := feedPuzzle{game: g, difficulty: s.Difficulty()}
+	f.puzzles[date] = p
+	slog.Debug("generated feed puzzle", "date", date, "difficulty", p.difficulty)
+	return p, nil
+}
+
+// entries returns the newest feedDays entries up to today, dropping older
+// puzzles from the cache
+func (f *feed) entries(today time.Time) ([]atomEntry, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	keep := make(map[string]bool)
+	var entries []atomEntry
+	for i := 0; i < feedDays; i++ {
+		day := today.AddDate(0, 0, -i)
+		date := day.Format(time.DateOnly)
+		keep[date] = true
+		p, err := f.puzzle(date)
+		if err != nil {
+			return nil, err
+		}
+		clues := DIM*DIM - p.game.remaining
+		e := atomEntry{
+			Title:   fmt.Sprintf("Sudoku for %v, %v", date, p.difficulty),
+			ID:      fmt.Sprintf("urn:sudoku:puzzle:%v", p.game.Line()),
+			Updated: day.Format(time.RFC3339),
+			Summary: fmt.Sprintf("Difficulty: %v, %v clues", p.difficulty, clues),
+			Content: atomContent{Type: "text", Body: p.game.GridString()},
+		}
+		if f.base != "" {
+			e.Link = &atomLink{Href: fmt.Sprintf("%v#%v", f.base, EncodeShare(p.game))}
+		}
+		entries = append(entries, e)
+	}
+	for date := range f.puzzles {
+		if !keep[date] {
+			delete(f.puzzles, date)
+		}
+	}
+	return entries, nil
+}
+
+// handleFeed serves GET /feed.atom, an Atom feed with a newly generated
+// puzzle each day
+func (f *feed) handleFeed(w http.ResponseWriter, r *http.Request) {
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	entries, err := f.entries(today)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	self := fmt.Sprintf("%v://%v%v", scheme, r.Host, r.URL.Path)
+	doc := atomFeed{
+		Title:   "Sudoku puzzles",
+		ID:      self,
+		Updated: today.Format(time.RFC3339),
+		Links:   []atomLink{{Rel: "self", Href: self}},
+		Author:  atomAuthor{Name: "sudoku-solver"},
+		Entries: entries,
+	}
+	if f.base != "" {
+		doc.Links = append(doc.Links, atomLink{Rel: "alternate", Href: f.base})
+	}
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	fmt.Fprint(w, xml.Header)
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		slog.Error("writing feed", "err", err)
+	}
+	fmt.Fprintln(w)
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"strings"
+)
+
+// Format is a text representation of a board
+type Format int
+
+const (
+	// GridFormat is 9 rows of digits, as in the example puzzle files.  When
+	// parsing, characters other than digits are ignored.
+	GridFormat Format = iota
+	// LineFormat is a single line of 81 cells, where 0 or . is empty
+	LineFormat
+	// ShareFormat is the compact share code printed by the share command
+	ShareFormat
+)
+
+var formatNames = []string{"grid", "line", "share"}
+
+func (f Format) String() string {
+	if f < 0 || int(f) >= len(formatNames) {
+		return fmt.Sprintf("Format(%d)", int(f))
+	}
+	return formatNames[f]
+}
+
+// ParseFormat returns the Format named s
+func ParseFormat(s string) (Format, error) {
+	for i, name := range formatNames {
+		if s == name {
+			return Format(i), nil
+		}
+	}
+	return 0, fmt.Errorf("Unknown format %q, expected one of: %v", s, strings.Join(formatNames, ", "))
+}
+
+// Parse reads a single board from r.  Blank lines and lines starting with #
+// before the puzzle are skipped, see ParsePuzzle for keeping the metadata
+// they may hold.  The puzzle may be written with other symbols, given as a
+// line such as "symbols: ABCDEFGHI" ahead of it, which the board keeps for
+// printing.
+func Parse(r io.Reader, format Format) (*Game, error) {
+	p, err := ParsePuzzle(r, format)
+	if err != nil {
+		return nil, err
+	}
+	return p.Game, nil
+}
+
+// parseText reads a single board from text written with digits, as Parse
+func parseText(text string, format Format) (*Game, error) {
+	if format == GridFormat {
+		return scanGame(strings.NewReader(text))
+	}
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		switch format {
+		case LineFormat:
+			return ParseLine(line)
+		case ShareFormat:
+			return DecodeShare(line)
+		}
+		return nil, fmt.Errorf("Unknown format %v", format)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("EOF while reading %v puzzle", format)
+}
+
+// Render writes the board to w in format, which Parse can read back
+func Render(w io.Writer, g *Game, format Format) error {
+	var s string
+	switch format {
+	case GridFormat:
+		s = g.GridString()
+	case LineFormat:
+		s = g.Line() + "\n"
+	case ShareFormat:
+		s = EncodeShare(g) + "\n"
+	default:
+		return fmt.Errorf("Unknown format %v", format)
+	}
+	_, err := io.WriteString(w, s)
+	return err
+}
+
+// ParseLine reads a board from a single line of 81 cells, where 0 or . both
+// represent an empty cell and other characters are ignored
+func ParseLine(line string) (*Game, error) {
+	b := NewGame()
+	cell := 0
+	for _, c := range line {
+		if c != '.' && (c < '0' || '9' < c) {
+			continue
+		}
+		if cell == DIM*DIM {
+			return nil, fmt.Errorf("More than %v cells in line", DIM*DIM)
+		}
+		if c != '.' {
+			b.MakeMove(cell/DIM, cell%DIM, int(c-'0'))
+		}
+		cell++
+	}
+	if cell != DIM*DIM {
+		return nil, fmt.Errorf("Expected %v cells in line, found %v", DIM*DIM, cell)
+	}
+	b.MarkGivens()
+	return b, nil
+}
+
+// Line formats the board as a single line of 81 digits, which ParseLine can
+// read back
+func (g *Game) Line() string {
+	var sb strings.Builder
+	for _, row := range g.board {
+		for _, val := range row {
+			sb.WriteByte(byte('0' + val))
+		}
+	}
+	return sb.String()
+}
+
+// Checksum returns a short checksum of the values of the board, the CRC-32 of
+// its Line as 8 hex digits, for confirming that archived solutions weren't
+// corrupted or mixed up.  It can be recomputed from the line format alone.
+func (g *Game) Checksum() string {
+	return fmt.Sprintf("%08x", crc32.ChecksumIEEE([]byte(g.Line())))
+}
+
+// ParseGrid reads a board of 9 rows as formatted by GridString, ignoring
+// non-numeric characters
+func ParseGrid(s string) (*Game, error) {
+	return scanGame(strings.NewReader(s))
+}
+
+// GridString formats a board in the layout of the example puzzle files, which
+// ParseGrid can read back
+func (g *Game) GridString() string {
+	var sb strings.Builder
+	for _, row := range g.board {
+		for ci, val := range row {
+			if ci > 0 && ci%3 == 0 {
+				sb.WriteByte(' ')
+			}
+			sb.WriteByte(byte('0' + val))
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+)
+
+// generateAttempts is the number of random carvings tried when the first
+// doesn't reach the target clue count
+const generateAttempts = 20
+
+// readSolution reads a completed board, checking that it is a valid solution
+func readSolution(fname string) (*Game, error) {
+	g, err := readGame(fname)
+	if err != nil {
+		return nil, err
+	}
+	if !g.ValidSolution() {
+		return nil, fmt.Errorf("%v: solution has %v empty cells", fname, g.remaining)
+	}
+	if bad := invalidCells(*g); len(bad) > 0 {
+		return nil, fmt.Errorf("%v: solution has invalid value %v at %v",
+			fname, g.board[bad[0].Row][bad[0].Col], bad[0])
+	}
+	return g, nil
+}
+
+// readMask reads a clue pattern from a text file of 9 rows.  x, X, # and *
+// mark cells which may hold a clue, while . 0 and - mark cells which must be
+// empty.  Other characters are ignored.
+func readMask(fname string) ([][]bool, error) {
+	data, err := os.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if len(lines) < DIM {
+		return nil, fmt.Errorf("%v: expected %v rows, found %v", fname, DIM, len(lines))
+	}
+	mask := make([][]bool, DIM)
+	for row := range mask {
+		for _, c := range lines[row] {
+			switch c {
+			case 'x', 'X', '#', '*':
+				mask[row] = append(mask[row], true)
+			case '.', '0', '-':
+				mask[row] = append(mask[row], false)
+			}
+		}
+		if len(mask[row]) != DIM {
+			return nil, fmt.Errorf("%v: expected %v cells in row %v, found %v",
+				fname, DIM, row+1, len(mask[row]))
+		}
+	}
+	return mask, nil
+}
+
+// generatePuzzle carves the puzzle with the fewest clues from
+// generateAttempts tries, stopping early once it has no more than clues.
+// solution is carved if not nil, and otherwise each attempt fills a new grid,
+// and clues are only placed in the cells of mask if not nil.
+func generatePuzzle(rng *rand.Rand, sym symmetry, clues int, solution *Game, mask [][]bool) (*Game, error) {
+	fixed := solution != nil
+	var best *Game
+	for i := 0; i < generateAttempts; i++ {
+		if !fixed && (solution == nil || mask != nil) {
+			// A mask may not admit a unique puzzle for every solution grid
+			solution = randomSolution(rng)
+		}
+		p := carveSymmetric(solution, rng, sym, clues, mask)
+		if p != nil && (best == nil || p.remaining > best.remaining) {
+			best = p
+		}
+		if best != nil && DIM*DIM-best.remaining <= clues {
+			break
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("No puzzle with a unique solution fits the mask after %v attempts",
+			generateAttempts)
+	}
+	return best, nil
+}
+
+// Generate returns a new puzzle with a unique solution, using the random
+// seed, the symmetry named as for the generate command, and a target number
+// of clues, 0 for a minimal puzzle.  The target may not be reached.
+func Generate(seed int64, symmetryName string, clues int) (*Game, error) {
+	sym, ok := symmetries[strings.ToLower(symmetryName)]
+	if !ok {
+		return nil, fmt.Errorf("Unknown symmetry %q", symmetryName)
+	}
+	return generatePuzzle(rand.New(rand.NewSource(seed)), sym, clues, nil, nil)
+}
+
+// generateVariant prints the description of a new variant puzzle under the
+// comma separated rules of list
+func generateVariant(rng *rand.Rand, seed int64, list string, sym symmetry, clues int) error {
+	rules, err := parseVariantRules(list)
+	if err != nil {
+		return err
+	}
+	spec, grade, err := GenerateVariant(rng, rules, sym, clues)
+	if err != nil {
+		return err
+	}
+	given := DIM*DIM - strings.Count(spec.Grid, "0")
+	if clues > 0 && given > clues {
+		fmt.Fprintf(os.Stderr, "Could not reach %v clues, best found has %v\n", clues, given)
+	}
+	fmt.Fprintf(os.Stderr, "Seed %v, %v clues, difficulty: %v\n", seed, given, grade)
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(spec)
+}
+
+// generateCommand handles: generate [flags], printing a new puzzle with a
+// unique solution in the layout of the example puzzle files
+func generateCommand(args []string) error {
+	fs := flag.NewFlagSet("generate", flag.ExitOnError)
+	fromSolution := fs.String("from-solution", "", "carve the puzzle out of the completed grid in `file`")
+	fromPack := fs.String("from-pack", "", "carve the puzzle out of the solution of a random puzzle of the pack in `file`")
+	tag := fs.String("tag", "", "only seed -from-pack with puzzles matching the comma separated tag `filters`")
+	symName := fs.String("symmetry", "none", "clue symmetry: none, rotational, mirror or diagonal")
+	clues := fs.Int("clues", 0, "target number of clues, 0 for a minimal puzzle")
+	maskFile := fs.String("mask", "", "only place clues in the cells marked in `file`")
+	seed := fs.Int64("seed", time.Now().UnixNano(), "random number generator seed")
+	variant := fs.String("variant", "", "comma separated variant `rules` to generate under, printing a description file: "+
+		variantRuleNames())
+	fs.Parse(args)
+	if fs.NArg() != 0 {
+		return fmt.Errorf("Usage: generate [flags]")
+	}
+	sym, ok := symmetries[strings.ToLower(*symName)]
+	if !ok {
+		return fmt.Errorf("Unknown symmetry %q", *symName)
+	}
+	if *variant != "" {
+		if *fromSolution != "" || *fromPack != "" || *maskFile != "" {
+			return fmt.Errorf("-variant can't be combined with -from-solution, -from-pack or -mask")
+		}
+		return generateVariant(rand.New(rand.NewSource(*seed)), *seed, *variant, sym, *clues)
+	}
+
+	var mask [][]bool
+	if *maskFile != "" {
+		var err error
+		if mask, err = readMask(*maskFile); err != nil {
+			return err
+		}
+	}
+
+	rng := rand.New(rand.NewSource(*seed))
+	var solution *Game
+	var seededBy string
+	switch {
+	case *fromSolution != "" && *fromPack != "":
+		return fmt.Errorf("-from-solution can't be combined with -from-pack")
+	case *fromSolution != "":
+		var err error
+		if solution, err = readSolution(*fromSolution); err != nil {
+			return err
+		}
+	case *fromPack != "":
+		pack, err := readPack(*fromPack)
+		if err != nil {
+			return err
+		}
+		p, ok := pack.Random(PuzzleQuery{Tag: *tag}, rng)
+		if !ok {
+			return fmt.Errorf("No puzzle of %v matches %q", *fromPack, *tag)
+		}
+		if solution, err = ParseLine(p.Solution); err != nil {
+			return fmt.Errorf("Puzzle %v solution: %v", p.ID, err)
+		}
+		seededBy = fmt.Sprintf("the solution of %v puzzle %v", pack.Name, p.ID)
+	case *tag != "":
+		return fmt.Errorf("-tag needs -from-pack")
+	}
+
+	best, err := generatePuzzle(rng, sym, *clues, solution, mask)
+	if err != nil {
+		return err
+	}
+	given := DIM*DIM - best.remaining
+	if *clues > 0 && given > *clues {
+		fmt.Fprintf(os.Stderr, "Could not reach %v clues, best found has %v\n", *clues, given)
+	}
+	s := NewStrategist(best.Clone())
+	s.Solve()
+	fmt.Fprintf(os.Stderr, "Seed %v, %v clues, difficulty: %v\n", *seed, given, s.Difficulty())
+	if seededBy != "" {
+		fmt.Fprintf(os.Stderr, "Carved from %v\n", seededBy)
+	}
+	fmt.Print(best.GridString())
+	return nil
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"math/rand"
+)
+
+// randomSolution fills an empty board by backtracking over candidates in a
+// random order
+func randomSolution(rng *rand.Rand) *Game {
+	g := NewGame()
+	randomFill(g, rng)
+	g.backtracks = 0
+	return g
+}
+
+func randomFill(g *Game, rng *rand.Rand) bool {
+	if g.ValidSolution() {
+		return true
+	}
+	row, col := g.NextEmptyCell()
+	candidates := g.CellCandidates(row, col)
+	for _, i := range rng.Perm(DIM) {
+		if val := i + 1; candidates[val] {
+			g.MakeMove(row, col, val)
+			if randomFill(g, rng) {
+				return true
+			}
+			g.UnmakeMove(row, col)
+		}
+	}
+	return false
+}
+
+// carvePuzzle removes clues from a copy of the solved board in a random order,
+// skipping any whose removal would allow a second solution.  The result is a
+// minimal puzzle: every remaining clue is needed for uniqueness.
+func carvePuzzle(solution *Game, rng *rand.Rand) *Game {
+	return carveSymmetric(solution, rng, noSymmetry, 0, nil)
+}
+
+// symmetry maps a cell to the cells which must have a clue whenever it does
+type symmetry func(c Cell) []Cell
+
+func noSymmetry(c Cell) []Cell {
+	return []Cell{c}
+}
+
+func rotationalSymmetry(c Cell) []Cell {
+	return uniqueCells(c, Cell{DIM - 1 - c.Row, DIM - 1 - c.Col})
+}
+
+func mirrorSymmetry(c Cell) []Cell {
+	return uniqueCells(c, Cell{c.Row, DIM - 1 - c.Col})
+}
+
+func diagonalSymmetry(c Cell) []Cell {
+	return uniqueCells(c, Cell{c.Col, c.Row})
+}
+
+func uniqueCells(cells ...Cell) []Cell {
+	var result []Cell
+	for _, c := range cells {
+		if !containsCell(result, c) {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// symmetries maps the names accepted by the generate command to symmetries
+var symmetries = map[string]symmetry{
+	"none":       noSymmetry,
+	"rotational": rotationalSymmetry,
+	"mirror":     mirrorSymmetry,
+	"diagonal":   diagonalSymmetry,
+}
+
+// carveSymmetric works like carvePuzzle, but removes each clue together with
+// its symmetric partners, and stops before the clue count drops below target.
+// If mask is not nil, only cells marked true may keep their clue; nil is
+// returned if the puzzle isn't unique with every other cell cleared.
+func carveSymmetric(solution *Game, rng *rand.Rand, sym symmetry, target int, mask [][]bool) *Game {
+	p := solution.Clone()
+	if mask != nil {
+		for ri := range mask {
+			for ci, allowed := range mask[ri] {
+				if !allowed {
+					p.UnmakeMove(ri, ci)
+				}
+			}
+		}
+		if p.CountSolutions(2) != 1 {
+			return nil
+		}
+	}
+	for _, i := range rng.Perm(DIM * DIM) {
+		group := sym(Cell{i / DIM, i % DIM})
+		if DIM*DIM-p.remaining-len(group) < target {
+			continue
+		}
+		removeGroupIfRedundant(p, group)
+	}
+	p.MarkGivens()
+	p.backtracks = 0
+	return p
+}
+
+// removeGroupIfRedundant clears the cells of group unless doing so makes the
+// solution of p ambiguous, true if they were cleared
+func removeGroupIfRedundant(p *Game, group []Cell) bool {
+	for _, c := range group {
+		if p.board[c.Row][c.Col] == 0 {
+			return false
+		}
+	}
+	cp := p.Snapshot()
+	for _, c := range group {
+		p.UnmakeMove(c.Row, c.Col)
+	}
+	if p.CountSolutions(2) == 1 {
+		return true
+	}
+	p.Restore(cp)
+	return false
+}
+
+// removeIfRedundant clears cell c unless doing so makes the solution of p
+// ambiguous, true if it was cleared
+func removeIfRedundant(p *Game, c Cell) bool {
+	return removeGroupIfRedundant(p, []Cell{c})
+}
+
+// redundantClues returns the givens of a puzzle with a unique solution which
+// could each be removed without allowing a second solution
+func redundantClues(p *Game) []Cell {
+	g := p.Clone()
+	var result []Cell
+	for ri := range g.board {
+		for ci := range g.board[ri] {
+			if !g.given[ri][ci] {
+				continue
+			}
+			c := Cell{ri, ci}
+			if removeIfRedundant(g, c) {
+				result = append(result, c)
+				g.MakeMove(ri, ci, p.board[ri][ci])
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// SolutionGrade is the verdict on a player's attempt at a puzzle
+type SolutionGrade struct {
+	// Correct is true if the attempt is complete and solves the puzzle
+	Correct bool `json:"correct"`
+	// Wrong lists the cells in r1c1 notation which disagree with the
+	// solution, or for a puzzle without a unique solution, break the rules.
+	// A given which was changed is always wrong.
+	Wrong   []string `json:"wrong"`
+	Percent float64  `json:"percent"`
+	Unique  bool     `json:"unique"`
+}
+
+// GradeSolution checks attempt, a partly or completely filled board, against
+// the puzzle
+func GradeSolution(puzzle, attempt *Game) SolutionGrade {
+	g := puzzle.Clone()
+	var wrong []Cell
+	for ri, cols := range attempt.board {
+		for ci, val := range cols {
+			switch {
+			case puzzle.given[ri][ci] && val != puzzle.board[ri][ci]:
+				wrong = append(wrong, Cell{ri, ci})
+			case !puzzle.given[ri][ci] && val != 0:
+				g.MakeMove(ri, ci, val)
+			}
+		}
+	}
+	p := g.Progress()
+	if p.Unique {
+		wrong = append(wrong, p.Errors...)
+	} else {
+		// No solution to compare with, so check the rules instead
+		for _, c := range invalidCells(*g) {
+			if g.board[c.Row][c.Col] != 0 {
+				wrong = append(wrong, c)
+			}
+		}
+	}
+	wrong = sortCells(wrong)
+	grade := SolutionGrade{
+		Correct: len(wrong) == 0 && g.ValidSolution(),
+		Wrong:   make([]string, len(wrong)),
+		Percent: p.Percent(),
+		Unique:  p.Unique,
+	}
+	for i, c := range wrong {
+		grade.Wrong[i] = c.String()
+	}
+	return grade
+}
+
+// sortCells orders cells by row and then column, removing duplicates
+func sortCells(cells []Cell) []Cell {
+	seen := make(map[Cell]bool)
+	for _, c := range cells {
+		seen[c] = true
+	}
+	cells = cells[:0]
+	for ri := 0; ri < DIM; ri++ {
+		for ci := 0; ci < DIM; ci++ {
+			if seen[Cell{ri, ci}] {
+				cells = append(cells, Cell{ri, ci})
+			}
+		}
+	}
+	return cells
+}
+
+// gradeCommand handles: grade-solution <puzzle file> <attempt file>
+func gradeCommand(args []string) error {
+	fs := flag.NewFlagSet("grade-solution", flag.ExitOnError)
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		return fmt.Errorf("Usage: grade-solution <puzzle file> <attempt file>")
+	}
+	puzzle, err := readGame(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	attempt, err := readGame(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	grade := GradeSolution(puzzle, attempt)
+	fmt.Printf("Correct? %v\n", grade.Correct)
+	fmt.Printf("Filled: %.0f%%\n", grade.Percent)
+	if !grade.Unique {
+		fmt.Println("Warning: puzzle does not have a unique solution, only the rules were checked")
+	}
+	if len(grade.Wrong) > 0 {
+		fmt.Printf("Wrong: %v\n", strings.Join(grade.Wrong, " "))
+	}
+	return nil
+}
@@ -0,0 +1,1111 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// The GraphQL API supports the subset of the language needed to query this
+// schema: operations with variables, aliases, arguments and __typename, but
+// no fragments, directives or introspection.  Queries and mutations are
+// answered at POST /graphql and subscriptions over a WebSocket at the same
+// path, see graphQLSchema.
+
+// graphQLSchema describes the GraphQL API in the schema language, served by
+// GET /graphql
+const graphQLSchema = `type Query {
+  puzzles(difficulty: String, minClues: Int, maxClues: Int, tag: String, limit: Int): [Puzzle]
+  puzzle(id: Int!): Puzzle
+  random(difficulty: String, minClues: Int, maxClues: Int, tag: String): Puzzle
+  daily(difficulty: String, date: String): Puzzle
+  solve(puzzle: String!, algo: String): Solution
+  hint(puzzle: String!): Hint
+}
+
+type Subscription {
+  steps(puzzle: String!): Step
+}
+
+type Puzzle {
+  id: Int
+  puzzle: String
+  difficulty: String
+  clues: Int
+  source: String
+  tags: [String]
+  metadata: Metadata
+}
+
+type Metadata {
+  author: String
+  source: String
+  rating: String
+  tags: [String]
+}
+
+type Solution {
+  solved: Boolean
+  solution: String
+  checksum: String
+  backtracks: Int
+}
+
+type Hint {
+  found: Boolean
+  technique: String
+  cell: String
+  value: Int
+  eliminations: [String]
+  description: String
+}
+
+type Step {
+  technique: String
+  cell: String
+  value: Int
+  eliminations: [String]
+  description: String
+  grid: String
+}
+`
+
+// gqlTypes maps the object types of graphQLSchema to the types of their
+// fields.  Fields are read from the JSON encoding of the value resolved,
+// under the same name.
+var gqlTypes = map[string]map[string]string{
+	"Puzzle": {"id": "Int", "puzzle": "String", "difficulty": "String", "clues": "Int", "source": "String",
+		"tags": "[String]", "metadata": "Metadata"},
+	"Metadata": {"author": "String", "source": "String", "rating": "String", "tags": "[String]"},
+	"Solution": {"solved": "Boolean", "solution": "String", "checksum": "String", "backtracks": "Int"},
+	"Hint": {"found": "Boolean", "technique": "String", "cell": "String", "value": "Int",
+		"eliminations": "[String]", "description": "String"},
+	"Step": {"technique": "String", "cell": "String", "value": "Int", "eliminations": "[String]",
+		"description": "String", "grid": "String"},
+}
+
+// gqlField is a field of the Query or Subscription type
+type gqlField struct {
+	typ string
+	// args maps each argument to its type, ending in ! if it is required
+	args map[string]string
+	// resolve answers a query field, subscribe sends each event of a
+	// subscription field to emit until it returns false
+	resolve   func(s *server, args map[string]interface{}) (interface{}, error)
+	subscribe func(s *server, args map[string]interface{}, emit func(interface{}) bool) error
+}
+
+// gqlRequest is the body of a GraphQL request, and the payload of a
+// subscribe message
+type gqlRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// gqlError is an error of a GraphQL response, Path leads to the field which
+// failed
+type gqlError struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+// gqlResponse is the result of a GraphQL request, Data is left out if the
+// request couldn't be run at all
+type gqlResponse struct {
+	Data   interface{} `json:"data,omitempty"`
+	Errors []gqlError  `json:"errors,omitempty"`
+}
+
+// gqlObject is a JSON object which keeps its fields in the order of the
+// selection set, as GraphQL requires
+type gqlObject []gqlEntry
+
+type gqlEntry struct {
+	key   string
+	value interface{}
+}
+
+func (o gqlObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, e := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(e.key)
+		value, err := json.Marshal(e.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// Documents are parsed into operations holding trees of selections
+
+type gqlOperation struct {
+	// kind is query, mutation or subscription
+	kind string
+	name string
+	// vars are the variables defined by the operation
+	vars       []gqlVariableDef
+	selections []gqlSelection
+}
+
+type gqlVariableDef struct {
+	name     string
+	required bool
+	// def is the default value, nil if none
+	def interface{}
+}
+
+type gqlSelection struct {
+	alias, name string
+	args        map[string]interface{}
+	selections  []gqlSelection
+}
+
+// gqlVariable is a reference to a variable in an argument
+type gqlVariable string
+
+// gqlToken kinds
+const (
+	gqlEOF = iota
+	gqlPunct
+	gqlName
+	gqlInt
+	gqlFloat
+	gqlString
+)
+
+type gqlToken struct {
+	kind int
+	text string
+}
+
+// gqlParser is a recursive descent parser of GraphQL documents, reading
+// one token ahead
+type gqlParser struct {
+	src string
+	pos int
+	tok gqlToken
+}
+
+// advance reads the next token
+func (p *gqlParser) advance() error {
+	// Commas are insignificant, like white space
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if c == '#' {
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+		} else if c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',' {
+			p.pos++
+		} else {
+			break
+		}
+	}
+	if p.pos == len(p.src) {
+		p.tok = gqlToken{kind: gqlEOF}
+		return nil
+	}
+	start := p.pos
+	c := p.src[p.pos]
+	switch {
+	case strings.HasPrefix(p.src[p.pos:], "..."):
+		p.pos += 3
+		p.tok = gqlToken{gqlPunct, "..."}
+	case strings.IndexByte("!$&()/:=@[]{}|", c) >= 0:
+		p.pos++
+		p.tok = gqlToken{gqlPunct, string(c)}
+	case c == '_' || unicode.IsLetter(rune(c)):
+		for p.pos < len(p.src) && (p.src[p.pos] == '_' || unicode.IsLetter(rune(p.src[p.pos])) ||
+			unicode.IsDigit(rune(p.src[p.pos]))) {
+			p.pos++
+		}
+		p.tok = gqlToken{gqlName, p.src[start:p.pos]}
+	case c == '-' || unicode.IsDigit(rune(c)):
+		kind := gqlInt
+		p.pos++
+		for p.pos < len(p.src) {
+			c := p.src[p.pos]
+			if c == '.' || c == 'e' || c == 'E' || (c == '-' || c == '+') && kind == gqlFloat {
+				kind = gqlFloat
+			} else if !unicode.IsDigit(rune(c)) {
+				break
+			}
+			p.pos++
+		}
+		p.tok = gqlToken{kind, p.src[start:p.pos]}
+	case strings.HasPrefix(p.src[p.pos:], `"""`):
+		return fmt.Errorf("Block strings are not supported")
+	case c == '"':
+		// GraphQL strings escape characters as JSON does
+		for p.pos++; p.pos < len(p.src) && p.src[p.pos] != '"'; p.pos++ {
+			if p.src[p.pos] == '\\' {
+				p.pos++
+			}
+		}
+		if p.pos >= len(p.src) {
+			return fmt.Errorf("Unterminated string")
+		}
+		p.pos++
+		var s string
+		if err := json.Unmarshal([]byte(p.src[start:p.pos]), &s); err != nil {
+			return fmt.Errorf("Invalid string %v", p.src[start:p.pos])
+		}
+		p.tok = gqlToken{gqlString, s}
+	default:
+		return fmt.Errorf("Unexpected character %q", c)
+	}
+	return nil
+}
+
+// is true if the current token is the punctuator or name text
+func (p *gqlParser) is(text string) bool {
+	return (p.tok.kind == gqlPunct || p.tok.kind == gqlName) && p.tok.text == text
+}
+
+// expect consumes the punctuator text
+func (p *gqlParser) expect(text string) error {
+	if !p.is(text) {
+		return p.unexpected("expected " + text)
+	}
+	return p.advance()
+}
+
+func (p *gqlParser) unexpected(want string) error {
+	if p.tok.kind == gqlEOF {
+		return fmt.Errorf("Unexpected end of document, %v", want)
+	}
+	return fmt.Errorf("Unexpected %q, %v", p.tok.text, want)
+}
+
+// name consumes a name
+func (p *gqlParser) name() (string, error) {
+	if p.tok.kind != gqlName {
+		return "", p.unexpected("expected a name")
+	}
+	name := p.tok.text
+	return name, p.advance()
+}
+
+// parseGraphQL parses a document into its operations
+func parseGraphQL(src string) ([]gqlOperation, error) {
+	p := &gqlParser{src: src}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	var ops []gqlOperation
+	for p.tok.kind != gqlEOF {
+		op := gqlOperation{kind: "query"}
+		switch {
+		case p.is("{"):
+		case p.is("query"), p.is("mutation"), p.is("subscription"):
+			op.kind = p.tok.text
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			if p.tok.kind == gqlName {
+				op.name, _ = p.name()
+			}
+			if p.is("(") {
+				vars, err := p.variableDefs()
+				if err != nil {
+					return nil, err
+				}
+				op.vars = vars
+			}
+		case p.is("fragment"):
+			return nil, fmt.Errorf("Fragments are not supported")
+		default:
+			return nil, p.unexpected("expected an operation")
+		}
+		if p.is("@") {
+			return nil, fmt.Errorf("Directives are not supported")
+		}
+		sels, err := p.selectionSet()
+		if err != nil {
+			return nil, err
+		}
+		op.selections = sels
+		ops = append(ops, op)
+	}
+	if len(ops) == 0 {
+		return nil, fmt.Errorf("Document has no operations")
+	}
+	return ops, nil
+}
+
+// variableDefs parses the variable definitions of an operation, such as
+// ($id: Int!, $tag: String = "x-chain")
+func (p *gqlParser) variableDefs() ([]gqlVariableDef, error) {
+	var defs []gqlVariableDef
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	for !p.is(")") {
+		if err := p.expect("$"); err != nil {
+			return nil, err
+		}
+		var def gqlVariableDef
+		var err error
+		if def.name, err = p.name(); err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		// Only whether the outermost type is required matters here, the
+		// arguments check the types of the values
+		depth := 0
+		for {
+			switch {
+			case p.is("["):
+				depth++
+			case p.is("]"):
+				depth--
+			case p.tok.kind == gqlName:
+			default:
+				return nil, p.unexpected("expected a type")
+			}
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			if p.is("!") {
+				def.required = depth == 0
+				if err := p.advance(); err != nil {
+					return nil, err
+				}
+			}
+			if depth == 0 {
+				break
+			}
+		}
+		if p.is("=") {
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			if def.def, err = p.value(true); err != nil {
+				return nil, err
+			}
+		}
+		defs = append(defs, def)
+	}
+	return defs, p.advance()
+}
+
+// selectionSet parses the fields between braces
+func (p *gqlParser) selectionSet() ([]gqlSelection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var sels []gqlSelection
+	for !p.is("}") {
+		if p.is("...") {
+			return nil, fmt.Errorf("Fragments are not supported")
+		}
+		var sel gqlSelection
+		var err error
+		if sel.name, err = p.name(); err != nil {
+			return nil, err
+		}
+		sel.alias = sel.name
+		if p.is(":") {
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			if sel.name, err = p.name(); err != nil {
+				return nil, err
+			}
+		}
+		if p.is("(") {
+			if sel.args, err = p.arguments(); err != nil {
+				return nil, err
+			}
+		}
+		if p.is("@") {
+			return nil, fmt.Errorf("Directives are not supported")
+		}
+		if p.is("{") {
+			if sel.selections, err = p.selectionSet(); err != nil {
+				return nil, err
+			}
+		}
+		sels = append(sels, sel)
+	}
+	if len(sels) == 0 {
+		return nil, fmt.Errorf("Empty selection set")
+	}
+	return sels, p.advance()
+}
+
+// arguments parses the arguments of a field, such as (id: 3)
+func (p *gqlParser) arguments() (map[string]interface{}, error) {
+	args := make(map[string]interface{})
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	for !p.is(")") {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if args[name], err = p.value(false); err != nil {
+			return nil, err
+		}
+	}
+	return args, p.advance()
+}
+
+// value parses a value, which may refer to a variable unless constant is
+// true.  Enum values are read as strings.
+func (p *gqlParser) value(constant bool) (interface{}, error) {
+	tok := p.tok
+	switch {
+	case p.is("$") && !constant:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		return gqlVariable(name), err
+	case p.is("["):
+		list := []interface{}{}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		for !p.is("]") {
+			v, err := p.value(constant)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, p.advance()
+	case p.is("{"):
+		obj := make(map[string]interface{})
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		for !p.is("}") {
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			if obj[name], err = p.value(constant); err != nil {
+				return nil, err
+			}
+		}
+		return obj, p.advance()
+	case tok.kind == gqlInt, tok.kind == gqlFloat:
+		var n float64
+		if err := json.Unmarshal([]byte(tok.text), &n); err != nil {
+			return nil, fmt.Errorf("Invalid number %v", tok.text)
+		}
+		return n, p.advance()
+	case tok.kind == gqlString:
+		return tok.text, p.advance()
+	case tok.kind == gqlName:
+		var v interface{} = tok.text
+		switch tok.text {
+		case "true":
+			v = true
+		case "false":
+			v = false
+		case "null":
+			v = nil
+		}
+		return v, p.advance()
+	}
+	return nil, p.unexpected("expected a value")
+}
+
+// coerceArgs checks the arguments of sel against the field, replacing
+// variables with their values
+func coerceArgs(sel gqlSelection, f gqlField, vars map[string]interface{}) (map[string]interface{}, error) {
+	for name := range sel.args {
+		if _, ok := f.args[name]; !ok {
+			return nil, fmt.Errorf("Unknown argument %q on field %q", name, sel.name)
+		}
+	}
+	args := make(map[string]interface{})
+	for name, typ := range f.args {
+		v, ok := sel.args[name]
+		if ref, isVar := v.(gqlVariable); isVar {
+			if v, ok = vars[string(ref)]; !ok {
+				return nil, fmt.Errorf("Variable $%v is not defined", ref)
+			}
+		}
+		if !ok || v == nil {
+			if strings.HasSuffix(typ, "!") {
+				return nil, fmt.Errorf("Argument %q of field %q is required", name, sel.name)
+			}
+			continue
+		}
+		switch want := strings.TrimSuffix(typ, "!"); want {
+		case "Int":
+			n, isNum := v.(float64)
+			if !isNum || n != float64(int(n)) {
+				return nil, fmt.Errorf("Argument %q of field %q must be an Int", name, sel.name)
+			}
+			args[name] = int(n)
+		case "String":
+			if _, isString := v.(string); !isString {
+				return nil, fmt.Errorf("Argument %q of field %q must be a String", name, sel.name)
+			}
+			args[name] = v
+		}
+	}
+	return args, nil
+}
+
+// operationVars returns the values of the variables of op, from those given
+// with the request or their defaults
+func operationVars(op gqlOperation, given map[string]interface{}) (map[string]interface{}, error) {
+	vars := make(map[string]interface{})
+	for _, def := range op.vars {
+		v, ok := given[def.name]
+		if !ok {
+			v = def.def
+		}
+		if v == nil && def.required {
+			return nil, fmt.Errorf("Variable $%v is required", def.name)
+		}
+		vars[def.name] = v
+	}
+	return vars, nil
+}
+
+// selectOperation picks the operation of the document named by the request,
+// which may be left out if there is only one
+func selectOperation(req gqlRequest) (gqlOperation, map[string]interface{}, error) {
+	ops, err := parseGraphQL(req.Query)
+	if err != nil {
+		return gqlOperation{}, nil, err
+	}
+	var op *gqlOperation
+	for i := range ops {
+		if req.OperationName == "" && len(ops) == 1 || ops[i].name == req.OperationName {
+			op = &ops[i]
+		}
+	}
+	if op == nil && req.OperationName == "" {
+		return gqlOperation{}, nil, fmt.Errorf("Document has several operations, operationName must choose one")
+	} else if op == nil {
+		return gqlOperation{}, nil, fmt.Errorf("No operation named %q", req.OperationName)
+	}
+	vars, err := operationVars(*op, req.Variables)
+	return *op, vars, err
+}
+
+// complete shapes the value resolved for a field of type typ to its
+// selections.  v is first converted through JSON, so structs resolved are
+// read by the names of their JSON fields.
+func complete(typ string, v interface{}, sels []gqlSelection) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil, err
+	}
+	return completeValue(typ, generic, sels)
+}
+
+func completeValue(typ string, v interface{}, sels []gqlSelection) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if strings.HasPrefix(typ, "[") {
+		inner := strings.TrimSuffix(strings.TrimPrefix(typ, "["), "]")
+		items, _ := v.([]interface{})
+		result := make([]interface{}, len(items))
+		for i, item := range items {
+			var err error
+			if result[i], err = completeValue(inner, item, sels); err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	}
+	fields, isObject := gqlTypes[typ]
+	if !isObject {
+		if len(sels) > 0 {
+			return nil, fmt.Errorf("Field of type %v can't have selections", typ)
+		}
+		return v, nil
+	}
+	if len(sels) == 0 {
+		return nil, fmt.Errorf("Field of type %v must have selections", typ)
+	}
+	m, _ := v.(map[string]interface{})
+	var obj gqlObject
+	for _, sel := range sels {
+		if sel.name == "__typename" {
+			obj = append(obj, gqlEntry{sel.alias, typ})
+			continue
+		}
+		ft, ok := fields[sel.name]
+		if !ok {
+			return nil, fmt.Errorf("Cannot query field %q on type %v", sel.name, typ)
+		}
+		if len(sel.args) > 0 {
+			return nil, fmt.Errorf("Field %q of type %v takes no arguments", sel.name, typ)
+		}
+		value, err := completeValue(ft, m[sel.name], sel.selections)
+		if err != nil {
+			return nil, err
+		}
+		obj = append(obj, gqlEntry{sel.alias, value})
+	}
+	return obj, nil
+}
+
+// executeGraphQL runs a query, reporting errors of each root field with its
+// path while still answering the others
+func (s *server) executeGraphQL(req gqlRequest) gqlResponse {
+	op, vars, err := selectOperation(req)
+	if err != nil {
+		return gqlResponse{Errors: []gqlError{{Message: err.Error()}}}
+	}
+	switch op.kind {
+	case "subscription":
+		return gqlResponse{Errors: []gqlError{{Message: "Subscriptions need a WebSocket connection to /graphql"}}}
+	case "mutation":
+		return gqlResponse{Errors: []gqlError{{Message: "The schema has no mutations"}}}
+	}
+	var resp gqlResponse
+	var data gqlObject
+	for _, sel := range op.selections {
+		if sel.name == "__typename" {
+			data = append(data, gqlEntry{sel.alias, "Query"})
+			continue
+		}
+		value, err := s.resolveField(gqlQueryFields, sel, vars)
+		if err != nil {
+			resp.Errors = append(resp.Errors, gqlError{Message: err.Error(), Path: []interface{}{sel.alias}})
+		}
+		data = append(data, gqlEntry{sel.alias, value})
+	}
+	resp.Data = data
+	return resp
+}
+
+// resolveField resolves a root field of fields and completes the result
+func (s *server) resolveField(fields map[string]gqlField, sel gqlSelection, vars map[string]interface{}) (interface{}, error) {
+	f, ok := fields[sel.name]
+	if !ok || f.resolve == nil {
+		return nil, fmt.Errorf("Cannot query field %q on type Query", sel.name)
+	}
+	args, err := coerceArgs(sel, f, vars)
+	if err != nil {
+		return nil, err
+	}
+	v, err := f.resolve(s, args)
+	if err != nil {
+		return nil, err
+	}
+	return complete(f.typ, v, sel.selections)
+}
+
+// Helpers for reading the arguments of resolvers, which coerceArgs has
+// checked
+
+func argString(args map[string]interface{}, name string) string {
+	s, _ := args[name].(string)
+	return s
+}
+
+func argInt(args map[string]interface{}, name string) int {
+	n, _ := args[name].(int)
+	return n
+}
+
+// argPuzzleQuery reads the arguments selecting puzzles from a pack
+func argPuzzleQuery(args map[string]interface{}) (PuzzleQuery, error) {
+	q := PuzzleQuery{MinClues: argInt(args, "minClues"), MaxClues: argInt(args, "maxClues"),
+		Tag: argString(args, "tag"), Limit: argInt(args, "limit")}
+	if v := argString(args, "difficulty"); v != "" {
+		d, err := ParseDifficulty(v)
+		if err != nil {
+			return q, err
+		}
+		q.Difficulty = d.String()
+	}
+	return q, nil
+}
+
+// puzzleArgs are the arguments selecting puzzles from the pack
+var puzzleArgs = map[string]string{"difficulty": "String", "minClues": "Int", "maxClues": "Int", "tag": "String"}
+
+// gqlQueryFields are the fields of the Query type of graphQLSchema
+var gqlQueryFields = map[string]gqlField{
+	"puzzles": {
+		typ:  "[Puzzle]",
+		args: map[string]string{"difficulty": "String", "minClues": "Int", "maxClues": "Int", "tag": "String", "limit": "Int"},
+		resolve: func(s *server, args map[string]interface{}) (interface{}, error) {
+			q, err := argPuzzleQuery(args)
+			if err != nil {
+				return nil, err
+			}
+			return s.pack.Query(q), nil
+		},
+	},
+	"puzzle": {
+		typ:  "Puzzle",
+		args: map[string]string{"id": "Int!"},
+		resolve: func(s *server, args map[string]interface{}) (interface{}, error) {
+			for _, p := range s.pack.Puzzles {
+				if p.ID == argInt(args, "id") {
+					return p, nil
+				}
+			}
+			return nil, nil
+		},
+	},
+	"random": {
+		typ:  "Puzzle",
+		args: puzzleArgs,
+		resolve: func(s *server, args map[string]interface{}) (interface{}, error) {
+			q, err := argPuzzleQuery(args)
+			if err != nil {
+				return nil, err
+			}
+			if p, ok := s.pack.Random(q, nil); ok {
+				return p, nil
+			}
+			return nil, nil
+		},
+	},
+	"daily": {
+		typ:  "Puzzle",
+		args: map[string]string{"difficulty": "String", "date": "String"},
+		resolve: func(s *server, args map[string]interface{}) (interface{}, error) {
+			d := s.dailyDifficulty
+			if v := argString(args, "difficulty"); v != "" {
+				var err error
+				if d, err = ParseDifficulty(v); err != nil {
+					return nil, err
+				}
+			}
+			date := time.Now().UTC().Format(time.DateOnly)
+			if v := argString(args, "date"); v != "" {
+				if _, err := time.Parse(time.DateOnly, v); err != nil {
+					return nil, fmt.Errorf("Invalid date %q, expected YYYY-MM-DD", v)
+				}
+				date = v
+			}
+			if p, ok := dailyPuzzle(s.pack, d, date); ok {
+				return p, nil
+			}
+			return nil, nil
+		},
+	},
+	"solve": {
+		typ:  "Solution",
+		args: map[string]string{"puzzle": "String!", "algo": "String"},
+		resolve: func(s *server, args map[string]interface{}) (interface{}, error) {
+			g, err := ParseLine(argString(args, "puzzle"))
+			if err != nil {
+				return nil, err
+			}
+			return rpcSolve(rpcPuzzleParams{Algo: argString(args, "algo")}, g)
+		},
+	},
+	"hint": {
+		typ:  "Hint",
+		args: map[string]string{"puzzle": "String!"},
+		resolve: func(s *server, args map[string]interface{}) (interface{}, error) {
+			g, err := ParseLine(argString(args, "puzzle"))
+			if err != nil {
+				return nil, err
+			}
+			return rpcHint(rpcPuzzleParams{}, g)
+		},
+	},
+}
+
+// gqlStep is a Step of the steps subscription, with the grid after it
+type gqlStep struct {
+	Technique    string   `json:"technique"`
+	Cell         string   `json:"cell,omitempty"`
+	Value        int      `json:"value,omitempty"`
+	Eliminations []string `json:"eliminations,omitempty"`
+	Description  string   `json:"description"`
+	Grid         string   `json:"grid"`
+}
+
+// gqlSubscriptionFields are the fields of the Subscription type of
+// graphQLSchema
+var gqlSubscriptionFields = map[string]gqlField{
+	"steps": {
+		typ:  "Step",
+		args: map[string]string{"puzzle": "String!"},
+		subscribe: func(s *server, args map[string]interface{}, emit func(interface{}) bool) error {
+			g, err := ParseLine(argString(args, "puzzle"))
+			if err != nil {
+				return err
+			}
+			st := NewStrategist(g)
+			for !g.ValidSolution() && st.Advance() {
+				step := st.Steps[len(st.Steps)-1]
+				event := gqlStep{Technique: step.Technique, Value: step.Value, Description: step.String(),
+					Grid: g.Line()}
+				if step.Value != 0 {
+					event.Cell = step.Cell.String()
+				}
+				for _, e := range step.Eliminations {
+					event.Eliminations = append(event.Eliminations, e.String())
+				}
+				if !emit(event) {
+					return nil
+				}
+			}
+			return nil
+		},
+	},
+}
+
+// handleGraphQL serves /graphql: a POST of a JSON gqlRequest, or a GET with
+// the query, operationName and variables query parameters, answers queries.
+// A WebSocket upgrade speaks the graphql-transport-ws protocol for
+// subscriptions, and a GET without a query returns graphQLSchema.
+func (s *server) handleGraphQL(w http.ResponseWriter, r *http.Request) {
+	var req gqlRequest
+	switch {
+	case r.Method == http.MethodGet && headerContains(r.Header, "Upgrade", "websocket"):
+		s.serveGraphQLWebSocket(w, r)
+		return
+	case r.Method == http.MethodGet && r.URL.Query().Get("query") == "":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprint(w, graphQLSchema)
+		return
+	case r.Method == http.MethodGet:
+		req.Query = r.URL.Query().Get("query")
+		req.OperationName = r.URL.Query().Get("operationName")
+		if v := r.URL.Query().Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				http.Error(w, fmt.Sprintf("Invalid variables: %v", err), http.StatusBadRequest)
+				return
+			}
+		}
+	case r.Method == http.MethodPost:
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes)).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	resp := s.executeGraphQL(req)
+	if resp.Data == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(resp)
+		return
+	}
+	writeJSON(w, resp)
+}
+
+// graphQLWebSocketProtocol is the subprotocol of WebSocket connections to
+// /graphql
+const graphQLWebSocketProtocol = "graphql-transport-ws"
+
+// gqlMessage is a message of the graphql-transport-ws protocol
+type gqlMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// gqlConn is a WebSocket connection to /graphql
+type gqlConn struct {
+	conn *wsConn
+	mu   sync.Mutex
+	// ops maps the id of each operation running to the channel closed to
+	// cancel it
+	ops map[string]chan struct{}
+}
+
+func (c *gqlConn) send(id, typ string, payload interface{}) {
+	msg := gqlMessage{ID: id, Type: typ}
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			slog.Error("graphql message", "err", err)
+			return
+		}
+		msg.Payload = data
+	}
+	data, _ := json.Marshal(msg)
+	if err := c.conn.WriteMessage(data); err != nil {
+		slog.Debug("graphql send", "err", err)
+	}
+}
+
+// finish forgets the operation id, false if it was already cancelled
+func (c *gqlConn) finish(id string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.ops[id]; !ok {
+		return false
+	}
+	delete(c.ops, id)
+	return true
+}
+
+// cancel stops the operation id, if it is running
+func (c *gqlConn) cancel(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if done, ok := c.ops[id]; ok {
+		close(done)
+		delete(c.ops, id)
+	}
+}
+
+func (s *server) serveGraphQLWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgradeWebSocket(w, r, graphQLWebSocketProtocol)
+	if err != nil {
+		slog.Debug("graphql upgrade", "err", err)
+		return
+	}
+	defer conn.Close()
+	c := &gqlConn{conn: conn, ops: make(map[string]chan struct{})}
+	defer func() {
+		c.mu.Lock()
+		for id, done := range c.ops {
+			close(done)
+			delete(c.ops, id)
+		}
+		c.mu.Unlock()
+	}()
+	acked := false
+	for {
+		data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var msg gqlMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			slog.Debug("graphql message", "err", err)
+			return
+		}
+		switch {
+		case msg.Type == "connection_init" && !acked:
+			acked = true
+			c.send("", "connection_ack", nil)
+		case msg.Type == "ping":
+			c.send("", "pong", nil)
+		case msg.Type == "pong":
+		case msg.Type == "subscribe" && acked:
+			s.startGraphQLOperation(c, msg)
+		case msg.Type == "complete":
+			c.cancel(msg.ID)
+		default:
+			// The protocol closes the connection on anything unexpected,
+			// including a subscribe before the connection is acknowledged
+			slog.Debug("graphql message", "type", msg.Type)
+			return
+		}
+	}
+}
+
+// startGraphQLOperation runs the operation of a subscribe message, sending
+// its results as next messages followed by complete
+func (s *server) startGraphQLOperation(c *gqlConn, msg gqlMessage) {
+	fail := func(err error) {
+		c.send(msg.ID, "error", []gqlError{{Message: err.Error()}})
+	}
+	var req gqlRequest
+	if err := json.Unmarshal(msg.Payload, &req); err != nil {
+		fail(fmt.Errorf("Invalid payload: %v", err))
+		return
+	}
+	c.mu.Lock()
+	if _, ok := c.ops[msg.ID]; ok || msg.ID == "" {
+		c.mu.Unlock()
+		fail(fmt.Errorf("Operation id %q is missing or already in use", msg.ID))
+		return
+	}
+	done := make(chan struct{})
+	c.ops[msg.ID] = done
+	c.mu.Unlock()
+
+	op, vars, err := selectOperation(req)
+	if err == nil && op.kind != "subscription" {
+		resp := s.executeGraphQL(req)
+		if c.finish(msg.ID) {
+			c.send(msg.ID, "next", resp)
+			c.send(msg.ID, "complete", nil)
+		}
+		return
+	}
+	var sel gqlSelection
+	var f gqlField
+	var args map[string]interface{}
+	if err == nil && len(op.selections) != 1 {
+		err = fmt.Errorf("A subscription must select exactly one field")
+	}
+	if err == nil {
+		sel = op.selections[0]
+		var ok bool
+		if f, ok = gqlSubscriptionFields[sel.name]; !ok {
+			err = fmt.Errorf("Cannot query field %q on type Subscription", sel.name)
+		}
+	}
+	if err == nil {
+		args, err = coerceArgs(sel, f, vars)
+	}
+	if err != nil {
+		c.finish(msg.ID)
+		fail(err)
+		return
+	}
+	go func() {
+		err := f.subscribe(s, args, func(v interface{}) bool {
+			select {
+			case <-done:
+				return false
+			default:
+			}
+			value, err := complete(f.typ, v, sel.selections)
+			resp := gqlResponse{Data: gqlObject{{sel.alias, value}}}
+			if err != nil {
+				resp = gqlResponse{Data: gqlObject{{sel.alias, nil}},
+					Errors: []gqlError{{Message: err.Error(), Path: []interface{}{sel.alias}}}}
+			}
+			c.send(msg.ID, "next", resp)
+			return err == nil
+		})
+		if !c.finish(msg.ID) {
+			return
+		}
+		if err != nil {
+			fail(err)
+			return
+		}
+		c.send(msg.ID, "complete", nil)
+	}()
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// Results keep the order and aliases of the selections, and a failing field
+// is reported at its path while the others are still answered
+func TestExecuteGraphQL(t *testing.T) {
+	g, err := readGame("hard.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pack := &Pack{Puzzles: []PackPuzzle{{ID: 1, Puzzle: g.Line(), Difficulty: "hard", Clues: 26}}}
+	s := &server{pack: pack}
+	resp := s.executeGraphQL(gqlRequest{
+		Query: `query Q($id: Int!, $p: String = "x") {
+			first: puzzle(id: $id) { clues id __typename }
+			solve(puzzle: $p) { solved }
+		}`,
+		Variables: map[string]interface{}{"id": 1.0},
+	})
+	data, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"data":{"first":{"clues":26,"id":1,"__typename":"Puzzle"},"solve":null},` +
+		`"errors":[{"message":"Expected 81 cells in line, found 0","path":["solve"]}]}`
+	if string(data) != want {
+		t.Errorf("got %s\nwant %s", data, want)
+	}
+}
+
+func TestParseGraphQLErrors(t *testing.T) {
+	for _, query := range []string{
+		``,
+		`{ }`,
+		`{ puzzle(id: 1 { id } }`,
+		`{ puzzles { ...F } }`,
+		`query { puzzle(id: "1) { id } }`,
+		`{ puzzle(id: 1) @skip(if: true) { id } }`,
+	} {
+		if _, err := parseGraphQL(query); err == nil {
+			t.Errorf("parseGraphQL(%q) succeeded", query)
+		}
+	}
+}
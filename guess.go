@@ -0,0 +1,91 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// Guess is a value to try in an empty cell, rated by how evenly it splits
+// the solutions of the puzzle
+type Guess struct {
+	Candidate
+	// Probability is the fraction of the solutions placing the value in the
+	// cell
+	Probability float64
+	// Bits is the information learned by trying the guess, the entropy of
+	// the split into the solutions with and without it, 1 for an even split
+	Bits float64
+}
+
+// String describes the guess for the command line
+func (g Guess) String() string {
+	return fmt.Sprintf("%v=%v  %.0f%% of solutions  %.2f bits", g.Cell, g.Value, 100*g.Probability, g.Bits)
+}
+
+// splitBits is the entropy in bits of a yes or no question answered yes with
+// probability p
+func splitBits(p float64) float64 {
+	if p <= 0 || p >= 1 {
+		return 0
+	}
+	return -p*math.Log2(p) - (1-p)*math.Log2(1-p)
+}
+
+// Guesses lists the values of the empty cells of puzzle which some but not
+// all of the solutions counted place there, the most even splits of the
+// solutions first and then in board order
+func (d *Distribution) Guesses(puzzle *Game) []Guess {
+	var result []Guess
+	for ri, row := range puzzle.board {
+		for ci, val := range row {
+			if val != 0 {
+				continue
+			}
+			for v := 1; v <= DIM; v++ {
+				if p := d.Probability(ri, ci, v); p > 0 && p < 1 {
+					result = append(result, Guess{Candidate{Cell{ri, ci}, v}, p, splitBits(p)})
+				}
+			}
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Bits > result[j].Bits
+	})
+	return result
+}
+
+// guessCommand handles: guess [flags] <puzzle file>, suggesting the values
+// to try which best split the solutions of an under-constrained puzzle
+func guessCommand(args []string) error {
+	fs := flag.NewFlagSet("guess", flag.ExitOnError)
+	n := fs.Int("n", 1, "suggest the best `n` guesses")
+	limit := fs.Int("limit", distributionLimit, "count every solution if there are at most `n`, else sample them")
+	samples := fs.Int("samples", distributionSamples, "number of solutions to sample when there are too many to count")
+	seed := fs.Int64("seed", time.Now().UnixNano(), "random seed, for repeatable samples")
+	fs.Parse(args)
+	if fs.NArg() != 1 || *n < 1 || *limit < 1 || *samples < 1 {
+		return fmt.Errorf("Usage: guess [flags] <puzzle file>")
+	}
+	board, err := readGame(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if !solvable(board) {
+		return fmt.Errorf("Puzzle has no solution")
+	}
+	d := board.SolutionDistribution(*limit, *samples, rand.New(rand.NewSource(*seed)))
+	fmt.Println(d.title())
+	guesses := d.Guesses(board)
+	if len(guesses) == 0 {
+		fmt.Println("Every solution counted agrees, there is nothing to guess")
+		return nil
+	}
+	for _, g := range guesses[:min(*n, len(guesses))] {
+		fmt.Println(g)
+	}
+	return nil
+}
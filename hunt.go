@@ -0,0 +1,99 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// huntScore rates a puzzle for the hunt command, higher is harder.  Puzzles
+// are compared by difficulty grade first and backtracks second, unless
+// byBacktracks is set.
+func huntScore(p *Game, byBacktracks bool) (score int, grade Difficulty, backtracks int) {
+	s := NewStrategist(p.Clone())
+	s.Solve()
+	grade = s.Difficulty()
+	g := p.Clone()
+	recursiveSolver(g, nil)
+	backtracks = g.backtracks
+	if byBacktracks {
+		return backtracks, grade, backtracks
+	}
+	return int(grade)*1000000 + backtracks, grade, backtracks
+}
+
+// mutatePuzzle moves a random clue of p elsewhere, keeping to the solution
+// grid, then adds clues until it is unique again and removes any which became
+// redundant
+func mutatePuzzle(p, solution *Game, rng *rand.Rand) *Game {
+	m := p.Clone()
+	var filled, empty []Cell
+	for ri := range m.board {
+		for ci, val := range m.board[ri] {
+			if val == 0 {
+				empty = append(empty, Cell{ri, ci})
+			} else {
+				filled = append(filled, Cell{ri, ci})
+			}
+		}
+	}
+	from := filled[rng.Intn(len(filled))]
+	to := empty[rng.Intn(len(empty))]
+	m.UnmakeMove(from.Row, from.Col)
+	m.MakeMove(to.Row, to.Col, solution.board[to.Row][to.Col])
+	for m.CountSolutions(2) > 1 {
+		c := randomEmptyCell(m, rng)
+		m.MakeMove(c.Row, c.Col, solution.board[c.Row][c.Col])
+	}
+	for _, i := range rng.Perm(DIM * DIM) {
+		removeIfRedundant(m, Cell{i / DIM, i % DIM})
+	}
+	m.MarkGivens()
+	m.backtracks = 0
+	return m
+}
+
+func randomEmptyCell(g *Game, rng *rand.Rand) Cell {
+	for {
+		c := Cell{rng.Intn(DIM), rng.Intn(DIM)}
+		if g.board[c.Row][c.Col] == 0 {
+			return c
+		}
+	}
+}
+
+// huntCommand handles: hunt [flags], hill climbing from a random puzzle
+// towards the hardest puzzle it can find
+func huntCommand(args []string) error {
+	fs := flag.NewFlagSet("hunt", flag.ExitOnError)
+	iterations := fs.Int("iterations", 500, "number of mutations to try")
+	seed := fs.Int64("seed", time.Now().UnixNano(), "random number generator seed")
+	byBacktracks := fs.Bool("backtracks", false, "score puzzles by backtracks instead of difficulty grade")
+	fs.Parse(args)
+	if fs.NArg() != 0 {
+		return fmt.Errorf("Usage: hunt [flags]")
+	}
+
+	rng := rand.New(rand.NewSource(*seed))
+	solution := randomSolution(rng)
+	best := carvePuzzle(solution, rng)
+	bestScore, grade, backtracks := huntScore(best, *byBacktracks)
+	fmt.Printf("Seed %v, starting at %v, backtracks: %v\n", *seed, grade, backtracks)
+	for i := 1; i <= *iterations; i++ {
+		candidate := mutatePuzzle(best, solution, rng)
+		score, grade, backtracks := huntScore(candidate, *byBacktracks)
+		if score < bestScore {
+			continue
+		}
+		if score > bestScore {
+			fmt.Printf("%v: %v, backtracks: %v, %v\n", i, grade, backtracks, candidate.Line())
+		}
+		// Accept sideways moves too, to wander across plateaus
+		best, bestScore = candidate, score
+	}
+
+	fmt.Println("\nHardest puzzle found:")
+	fmt.Println(best)
+	return nil
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// importTimeout limits fetching a page to import
+const importTimeout = 30 * time.Second
+
+// maxPageBytes limits the size of an imported page
+const maxPageBytes = 8 << 20
+
+// importers parse the pages of each supported site, returning the puzzles
+// with their source and the site's rating as metadata
+var importers = map[string]func(page string) ([]*Puzzle, error){
+	"nyt":             importNYT,
+	"sudoku-exchange": importSudokuExchange,
+	"websudoku":       importWebSudoku,
+}
+
+// detectSite guesses which site a page was saved from
+func detectSite(page string) (string, error) {
+	switch {
+	case exchangeLine.MatchString(page):
+		return "sudoku-exchange", nil
+	case strings.Contains(page, "puzzle_data"):
+		return "nyt", nil
+	case strings.Contains(page, "cheat") || strings.Contains(strings.ToLower(page), `id="f00"`):
+		return "websudoku", nil
+	}
+	return "", fmt.Errorf("Could not recognize the page, expected one saved from nytimes.com or " +
+		"websudoku.com, or a Sudoku Exchange puzzle bank")
+}
+
+// exchangeLine matches the first line of a Sudoku Exchange puzzle bank, a
+// hex ID, 81 digits, and a rating
+var exchangeLine = regexp.MustCompile(`^\s*[0-9a-f]{12}\s+[0-9.]{81}\s+[0-9.]+\s*(\n|$)`)
+
+// importSudokuExchange reads the lines of a Sudoku Exchange puzzle bank, each
+// holding an ID, the puzzle in 81 digits and the bank's difficulty rating
+func importSudokuExchange(bank string) ([]*Puzzle, error) {
+	var result []*Puzzle
+	for i, line := range strings.Split(bank, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("Line %v: expected an ID, a puzzle and a rating", i+1)
+		}
+		g, err := ParseLine(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("Line %v: %v", i+1, err)
+		}
+		if _, err := strconv.ParseFloat(fields[2], 64); err != nil {
+			return nil, fmt.Errorf("Line %v: invalid rating %q", i+1, fields[2])
+		}
+		result = append(result, &Puzzle{Game: g, Metadata: Metadata{
+			Source: "Sudoku Exchange " + fields[0],
+			Rating: fields[2],
+		}})
+	}
+	return result, nil
+}
+
+// ratingStats summarizes the ratings of the puzzles given one difficulty by
+// our grader
+type ratingStats struct {
+	count         int
+	sum, min, max float64
+}
+
+// compareRatings grades each puzzle with the strategy engine and prints the
+// spread of the site's ratings for each difficulty
+func compareRatings(puzzles []*Puzzle) {
+	stats := make(map[Difficulty]*ratingStats)
+	for _, p := range puzzles {
+		rating, _ := strconv.ParseFloat(p.Rating, 64)
+		s := NewStrategist(p.Game.Clone())
+		s.Solve()
+		d := s.Difficulty()
+		rs := stats[d]
+		if rs == nil {
+			rs = &ratingStats{min: rating, max: rating}
+			stats[d] = rs
+		}
+		rs.count++
+		rs.sum += rating
+		rs.min = min(rs.min, rating)
+		rs.max = max(rs.max, rating)
+	}
+	fmt.Printf("%-18v %7v %7v %7v %7v\n", "Difficulty", "Puzzles", "Rating", "Min", "Max")
+	for d := Easy; d <= RequiresGuessing; d++ {
+		if rs := stats[d]; rs != nil {
+			fmt.Printf("%-18v %7v %7.2f %7.2f %7.2f\n", d, rs.count, rs.sum/float64(rs.count), rs.min, rs.max)
+		}
+	}
+}
+
+// nytPuzzle is a puzzle of the gameData object of the NYT Sudoku page
+type nytPuzzle struct {
+	Difficulty string `json:"difficulty"`
+	PrintDate  string `json:"print_date"`
+	PuzzleID   int    `json:"puzzle_id"`
+	PuzzleData struct {
+		Puzzle []int `json:"puzzle"`
+	} `json:"puzzle_data"`
+}
+
+// importNYT reads the easy, medium and hard puzzles from the gameData object
+// of a saved NYT Sudoku page, or from that object saved as JSON
+func importNYT(page string) ([]*Puzzle, error) {
+	data := strings.TrimSpace(page)
+	if i := strings.Index(data, "gameData"); i >= 0 && !strings.HasPrefix(data, "{") {
+		j := strings.Index(data[i:], "{")
+		if j < 0 {
+			return nil, fmt.Errorf("NYT page has no gameData object")
+		}
+		data = data[i+j:]
+	}
+	// Decode stops at the end of the object, ignoring the script after it
+	var game map[string]json.RawMessage
+	if err := json.NewDecoder(strings.NewReader(data)).Decode(&game); err != nil {
+		return nil, fmt.Errorf("NYT gameData: %v", err)
+	}
+	var result []*Puzzle
+	for _, level := range []string{"easy", "medium", "hard"} {
+		raw, ok := game[level]
+		if !ok {
+			continue
+		}
+		var p nytPuzzle
+		if err := json.Unmarshal(raw, &p); err != nil {
+			return nil, fmt.Errorf("NYT %v puzzle: %v", level, err)
+		}
+		if len(p.PuzzleData.Puzzle) != DIM*DIM {
+			return nil, fmt.Errorf("NYT %v puzzle has %v cells, expected %v", level, len(p.PuzzleData.Puzzle),
+				DIM*DIM)
+		}
+		var sb strings.Builder
+		for _, val := range p.PuzzleData.Puzzle {
+			if val < 0 || DIM < val {
+				return nil, fmt.Errorf("NYT %v puzzle has invalid value %v", level, val)
+			}
+			sb.WriteByte(byte('0' + val))
+		}
+		g, err := ParseLine(sb.String())
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, &Puzzle{Game: g, Metadata: Metadata{
+			Source: fmt.Sprintf("NYT %v, puzzle %v", p.PrintDate, p.PuzzleID),
+			Rating: level,
+		}})
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("NYT gameData has no puzzles")
+	}
+	return result, nil
+}
+
+var (
+	inputTag  = regexp.MustCompile(`(?is)<input\b[^>]*>`)
+	attribute = regexp.MustCompile(`(?is)\b([a-z]+)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+)
+
+// inputAttributes returns the attributes of each input element of page, with
+// lower case names
+func inputAttributes(page string) []map[string]string {
+	var inputs []map[string]string
+	for _, tag := range inputTag.FindAllString(page, -1) {
+		attrs := make(map[string]string)
+		for _, m := range attribute.FindAllStringSubmatch(tag, -1) {
+			attrs[strings.ToLower(m[1])] = m[2] + m[3] + m[4]
+		}
+		if strings.Contains(strings.ToLower(tag), "readonly") {
+			attrs["readonly"] = "readonly"
+		}
+		inputs = append(inputs, attrs)
+	}
+	return inputs
+}
+
+// importWebSudoku reads the puzzle of a saved websudoku.com page.  The page
+// holds the solution in the hidden cheat input, with the hidden editmask
+// marking the givens with 0; failing that, the givens are the read only
+// inputs of the board, identified as f followed by column and row.
+func importWebSudoku(page string) ([]*Puzzle, error) {
+	byID := make(map[string]map[string]string)
+	for _, attrs := range inputAttributes(page) {
+		id := attrs["id"]
+		if id == "" {
+			id = attrs["name"]
+		}
+		byID[strings.ToLower(id)] = attrs
+	}
+	source := "websudoku.com"
+	if pid := byID["pid"]["value"]; pid != "" {
+		source += ", puzzle " + pid
+	}
+	cells := []byte(strings.Repeat("0", DIM*DIM))
+	cheat, mask := byID["cheat"]["value"], byID["editmask"]["value"]
+	switch {
+	case len(cheat) == DIM*DIM && len(mask) == DIM*DIM:
+		for i := range cells {
+			if mask[i] == '0' {
+				cells[i] = cheat[i]
+			}
+		}
+	default:
+		found := false
+		for row := 0; row < DIM; row++ {
+			for col := 0; col < DIM; col++ {
+				attrs := byID[fmt.Sprintf("f%v%v", col, row)]
+				if attrs == nil {
+					continue
+				}
+				found = true
+				if v := attrs["value"]; attrs["readonly"] != "" && len(v) == 1 {
+					cells[row*DIM+col] = v[0]
+				}
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("websudoku page has no puzzle")
+		}
+	}
+	g, err := ParseLine(string(cells))
+	if err != nil {
+		return nil, fmt.Errorf("websudoku puzzle: %v", err)
+	}
+	return []*Puzzle{{Game: g, Metadata: Metadata{Source: source}}}, nil
+}
+
+// readPage reads a saved page, or fetches it if name is an http or https URL
+func readPage(name string) (string, error) {
+	if !strings.HasPrefix(name, "http://") && !strings.HasPrefix(name, "https://") {
+		data, err := os.ReadFile(name)
+		return string(data), err
+	}
+	client := &http.Client{Timeout: importTimeout}
+	resp, err := client.Get(name)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Fetching %v: %v", name, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxPageBytes))
+	return string(data), err
+}
+
+// importCommand handles: import [flags] <saved page or URL>, printing the
+// puzzles of a page from a puzzle site or a puzzle bank, each after metadata
+// comments naming its source so the output can be read by batch and pack
+func importCommand(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	site := fs.String("site", "auto", "site the page is from: auto, nyt, sudoku-exchange or websudoku")
+	difficulty := fs.String("difficulty", "", "only import the NYT puzzle of this difficulty: easy, medium or hard")
+	output := fs.String("o", "line", "output format: "+strings.Join(formatNames, ", "))
+	compare := fs.Bool("compare", false, "print how the site's ratings compare with our grades instead")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("Usage: import [flags] <saved page or URL>")
+	}
+	format, err := ParseFormat(*output)
+	if err != nil {
+		return err
+	}
+	page, err := readPage(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *site == "auto" {
+		if *site, err = detectSite(page); err != nil {
+			return err
+		}
+	}
+	importer, ok := importers[*site]
+	if !ok {
+		return fmt.Errorf("Unknown site %q, expected auto, nyt, sudoku-exchange or websudoku", *site)
+	}
+	puzzles, err := importer(page)
+	if err != nil {
+		return err
+	}
+	if *compare {
+		if *site != "sudoku-exchange" {
+			return fmt.Errorf("Only Sudoku Exchange puzzles have ratings to compare")
+		}
+		compareRatings(puzzles)
+		return nil
+	}
+	found := false
+	for _, p := range puzzles {
+		if *difficulty != "" && p.Rating != *difficulty {
+			continue
+		}
+		found = true
+		if err := RenderPuzzle(os.Stdout, p, format); err != nil {
+			return err
+		}
+	}
+	if !found {
+		return fmt.Errorf("No %v puzzle in the page", *difficulty)
+	}
+	return nil
+}
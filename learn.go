@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// learnAttempts is the number of random puzzles searched for a position
+// demonstrating a technique before giving up
+const learnAttempts = 200
+
+// lesson is a position where the technique being taught is the easiest way
+// forward
+type lesson struct {
+	s    *Strategist
+	step *Step
+	// solution is used to tell a true deduction from a wrong one
+	solution *Game
+}
+
+// findLesson carves random puzzles and solves each with the techniques easier
+// than t until they stall, returning the first position where t applies, or
+// nil if none of learnAttempts puzzles has one
+func findLesson(rng *rand.Rand, t technique) *lesson {
+	for i := 0; i < learnAttempts; i++ {
+		solution := randomSolution(rng)
+		s := NewStrategist(carvePuzzle(solution, rng))
+		for progress := true; progress && !s.game.ValidSolution(); {
+			progress = false
+			for _, easier := range techniques {
+				if easier.id == t.id {
+					break
+				}
+				if s.try(easier) {
+					progress = true
+					break
+				}
+			}
+		}
+		if step := t.find(s); step != nil {
+			step.Technique = t.name
+			return &lesson{s, step, solution}
+		}
+	}
+	return nil
+}
+
+// parseDeduction reads an answer such as r1c2=5 placing a value, or r1c2<>5
+// eliminating a candidate
+func parseDeduction(s string) (c Candidate, eliminate bool, err error) {
+	name, val, ok := strings.Cut(s, "<>")
+	eliminate = ok
+	if !ok {
+		name, val, ok = strings.Cut(s, "=")
+	}
+	if !ok {
+		return c, false, fmt.Errorf("Expected a placement such as r1c2=5 or an elimination such as r1c2<>5")
+	}
+	if c.Cell, err = ParseCell(strings.TrimSpace(name)); err != nil {
+		return c, false, err
+	}
+	if c.Value, err = strconv.Atoi(strings.TrimSpace(val)); err != nil || c.Value < 1 || DIM < c.Value {
+		return c, false, fmt.Errorf("Invalid value %q, expected 1 through %v", val, DIM)
+	}
+	return c, eliminate, nil
+}
+
+// check compares an answer to the lesson, describing why it isn't the
+// deduction wanted, or returning "" if it is
+func (l *lesson) check(c Candidate, eliminate bool) string {
+	if l.s.game.board[c.Row][c.Col] != 0 {
+		return fmt.Sprintf("%v is already filled", c.Cell)
+	}
+	if !l.s.has(c.Cell, c.Value) {
+		return fmt.Sprintf("%v isn't a candidate of %v", c.Value, c.Cell)
+	}
+	if solved := l.solution.board[c.Row][c.Col] == c.Value; solved == eliminate {
+		return "That's not right, look again"
+	}
+	if !eliminate && l.step.Value != 0 && l.step.Cell == c.Cell && l.step.Value == c.Value {
+		return ""
+	}
+	for _, e := range l.step.Eliminations {
+		if eliminate && e == c {
+			return ""
+		}
+	}
+	return fmt.Sprintf("That's true, but it isn't the %v this position shows", l.step.Technique)
+}
+
+// hint points towards the deduction of the lesson without giving it away: the
+// cell of a naked single, the unit of a hidden single, and otherwise the digit
+func (l *lesson) hint() string {
+	switch l.step.Technique {
+	case "Naked Single":
+		return fmt.Sprintf("Look at the candidates left in %v", l.step.Cell)
+	case "Hidden Single":
+		// The first unit where the cell is the only place for the value is
+		// the one findHiddenSingle found it in
+		for i, unit := range units {
+			if cells := l.s.cellsWith(unit, l.step.Value); len(cells) == 1 && cells[0] == l.step.Cell {
+				return fmt.Sprintf("Look at where digit %v can go in %v", l.step.Value, unitName(i))
+			}
+		}
+	}
+	if l.step.Value != 0 {
+		return fmt.Sprintf("Look at where digit %v can go", l.step.Value)
+	}
+	return fmt.Sprintf("Look at where digit %v can go", l.step.Eliminations[0].Value)
+}
+
+// teach shows a lesson and checks answers read from in until one is right,
+// the user gives up with a blank line, or in runs out, false in that case
+func (l *lesson) teach(in *bufio.Reader, out io.Writer) bool {
+	fmt.Fprintln(out, l.s.game)
+	fmt.Fprintln(out, "\nCandidates:")
+	fmt.Fprintln(out, l.s.PencilMarks())
+	for {
+		fmt.Fprintf(out, "\nFind a %v step.  Enter a placement such as r1c2=5 or an elimination such as\n"+
+			"r1c2<>5, ? for a hint, or nothing to see the answer: ", l.step.Technique)
+		line, err := in.ReadString('\n')
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "" && err != nil:
+			fmt.Fprintln(out)
+			return false
+		case line == "":
+			fmt.Fprintf(out, "The answer was %v\n", *l.step)
+			return true
+		case line == "?":
+			fmt.Fprintln(out, l.hint())
+			continue
+		}
+		c, eliminate, perr := parseDeduction(line)
+		if perr != nil {
+			fmt.Fprintln(out, perr)
+		} else if msg := l.check(c, eliminate); msg != "" {
+			fmt.Fprintln(out, msg)
+		} else {
+			fmt.Fprintf(out, "Correct! %v\n", *l.step)
+			return true
+		}
+		if err != nil {
+			return false
+		}
+	}
+}
+
+// learnCommand handles: learn [flags] <technique>, a tutorial which finds
+// positions demonstrating the technique and asks the user to spot it
+func learnCommand(args []string) error {
+	fs := flag.NewFlagSet("learn", flag.ExitOnError)
+	seed := fs.Int64("seed", time.Now().UnixNano(), "random number generator seed")
+	rounds := fs.Int("n", 3, "number of positions to practice on")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("Usage: learn [flags] <technique>, one of: %v", strings.Join(techniqueIDs(), ", "))
+	}
+	ids, err := parseTechniques(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	rng := rand.New(rand.NewSource(*seed))
+	in := bufio.NewReader(os.Stdin)
+	for _, t := range techniques {
+		if !ids[t.id] {
+			continue
+		}
+		for i := 0; i < *rounds; i++ {
+			l := findLesson(rng, t)
+			if l == nil {
+				return fmt.Errorf("No position using %v found in %v puzzles", t.name, learnAttempts)
+			}
+			fmt.Printf("Position %v of %v:\n", i+1, *rounds)
+			if !l.teach(in, os.Stdout) {
+				return nil
+			}
+			fmt.Println()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+)
+
+// Lint checks a description for mistakes which make the puzzle unsolvable
+// without searching for a solution: every structural problem found when
+// building it, cage sums which no distinct values can reach, thermometers,
+// renbans and arrows too long, KenKen targets out of reach, cells in more
+// than one cage or both even and odd, palindromes whose mirrored cells share
+// a group, and givens which already break a rule.
+func (s *VariantSpec) Lint() []Problem {
+	b := s.build()
+	for i, spec := range s.Cages {
+		path := fmt.Sprintf("cages[%v]", i)
+		if n := len(spec.Cells); n > DIM {
+			b.problem(path, "cage of %v cells can't hold distinct values", n)
+		} else if lo, hi := distinctSumRange(allValues, n); n > 0 && (spec.Sum < lo || hi < spec.Sum) {
+			b.problem(path+".sum", "%v distinct values can't add up to %v, only %v through %v",
+				n, spec.Sum, lo, hi)
+		}
+	}
+	for i, names := range s.Thermos {
+		if len(names) > DIM {
+			b.problem(fmt.Sprintf("thermos[%v]", i), "thermometer of %v cells can't strictly increase",
+				len(names))
+		}
+	}
+	for i, names := range s.Renbans {
+		if len(names) > DIM {
+			b.problem(fmt.Sprintf("renbans[%v]", i), "renban of %v cells can't hold distinct values",
+				len(names))
+		}
+	}
+
+	// Cells may only be in one cage
+	var cage [DIM][DIM]int
+	for i, spec := range s.Cages {
+		for j, name := range spec.Cells {
+			c, err := ParseCell(name)
+			if err != nil {
+				continue
+			}
+			if prev := cage[c.Row][c.Col]; prev != 0 && prev != i+1 {
+				b.problem(fmt.Sprintf("cages[%v].cells[%v]", i, j), "%v is also in cages[%v]", c, prev-1)
+			} else {
+				cage[c.Row][c.Col] = i + 1
+			}
+		}
+	}
+
+	for i, spec := range s.Arrows {
+		if n := len(spec.Arrow); n >= DIM {
+			b.problem(fmt.Sprintf("arrows[%v].arrow", i), "arrow of %v cells adds up to more than %v", n, DIM)
+		}
+	}
+	for i, spec := range s.KenKen {
+		n := len(spec.Cells)
+		reachable := true
+		switch {
+		case n == 0 || spec.Target <= 0:
+		case spec.Op == "+" || spec.Op == "" && n == 1:
+			reachable = n <= spec.Target && spec.Target <= DIM*n
+		case spec.Op == "*":
+			reachable = productReachable(spec.Target, n)
+		}
+		if !reachable {
+			b.problem(fmt.Sprintf("kenken[%v].target", i), "%v values can't make %v with %q",
+				n, spec.Target, spec.Op)
+		}
+	}
+	var even [DIM][DIM]bool
+	for _, name := range s.Even {
+		if c, err := ParseCell(name); err == nil {
+			even[c.Row][c.Col] = true
+		}
+	}
+	for i, name := range s.Odd {
+		if c, err := ParseCell(name); err == nil && even[c.Row][c.Col] {
+			b.problem(fmt.Sprintf("odd[%v]", i), "%v is also even", c)
+		}
+	}
+
+	if b.game == nil {
+		return b.problems
+	}
+	v := NewVariant(b.game, b.groups, b.rules)
+	g := v.Game
+	for ri, cols := range g.board {
+		for ci, val := range cols {
+			c := Cell{ri, ci}
+			for _, p := range v.peers[ri][ci] {
+				if val != 0 && g.board[p.Row][p.Col] == val && (p.Row > ri || p.Row == ri && p.Col > ci) {
+					b.problem("grid", "%v and %v are both given %v", c, p, val)
+				}
+			}
+		}
+	}
+	for i, rule := range b.rules {
+		if p, ok := rule.(*Palindrome); ok {
+			for j, c := range p.cells[:len(p.cells)/2] {
+				if m := p.cells[len(p.cells)-1-j]; v.sees(c, m) {
+					b.problem(b.paths[i], "%v and %v must match but share a group", c, m)
+				}
+			}
+		}
+		for _, c := range rule.Cells() {
+			val := g.board[c.Row][c.Col]
+			if val == 0 {
+				continue
+			}
+			g.board[c.Row][c.Col] = 0
+			broken := rule.Allowed(g, c, 1<<uint(val)) == 0
+			g.board[c.Row][c.Col] = val
+			if broken {
+				b.problem(b.paths[i], "given %v=%v breaks the rule", c, val)
+				break
+			}
+		}
+	}
+	return b.problems
+}
+
+// lintCommand handles: lint <description file>..., reporting every problem
+// found in each file
+func lintCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("Usage: lint <description file>...")
+	}
+	total := 0
+	for _, fname := range args {
+		spec, err := readVariantSpec(fname)
+		if err != nil {
+			fmt.Println(err)
+			total++
+			continue
+		}
+		problems := spec.Lint()
+		for _, p := range problems {
+			fmt.Printf("%v: %v\n", fname, p)
+		}
+		if len(problems) == 0 {
+			fmt.Printf("%v: ok\n", fname)
+		}
+		total += len(problems)
+	}
+	if total > 0 {
+		return fmt.Errorf("Found %v problems", total)
+	}
+	return nil
+}
@@ -2,43 +2,409 @@ package main
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
+	"strings"
+	"time"
 )
 
 // DIM is the dimension of the board
 const DIM = 9
 
+// commands maps sub-command names to their handlers, which receive the
+// arguments following the name
+var commands = map[string]func(args []string) error{
+	"batch":          batchCommand,
+	"bot":            botCommand,
+	"distribution":   distributionCommand,
+	"export":         exportCommand,
+	"generate":       generateCommand,
+	"guess":          guessCommand,
+	"grade-solution": gradeCommand,
+	"hunt":           huntCommand,
+	"import":         importCommand,
+	"is-minimal":     isMinimalCommand,
+	"learn":          learnCommand,
+	"lint":           lintCommand,
+	"merge-reports":  mergeReportsCommand,
+	"multigrid":      multiGridCommand,
+	"pack":           packCommand,
+	"play":           playCommand,
+	"publish":        publishCommand,
+	"query":          queryCommand,
+	"replay":         replayCommand,
+	"sample":         sampleCommand,
+	"selftest":       selfTestCommand,
+	"serve":          serveCommand,
+	"share":          shareCommand,
+	"similar":        similarCommand,
+	"solutions":      solutionsCommand,
+	"stats":          statsCommand,
+	"stuck":          stuckCommand,
+	"variant":        variantCommand,
+	"why":            whyCommand,
+	"worksheet":      worksheetCommand,
+}
+
+var (
+	explain      = flag.Bool("explain", false, "print the logical steps used before backtracking")
+	diff         = flag.Bool("diff", false, "highlight the cells filled in by the solver")
+	diffOnly     = flag.Bool("diff-only", false, "also print a grid of only the digits added by the solver")
+	maskSolution = flag.Bool("mask-solution", false,
+		"print an answer key of only the digits added by the solver in place of the solution, for grading overlays")
+	check  = flag.Bool("check", false, "warn if the puzzle has multiple solutions or redundant clues")
+	verify = flag.Bool("verify", false, "cross check the puzzle with two independent solvers, failing if they disagree")
+
+	algo      = flag.String("algo", "backtrack", "solving algorithm: "+solverNames())
+	propagate = flag.Bool("propagate", false, "fill cells forced by constraint propagation before solving")
+	logicOnly = flag.Bool("logic-only", false,
+		"only apply the strategy engine, printing the partly solved board and its candidates instead of guessing")
+
+	stdio     = flag.Bool("stdio", false, "serve JSON-RPC 2.0 requests on stdin and stdout, for editors and tools")
+	traceFile = flag.String("trace", "", "write every decision made while solving to `file` as JSON, see replay")
+
+	fromClipboard = flag.Bool("from-clipboard", false, "read the puzzle from the clipboard instead of a file")
+	toClipboard   = flag.Bool("to-clipboard", false, "copy the solution to the clipboard")
+
+	output = flag.String("o", "grid",
+		"output `format`: grid, json, spoken to describe the puzzle and solution in words, braille, emoji, "+
+			"side-by-side or side-by-side-html for the puzzle next to its solution, qr for the share code of the "+
+			"puzzle as a terminal QR code, or template")
+	templateFile = flag.String("template", "", "render the puzzle and solution through the text/template in `file` for -o template")
+	themeName    = flag.String("theme", "light", "color `theme` of boards in the terminal, images and HTML: "+
+		strings.Join(themeNames(), ", ")+", or one defined in the config file")
+	emojiSet = flag.String("emoji", "keycaps", "emoji `set` of -o emoji: "+
+		emojiSetNames()+", one defined in the config file, or a comma separated list")
+	brailleSpacing = flag.Int("braille-spacing", 1, "blank braille cells between values for -o braille")
+)
+
+// platformMain replaces the command line interface on platforms without one,
+// such as WebAssembly, when set by an init function
+var platformMain func()
+
 func main() {
-	if len(os.Args) != 2 {
-		fmt.Println("Puzzle filename required")
-		os.Exit(1)
+	if platformMain != nil {
+		platformMain()
+		return
+	}
+	annealer := solvers["anneal"].(*Annealer)
+	flag.Float64Var(&annealer.Temperature, "anneal-temp", annealer.Temperature, "starting temperature for -algo=anneal")
+	flag.Float64Var(&annealer.Cooling, "anneal-cooling", annealer.Cooling, "temperature multiplier per move for -algo=anneal")
+	flag.IntVar(&annealer.Iterations, "anneal-iterations", annealer.Iterations, "moves before -algo=anneal restarts")
+	flag.IntVar(&annealer.Restarts, "anneal-restarts", annealer.Restarts, "restarts before -algo=anneal gives up")
+	flag.IntVar(&forcingDepth, "chain-depth", forcingDepth,
+		"maximum singles followed by a forcing chain")
+	disable := flag.String("disable", "",
+		"comma separated techniques for the strategy engine to skip: "+
+			strings.Join(techniqueIDs(), ", "))
+	logLevel := flag.String("log-level", "info", "log messages at `level` and above: debug, info, warn or error")
+	logFormat := flag.String("log-format", "text", "log `format`: text or json")
+	flag.Parse()
+	if err := setupLogging(*logLevel, *logFormat); err != nil {
+		fatal(err)
+	}
+	if err := disableTechniques(*disable); err != nil {
+		fatal(err)
 	}
-	board, err := readGame(os.Args[1])
+	theme, err := LookupTheme(*themeName)
 	if err != nil {
-		fmt.Println(err)
+		fatal(err)
+	}
+	activeTheme = theme
+	if *stdio {
+		if err := serveRPC(os.Stdin, os.Stdout); err != nil {
+			fatal(err)
+		}
+		return
+	}
+	if cmd, ok := commands[flag.Arg(0)]; ok {
+		slog.Debug("running command", "command", flag.Arg(0), "args", flag.Args()[1:])
+		if err := cmd(flag.Args()[1:]); err != nil {
+			fatal(err)
+		}
+		return
+	}
+	puzzle := &Puzzle{}
+	if *fromClipboard {
+		puzzle.Game, err = readClipboardGame()
+	} else if flag.NArg() != 1 {
+		fatal(fmt.Errorf("Puzzle filename required"))
+	} else {
+		puzzle, err = readPuzzle(flag.Arg(0))
+	}
+	if err != nil {
+		fatal(err)
+	}
+	board := puzzle.Game
+	switch *output {
+	case "grid", "spoken", "braille", "emoji", "side-by-side":
+	case "json":
+		if err := writeJSONOutput(os.Stdout, puzzle, *algo, *check); err != nil {
+			fatal(err)
+		}
+		return
+	case "side-by-side-html":
+		if err := sideBySideHTML(puzzle, *algo, *maskSolution); err != nil {
+			fatal(err)
+		}
+		return
+	case "qr":
+		// The same code as share -o qr without a -url
+		link := "#" + EncodeShare(board)
+		q, err := qrEncode([]byte(link))
+		if err != nil {
+			fatal(err)
+		}
+		fmt.Print(q)
+		fmt.Println(link)
+		return
+	case "template":
+		if *templateFile == "" {
+			fatal(fmt.Errorf("-o template requires -template file"))
+		}
+		if err := writeTemplate(os.Stdout, puzzle, *templateFile, *algo); err != nil {
+			fatal(err)
+		}
 		return
+	default:
+		fatal(fmt.Errorf("Unknown output format %q, expected grid, json, spoken, braille, emoji, side-by-side, "+
+			"side-by-side-html, qr or template", *output))
+	}
+	spoken := *output == "spoken"
+	var emoji []string
+	if *output == "emoji" {
+		if emoji, err = LookupEmoji(*emojiSet); err != nil {
+			fatal(err)
+		}
+	}
+	puzzleBoard := board.Clone()
+	switch *output {
+	case "side-by-side":
+	case "spoken":
+		fmt.Println("Starting configuration:")
+		fmt.Println(board.SpokenString())
+	case "braille":
+		fmt.Println("Starting configuration:")
+		fmt.Println(board.BrailleString(*brailleSpacing))
+	case "emoji":
+		fmt.Println("Starting configuration:")
+		fmt.Println(board.EmojiString(emoji))
+	default:
+		fmt.Println("Starting configuration:")
+		printGrid(board, false)
+	}
+	var trace *Trace
+	if *traceFile != "" {
+		trace = board.StartTrace()
+	}
+
+	if *check {
+		checkPuzzle(board)
+	}
+	if *explain {
+		explainSolve(board)
+	}
+	if *verify {
+		if err := Verify(board); err != nil {
+			fatal(err)
+		}
+		fmt.Printf("\nVerified with %v\n", strings.Join(verifiers, " and "))
 	}
-	fmt.Println("Starting configuration:")
-	fmt.Println(board)
 
-	solved := recursiveSolver(board)
+	if *logicOnly {
+		logicOnlySolve(board)
+		return
+	}
 
-	fmt.Printf("\nSolved? %v\n\n", solved)
+	solver, ok := solvers[*algo]
+	if !ok {
+		fatal(fmt.Errorf("Unknown algorithm %q", *algo))
+	}
+	if *propagate {
+		placed, ok := Propagate(board)
+		fmt.Printf("\nPropagation placed %v cells, consistent? %v\n", placed, ok)
+	}
+	var stats Stats
+	start := time.Now()
+	solved := solver.Solve(board, &stats)
+	slog.Debug("solved", "algo", *algo, "solved", solved, "backtracks", stats.Backtracks,
+		"duration", time.Since(start))
 
-	fmt.Println("Ending configuration:")
-	fmt.Println(board)
+	fmt.Printf("\nSolved? %v\n", solved)
+	if solved {
+		fmt.Printf("Checksum: %v\n", board.Checksum())
+	}
+	// The givens may conflict even though the solver filled the board
+	if !solved || len(Violations(puzzleBoard, false)) > 0 {
+		if c := AnalyzeConflict(puzzleBoard); c != nil {
+			fmt.Println(c)
+		}
+	}
+	if stats.Iterations > 0 {
+		fmt.Printf("Iterations: %v, Restarts: %v\n", stats.Iterations, stats.Restarts)
+	}
+	fmt.Println()
+
+	switch {
+	case spoken:
+		fmt.Println("Placements:")
+		fmt.Println(board.SpokenPlacements())
+	case *output == "side-by-side":
+		fmt.Println(SideBySideString(puzzleBoard, board, *diff, *maskSolution))
+	case *output == "braille":
+		fmt.Println("Ending configuration:")
+		fmt.Println(board.BrailleString(*brailleSpacing))
+	case *output == "emoji":
+		fmt.Println("Ending configuration:")
+		fmt.Println(board.EmojiString(emoji))
+	case *maskSolution:
+		fmt.Println("Answer key:")
+		fmt.Println(board.DiffString(true))
+	default:
+		fmt.Println("Ending configuration:")
+		printGrid(board, *diff)
+	}
+	if *diffOnly && *output == "grid" && !*maskSolution {
+		fmt.Println("\nAdded by solver:")
+		fmt.Println(board.DiffString(true))
+	}
 
 	validateSolution(*board)
+
+	if trace != nil {
+		if solved {
+			trace.Checksum = board.Checksum()
+		}
+		if err := writeTrace(*traceFile, trace); err != nil {
+			fatal(err)
+		}
+		fmt.Printf("\nWrote %v decisions to %v\n", len(trace.Decisions), *traceFile)
+	}
+
+	if *toClipboard {
+		if err := writeClipboard(board.GridString()); err != nil {
+			fatal(err)
+		}
+		fmt.Println("\nSolution copied to clipboard")
+	}
+}
+
+// printGrid prints the board as String does, or as DiffString if diff is
+// true, adapting the layout to the terminal if stdout is one
+func printGrid(board *Game, diff bool) {
+	switch {
+	case isTerminal(os.Stdout):
+		fmt.Println(board.TerminalString(os.Stdout, diff))
+		if !diff {
+			fmt.Printf("Remaining: %v, Backtracks: %v\n", board.remaining, board.backtracks)
+		}
+	case diff:
+		fmt.Println(board.DiffString(false))
+	default:
+		fmt.Println(board)
+	}
+}
+
+// sideBySideHTML solves the puzzle, writing the page of -o side-by-side-html
+// to stdout, with only the cells filled in by the solver if mask is true
+func sideBySideHTML(p *Puzzle, algo string, mask bool) error {
+	solver, ok := solvers[algo]
+	if !ok {
+		return fmt.Errorf("Unknown algorithm %q", algo)
+	}
+	solved := p.Game.Clone()
+	var stats Stats
+	if !solver.Solve(solved, &stats) {
+		return fmt.Errorf("Puzzle has no solution")
+	}
+	if mask {
+		writeSideBySideHTML(os.Stdout, p, solved.AnswerKey(), "Answer key")
+	} else {
+		writeSideBySideHTML(os.Stdout, p, solved, "Solution")
+	}
+	return nil
+}
+
+// checkPuzzle warns about a broken puzzle, one without a unique solution or
+// with clues which could be removed
+func checkPuzzle(board *Game) {
+	for _, w := range puzzleWarnings(board) {
+		fmt.Printf("\nWarning: %v\n", w)
+	}
+}
+
+// puzzleWarnings returns the problems found by checkPuzzle
+func puzzleWarnings(board *Game) []string {
+	switch board.CountSolutions(2) {
+	case 0:
+		return []string{"puzzle has no solution"}
+	case 1:
+		if redundant := redundantClues(board); len(redundant) > 0 {
+			return []string{fmt.Sprintf("puzzle has %v redundant clues: %v",
+				len(redundant), strings.Trim(fmt.Sprint(redundant), "[]"))}
+		}
+		return nil
+	}
+	warnings := []string{"puzzle has multiple solutions"}
+	if clues := SuggestUniqueClues(board); clues != nil {
+		warnings = append(warnings, fmt.Sprintf("adding the %v clues %v makes the solution unique",
+			len(clues), givenList(clues)))
+	}
+	return warnings
+}
+
+// logicOnlySolve applies the strategy engine to the board without guessing,
+// printing the partially solved board and the pencil marks left where it
+// got stuck
+func logicOnlySolve(board *Game) {
+	s := NewStrategist(board)
+	solved := s.Solve()
+	fmt.Printf("\nSolved by logic? %v\nSteps: %v\n\n", solved, len(s.Steps))
+	fmt.Println("Ending configuration:")
+	fmt.Println(board.DiffString(false))
+	if !solved {
+		fmt.Println("\nRemaining candidates:")
+		fmt.Println(s.PencilMarks())
+	}
+}
+
+// explainSolve applies the strategy engine to the board, printing each step
+func explainSolve(board *Game) {
+	s := NewStrategist(board)
+	s.Solve()
+	fmt.Println("\nLogical steps:")
+	for i, step := range s.Steps {
+		fmt.Printf("%3v. %v\n", i+1, step)
+	}
+	fmt.Printf("\nDifficulty: %v\n", s.Difficulty())
 }
 
 // readGame reads a board from a text file, ignoring non-numeric characters
 func readGame(fname string) (*Game, error) {
+	p, err := readPuzzle(fname)
+	if err != nil {
+		return nil, err
+	}
+	return p.Game, nil
+}
+
+// readPuzzle reads a board and its metadata in the layout of the example
+// puzzle files
+func readPuzzle(fname string) (*Puzzle, error) {
 	file, err := os.Open(fname)
 	if err != nil {
 		return nil, err
 	}
-	scanner := bufio.NewScanner(file)
+	defer file.Close()
+	return ParsePuzzle(file, GridFormat)
+}
+
+// scanGame reads a board of 9 rows, ignoring non-numeric characters
+func scanGame(r io.Reader) (*Game, error) {
+	scanner := bufio.NewScanner(r)
 	b := NewGame()
 	for row := 0; row < DIM; row++ {
 		if !scanner.Scan() {
@@ -53,6 +419,9 @@ func readGame(fname string) (*Game, error) {
 			// ASCII values 48..57 represent 0..9
 			if 48 <= c && c <= 57 {
 				// c is numeric
+				if col == DIM {
+					return nil, fmt.Errorf("More than %v cells in row %v", DIM, row+1)
+				}
 				if c > 0 {
 					b.MakeMove(row, col, int(c-48))
 				}
@@ -61,6 +430,7 @@ func readGame(fname string) (*Game, error) {
 		}
 
 	}
+	b.MarkGivens()
 
 	return b, nil
 }
@@ -68,16 +438,27 @@ func readGame(fname string) (*Game, error) {
 // validateSolution cross checks each cell of the board.  Not part of the
 // solver, but used to validate the solvers correctness.
 func validateSolution(b Game) {
-	for row := 0; row < DIM; row++ {
-		for col := 0; col < DIM; col++ {
-			// Hold on to the move for this cell
-			expect := b.board[row][col]
-			// Reset move and check that the expected move is in the candidate list
-			b.board[row][col] = 0
-			candidates := b.CellCandidates(row, col)
-			if !candidates[expect] {
-				fmt.Printf("Invalid value %v at row %v, col %v\n", expect, row+1, col+1)
+	for _, c := range invalidCells(b) {
+		fmt.Printf("Invalid value %v at row %v, col %v\n", b.board[c.Row][c.Col], c.Row+1, c.Col+1)
+	}
+}
+
+// invalidCells returns the cells of the board whose value is not a legal move
+// given the rest of the board, including empty cells
+func invalidCells(b Game) []Cell {
+	var result []Cell
+	for row, cols := range b.board {
+		for col, val := range cols {
+			valid := val != 0
+			for _, p := range peers[row][col] {
+				if b.board[p.Row][p.Col] == val {
+					valid = false
+				}
+			}
+			if !valid {
+				result = append(result, Cell{row, col})
 			}
 		}
 	}
+	return result
 }
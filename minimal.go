@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// isMinimalCommand handles: is-minimal <puzzle file>, reporting any clue which
+// isn't needed for the solution to be unique
+func isMinimalCommand(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("Usage: is-minimal <puzzle file>")
+	}
+	board, err := readGame(args[0])
+	if err != nil {
+		return err
+	}
+	if n := board.CountSolutions(2); n != 1 {
+		return fmt.Errorf("Puzzle has %v solutions, minimality requires exactly one",
+			map[int]string{0: "no", 2: "multiple"}[n])
+	}
+	redundant := redundantClues(board)
+	given := DIM*DIM - board.remaining
+	if len(redundant) == 0 {
+		fmt.Printf("Minimal: all %v clues are needed\n", given)
+		return nil
+	}
+	names := make([]string, len(redundant))
+	for i, c := range redundant {
+		names[i] = fmt.Sprintf("%v=%v", c, board.board[c.Row][c.Col])
+	}
+	fmt.Printf("Removable clues: %v\n", strings.Join(names, ", "))
+	return fmt.Errorf("Not minimal: %v of %v clues can be removed individually",
+		len(redundant), given)
+}
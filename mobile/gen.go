@@ -0,0 +1,46 @@
+//go:build ignore
+
+// gen copies the solver from the parent directory into internal/engine as an
+// importable package, since gomobile can't bind package main.  Run it with
+// go generate before gomobile bind.
+package main
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const engine = "internal/engine"
+
+func main() {
+	if err := os.RemoveAll(engine); err != nil {
+		log.Fatal(err)
+	}
+	if err := os.MkdirAll(engine, 0755); err != nil {
+		log.Fatal(err)
+	}
+	names, err := filepath.Glob("../*.go")
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, name := range names {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		src, err := os.ReadFile(name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		src = bytes.Replace(src, []byte("\npackage main\n"), []byte("\npackage engine\n"), 1)
+		if bytes.HasPrefix(src, []byte("package main\n")) {
+			src = append([]byte("package engine\n"), src[len("package main\n"):]...)
+		}
+		out := append([]byte("// Code generated by gen.go from "+filepath.Base(name)+"; DO NOT EDIT.\n\n"), src...)
+		if err := os.WriteFile(filepath.Join(engine, filepath.Base(name)), out, 0644); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+//go:build mobile
+
+// Package mobile is the solver's API for Android and iOS apps, built with
+// gomobile:
+//
+//	go generate ./mobile
+//	gomobile bind -tags mobile -target android ./mobile
+//
+// gomobile only binds strings, integers and errors across the boundary, so
+// boards are passed in the 81 digit line format and hints as JSON.
+package mobile
+
+//go:generate go run gen.go
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/jhillyerd/sudoku-solver/mobile/internal/engine"
+)
+
+// Solve returns the solution of the puzzle
+func Solve(puzzle string) (string, error) {
+	g, err := engine.ParseLine(puzzle)
+	if err != nil {
+		return "", err
+	}
+	if !(engine.Backtracker{}).Solve(g, &engine.Stats{}) {
+		return "", fmt.Errorf("Puzzle has no solution")
+	}
+	return g.Line(), nil
+}
+
+// Generate returns a new puzzle with a unique solution from the random seed.
+// symmetry is named as for the generate command, and clues is the target
+// number of clues, 0 for a minimal puzzle.
+func Generate(seed int64, symmetry string, clues int) (string, error) {
+	g, err := engine.Generate(seed, symmetry, clues)
+	if err != nil {
+		return "", err
+	}
+	return g.Line(), nil
+}
+
+// hint is the JSON returned by Hint, the fields of the hint method of -stdio
+type hint struct {
+	// Found is false if the strategy engine has no step to offer
+	Found     bool   `json:"found"`
+	Technique string `json:"technique,omitempty"`
+	// Cell and Value are set when the step places a value
+	Cell         string   `json:"cell,omitempty"`
+	Value        int      `json:"value,omitempty"`
+	Eliminations []string `json:"eliminations,omitempty"`
+	Description  string   `json:"description,omitempty"`
+}
+
+// Hint returns the next step of the strategy engine for the puzzle as JSON,
+// such as {"found":true,"technique":"Naked Single","cell":"r1c4","value":3,
+// "description":"Naked Single: r1c4=3"}
+func Hint(puzzle string) (string, error) {
+	g, err := engine.ParseLine(puzzle)
+	if err != nil {
+		return "", err
+	}
+	var h hint
+	if step := engine.Hint(g); step != nil {
+		h = hint{Found: true, Technique: step.Technique, Value: step.Value, Description: step.String()}
+		if step.Value != 0 {
+			h.Cell = step.Cell.String()
+		}
+		for _, e := range step.Eliminations {
+			h.Eliminations = append(h.Eliminations, e.String())
+		}
+	}
+	data, err := json.Marshal(h)
+	return string(data), err
+}
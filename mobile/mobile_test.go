@@ -0,0 +1,52 @@
+//go:build mobile
+
+package mobile
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const hardPuzzle = "405008020000100000020067090008000030506000201010000400080970060000001000090800507"
+
+func TestSolve(t *testing.T) {
+	solution, err := Solve(hardPuzzle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range hardPuzzle {
+		if hardPuzzle[i] != '0' && hardPuzzle[i] != solution[i] {
+			t.Fatalf("Solve(%v) = %v, which changes the givens", hardPuzzle, solution)
+		}
+	}
+	if _, err := Solve("123"); err == nil {
+		t.Error("Solve succeeded on a short puzzle")
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	a, err := Generate(1, "rotational", 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := Generate(1, "rotational", 30); a != b {
+		t.Errorf("Generate isn't repeatable for a seed: %v and %v", a, b)
+	}
+	if _, err := Generate(1, "spiral", 30); err == nil {
+		t.Error("Generate accepted an unknown symmetry")
+	}
+}
+
+func TestHint(t *testing.T) {
+	data, err := Hint(hardPuzzle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var h hint
+	if err := json.Unmarshal([]byte(data), &h); err != nil {
+		t.Fatal(err)
+	}
+	if !h.Found || h.Technique != "Naked Single" || h.Cell != "r1c4" || h.Value != 3 {
+		t.Errorf("Hint = %v", data)
+	}
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/bits"
+	"os"
+	"strings"
+)
+
+// MultiGridSpec is the description file format for puzzles of several
+// overlapping grids, such as Samurai or the twin and triple Gattai puzzles.
+// Each grid is placed on a shared canvas with its top left cell At, in r1c1
+// notation, and cells where grids overlap belong to all of them.
+//
+//	{
+//	  "grids": [
+//	    {"name": "left", "at": "r1c1", "grid": "..."},
+//	    {"name": "right", "at": "r7c7", "grid": "..."}
+//	  ]
+//	}
+type MultiGridSpec struct {
+	Grids []GridPlacement `json:"grids"`
+}
+
+// GridPlacement is one grid of a multi-grid puzzle.  Grid holds its givens as
+// a line of 81 cells, and a given in an overlap only needs to appear in one of
+// the grids sharing it.
+type GridPlacement struct {
+	Name string `json:"name"`
+	At   string `json:"at"`
+	Grid string `json:"grid"`
+}
+
+// maxCanvas limits the size of the canvas, in cells along each side
+const maxCanvas = 64
+
+// MultiGrid is a puzzle of overlapping grids, each following the classic
+// rules, ready to solve
+type MultiGrid struct {
+	Names []string
+	// origins holds the top left cell of each grid on the canvas
+	origins []Cell
+	rows    int
+	cols    int
+	// board holds the canvas, -1 for cells outside every grid, access as
+	// board[row*cols+col]
+	board []int
+	// peers lists the cells sharing a row, column or box of any grid
+	peers      [][]int
+	remaining  int
+	backtracks int
+}
+
+// parseCanvasCell reads a cell of the canvas in r1c1 notation, which unlike
+// ParseCell may lie beyond the first grid
+func parseCanvasCell(s string) (Cell, error) {
+	var row, col int
+	var extra string
+	n, _ := fmt.Sscanf(strings.ToLower(s), "r%dc%d%s", &row, &col, &extra)
+	if n != 2 || row < 1 || col < 1 || maxCanvas-DIM+1 < row || maxCanvas-DIM+1 < col {
+		return Cell{}, fmt.Errorf("Invalid cell %q, expected r1c1 through r%vc%v",
+			s, maxCanvas-DIM+1, maxCanvas-DIM+1)
+	}
+	return Cell{row - 1, col - 1}, nil
+}
+
+// MultiGrid validates the description and builds the puzzle it describes
+func (s *MultiGridSpec) MultiGrid() (*MultiGrid, error) {
+	if len(s.Grids) == 0 {
+		return nil, fmt.Errorf("grids: no grids given")
+	}
+	m := &MultiGrid{}
+	games := make([]*Game, len(s.Grids))
+	for i, spec := range s.Grids {
+		path := fmt.Sprintf("grids[%v]", i)
+		at, err := parseCanvasCell(spec.At)
+		if err != nil {
+			return nil, fmt.Errorf("%v.at: %v", path, err)
+		}
+		if games[i], err = ParseLine(spec.Grid); err != nil {
+			return nil, fmt.Errorf("%v.grid: %v", path, err)
+		}
+		name := spec.Name
+		if name == "" {
+			name = fmt.Sprintf("grid %v", i+1)
+		}
+		m.Names = append(m.Names, name)
+		m.origins = append(m.origins, at)
+		m.rows, m.cols = max(m.rows, at.Row+DIM), max(m.cols, at.Col+DIM)
+	}
+
+	m.board = make([]int, m.rows*m.cols)
+	for i := range m.board {
+		m.board[i] = -1
+	}
+	for i, g := range games {
+		for ri, cols := range g.board {
+			for ci, val := range cols {
+				idx := m.index(i, Cell{ri, ci})
+				if m.board[idx] <= 0 {
+					m.board[idx] = val
+				} else if val != 0 && val != m.board[idx] {
+					return nil, fmt.Errorf("grids[%v].grid: %v is given %v, but %v elsewhere",
+						i, Cell{ri, ci}, val, m.board[idx])
+				}
+			}
+		}
+	}
+
+	m.peers = make([][]int, len(m.board))
+	seen := make(map[[2]int]bool)
+	for i := range games {
+		for _, unit := range units {
+			for _, c := range unit {
+				for _, p := range unit {
+					a, b := m.index(i, c), m.index(i, p)
+					if a != b && !seen[[2]int{a, b}] {
+						seen[[2]int{a, b}] = true
+						m.peers[a] = append(m.peers[a], b)
+					}
+				}
+			}
+		}
+	}
+	for idx, val := range m.board {
+		if val == 0 {
+			m.remaining++
+		} else if val > 0 && m.candidates(idx)&(1<<uint(val)) == 0 {
+			return nil, fmt.Errorf("%v is given %v more than once in a row, column or box",
+				m.cellName(idx), val)
+		}
+	}
+	return m, nil
+}
+
+// index returns the canvas index of cell c of grid i
+func (m *MultiGrid) index(i int, c Cell) int {
+	return (m.origins[i].Row+c.Row)*m.cols + m.origins[i].Col + c.Col
+}
+
+// cellName locates a canvas cell by the first grid containing it
+func (m *MultiGrid) cellName(idx int) string {
+	row, col := idx/m.cols, idx%m.cols
+	for i, o := range m.origins {
+		if o.Row <= row && row < o.Row+DIM && o.Col <= col && col < o.Col+DIM {
+			return fmt.Sprintf("%v %v", m.Names[i], Cell{row - o.Row, col - o.Col})
+		}
+	}
+	return Cell{row, col}.String()
+}
+
+// candidates returns the legal values of a canvas cell as a bit set
+func (m *MultiGrid) candidates(idx int) uint16 {
+	var used uint16
+	for _, p := range m.peers[idx] {
+		used |= 1 << uint(m.board[p])
+	}
+	return allValues &^ used
+}
+
+// nextEmptyCell returns the empty cell with the fewest candidates, and them
+func (m *MultiGrid) nextEmptyCell() (int, uint16) {
+	best, bestMask, min := -1, uint16(0), DIM+1
+	for idx, val := range m.board {
+		if val != 0 {
+			continue
+		}
+		mask := m.candidates(idx)
+		if n := bits.OnesCount16(mask); n < min {
+			best, bestMask, min = idx, mask, n
+			if n == 0 {
+				break
+			}
+		}
+	}
+	return best, bestMask
+}
+
+// Solve fills every grid by recursive backtracking, giving up if stats has a
+// deadline which expires.  stats may be nil.
+func (m *MultiGrid) Solve(stats *Stats) bool {
+	start := m.backtracks
+	solved := m.search(stats, nil)
+	if stats != nil {
+		stats.Backtracks += m.backtracks - start
+	}
+	return solved
+}
+
+// CountSolutions counts the solutions, stopping once limit have been found.
+// The board is left unchanged.
+func (m *MultiGrid) CountSolutions(limit int) int {
+	found := 0
+	if limit > 0 {
+		m.search(nil, func() bool {
+			found++
+			return found >= limit
+		})
+	}
+	return found
+}
+
+// search fills the board, calling found for each solution if it isn't nil
+// and continuing until found returns true.  Solutions are only left on the
+// board if found is nil.
+func (m *MultiGrid) search(stats *Stats, found func() bool) bool {
+	if m.remaining == 0 {
+		return found == nil || found()
+	}
+	if stats.expired() {
+		return false
+	}
+	idx, candidates := m.nextEmptyCell()
+	for mask := candidates; mask != 0; mask &= mask - 1 {
+		m.board[idx] = bits.TrailingZeros16(mask)
+		m.remaining--
+		done := m.search(stats, found)
+		if done && found == nil {
+			return true
+		}
+		m.board[idx] = 0
+		m.remaining++
+		m.backtracks++
+		if done {
+			return true
+		}
+	}
+	return false
+}
+
+// Grid returns grid i of the puzzle as a Game, as currently filled
+func (m *MultiGrid) Grid(i int) *Game {
+	g := NewGame()
+	for ri := 0; ri < DIM; ri++ {
+		for ci := 0; ci < DIM; ci++ {
+			g.MakeMove(ri, ci, m.board[m.index(i, Cell{ri, ci})])
+		}
+	}
+	return g
+}
+
+// String draws the whole canvas, with blanks outside the grids
+func (m *MultiGrid) String() string {
+	lines := make([]string, m.rows)
+	for row := range lines {
+		line := make([]byte, 0, 2*m.cols)
+		for col := 0; col < m.cols; col++ {
+			switch val := m.board[row*m.cols+col]; val {
+			case -1:
+				line = append(line, ' ', ' ')
+			case 0:
+				line = append(line, '.', ' ')
+			default:
+				line = append(line, byte('0'+val), ' ')
+			}
+		}
+		lines[row] = strings.TrimRight(string(line), " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// multiGridCommand handles: multigrid [flags] <description file>, solving a
+// puzzle of overlapping grids
+func multiGridCommand(args []string) error {
+	fs := flag.NewFlagSet("multigrid", flag.ExitOnError)
+	check := fs.Bool("check", false, "warn if the puzzle doesn't have a unique solution")
+	split := fs.Bool("split", false, "print each grid of the solution separately, by name")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("Usage: multigrid [flags] <description file>")
+	}
+	file, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	var spec MultiGridSpec
+	if err := decodeStrict(file, &spec); err != nil {
+		return fmt.Errorf("%v: %v", fs.Arg(0), err)
+	}
+	m, err := spec.MultiGrid()
+	if err != nil {
+		return fmt.Errorf("%v: %v", fs.Arg(0), err)
+	}
+	fmt.Printf("Starting configuration, %v grids:\n", len(m.Names))
+	fmt.Println(m)
+	if *check {
+		switch m.CountSolutions(2) {
+		case 0:
+			fmt.Println("\nWarning: puzzle has no solution")
+		case 2:
+			fmt.Println("\nWarning: puzzle has multiple solutions")
+		}
+	}
+	var stats Stats
+	solved := m.Solve(&stats)
+	fmt.Printf("\nSolved? %v\nBacktracks: %v\n\n", solved, stats.Backtracks)
+	fmt.Println("Ending configuration:")
+	if *split {
+		for i, name := range m.Names {
+			fmt.Printf("%v:\n%v\n", name, m.Grid(i))
+		}
+	} else {
+		fmt.Println(m)
+	}
+	if !solved {
+		return fmt.Errorf("Puzzle has no solution")
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Sizes of the overlay SVG in pixels
+const (
+	overlayMargin = 4
+	overlayTitle  = 28
+)
+
+// overlayRefresh is the default number of seconds between reloads of the
+// overlay page
+const overlayRefresh = 2
+
+// overlaySnapshot is the state of one player in the race, copied under the
+// race lock for rendering
+type overlaySnapshot struct {
+	name   string
+	given  *Game
+	grid   *Game
+	winner string
+}
+
+// snapshot copies the grid of the player called name, or of the player who
+// has filled the most cells if name is empty.  The grid is only the givens if
+// there is nobody to show, and false is returned if there is no such player
+// or no race.
+func (r *race) snapshot(name string) (overlaySnapshot, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.given == nil {
+		return overlaySnapshot{}, false
+	}
+	s := overlaySnapshot{given: r.given.Clone(), winner: r.winner}
+	var best *racer
+	for p := range r.racers {
+		if name != "" && p.name != name {
+			continue
+		}
+		if best == nil || filledOf(p) > filledOf(best) || filledOf(p) == filledOf(best) && p.name < best.name {
+			best = p
+		}
+	}
+	switch {
+	case best != nil && best.grid != nil:
+		s.name, s.grid = best.name, best.grid.Clone()
+	case best != nil:
+		s.name, s.grid = best.name, s.given
+	case name != "":
+		return s, false
+	default:
+		s.grid = s.given
+	}
+	return s, true
+}
+
+// filledOf is the number of cells the player has filled, including givens
+func filledOf(p *racer) int {
+	if p.grid == nil {
+		return 0
+	}
+	return DIM*DIM - p.grid.remaining
+}
+
+// writeSVG draws the board of the snapshot under a title naming the player
+func (s overlaySnapshot) writeSVG(w io.Writer) {
+	title := "Race"
+	if s.name != "" {
+		title = fmt.Sprintf("%v: %v/%v", s.name, DIM*DIM-s.grid.remaining, DIM*DIM)
+	}
+	if s.winner != "" {
+		title += fmt.Sprintf(", won by %v", s.winner)
+	}
+	writeBoardSVG(w, s.given, s.grid, title, activeBoardStyle())
+}
+
+// writeBoardSVG draws grid as an SVG image in style, with the givens of
+// puzzle in bold, under title
+func writeBoardSVG(w io.Writer, puzzle, grid *Game, title string, style BoardStyle) {
+	cell := style.Cell
+	margin := overlayMargin + style.BoxLine/2
+	board := DIM*cell + 2*margin
+	fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%v" height="%v" viewBox="0 0 %[1]v %[2]v" `+
+		`font-family="%v">`+"\n", board, board+overlayTitle, html.EscapeString(style.Font))
+	fmt.Fprintf(w, `<rect width="100%%" height="100%%" fill="%v" fill-opacity="0.85"/>`+"\n", htmlColor(style.Background))
+	fmt.Fprintf(w, `<text x="%v" y="%v" font-size="18" fill="%v">%v</text>`+"\n", margin, overlayTitle-8,
+		htmlColor(style.Given), html.EscapeString(title))
+	fmt.Fprintf(w, `<g transform="translate(%v,%v)">`+"\n", margin, overlayTitle+margin)
+	writeGridLines(w, style)
+	for ri, row := range grid.board {
+		for ci, val := range row {
+			if val == 0 {
+				continue
+			}
+			fill, weight := style.Filled, "normal"
+			if puzzle.board[ri][ci] != 0 {
+				fill, weight = style.Given, "bold"
+			}
+			fmt.Fprintf(w, `<text x="%v" y="%v" font-size="%v" text-anchor="middle" fill="%v" font-weight="%v">%v</text>`+"\n",
+				ci*cell+cell/2, ri*cell+cell*3/4, cell*13/20, htmlColor(fill), weight, val)
+		}
+	}
+	fmt.Fprintln(w, "</g>\n</svg>")
+}
+
+// writeGridLines draws the lines between the cells and boxes of an SVG
+// board in style
+func writeGridLines(w io.Writer, style BoardStyle) {
+	cell := style.Cell
+	for i := 0; i <= DIM; i++ {
+		width := style.Line
+		if i%3 == 0 {
+			width = style.BoxLine
+		}
+		pos := i * cell
+		fmt.Fprintf(w, `<line x1="%v" y1="0" x2="%v" y2="%v" stroke="%v" stroke-width="%v"/>`+"\n",
+			pos, pos, DIM*cell, htmlColor(style.Grid), width)
+		fmt.Fprintf(w, `<line x1="0" y1="%v" x2="%v" y2="%v" stroke="%v" stroke-width="%v"/>`+"\n",
+			pos, DIM*cell, pos, htmlColor(style.Grid), width)
+	}
+}
+
+// handleOverlay serves GET /race/overlay, the board of a player in the race
+// as a page which reloads itself, for adding to a stream as a browser source.
+// Optional query parameters: name of the player, the one furthest along by
+// default, refresh in seconds, and format=svg for the bare image.
+func (r *race) handleOverlay(w http.ResponseWriter, req *http.Request) {
+	q := req.URL.Query()
+	refresh := overlayRefresh
+	if v := q.Get("refresh"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, fmt.Sprintf("Invalid refresh %q, expected a number of seconds", v), http.StatusBadRequest)
+			return
+		}
+		refresh = n
+	}
+	name := q.Get("name")
+	s, ok := r.snapshot(name)
+	if !ok {
+		http.Error(w, fmt.Sprintf("No player %q in the race", name), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	switch q.Get("format") {
+	case "svg":
+		w.Header().Set("Content-Type", "image/svg+xml")
+		s.writeSVG(w)
+	case "", "html":
+		var sb strings.Builder
+		s.writeSVG(&sb)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintf(w, "<!DOCTYPE html>\n<html>\n<head>\n<meta http-equiv=\"refresh\" content=\"%v\">\n"+
+			"<style>body { margin: 0; background: transparent; }</style>\n</head>\n<body>\n%v</body>\n</html>\n",
+			refresh, sb.String())
+	default:
+		http.Error(w, fmt.Sprintf("Invalid format %q, expected html or svg", q.Get("format")), http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Pack is a bundle of graded puzzles, stored as JSON
+type Pack struct {
+	Name    string       `json:"name"`
+	Created time.Time    `json:"created"`
+	Puzzles []PackPuzzle `json:"puzzles"`
+}
+
+// PackPuzzle is a single puzzle of a pack, boards are in the 81 digit line
+// format
+type PackPuzzle struct {
+	ID         int    `json:"id"`
+	Puzzle     string `json:"puzzle"`
+	Solution   string `json:"solution"`
+	Difficulty string `json:"difficulty"`
+	Clues      int    `json:"clues"`
+	Backtracks int    `json:"backtracks"`
+	// Source records the file, and line for collections, the puzzle came from
+	Source string `json:"source"`
+	// Tags lists the techniques needed to solve the puzzle, such as x-chain,
+	// and the symmetry of its clues, such as rotational-symmetry
+	Tags []string `json:"tags,omitempty"`
+	// Metadata is the attribution read with the puzzle, if it had any
+	Metadata *Metadata `json:"metadata,omitempty"`
+}
+
+// ParseDifficulty reads a difficulty in the form produced by its String method
+func ParseDifficulty(s string) (Difficulty, error) {
+	for d := Easy; d <= RequiresGuessing; d++ {
+		if strings.EqualFold(s, d.String()) {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("Unknown difficulty %q", s)
+}
+
+// sourcedGame is a puzzle along with a description of where it was read from
+type sourcedGame struct {
+	game   *Game
+	source string
+	meta   Metadata
+}
+
+// scanPuzzles calls fn with every puzzle at path, stopping at the first error.
+// A directory is searched for puzzle files, while a file may hold any number
+// of puzzles as read by Decoder.
+func scanPuzzles(path string, fn func(sourcedGame) error) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		files, err := filepath.Glob(filepath.Join(path, "*.txt"))
+		if err != nil {
+			return err
+		}
+		for _, f := range files {
+			if err := scanPuzzles(f, fn); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	// The first puzzle is held back until it's known whether the file is a
+	// collection, which are sourced by line
+	var first *sourcedGame
+	dec := NewDecoder(file)
+	for {
+		g, err := dec.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%v line %v: %v", path, dec.Line(), err)
+		}
+		sg := sourcedGame{g, fmt.Sprintf("%v:%v", path, dec.Line()), dec.Metadata()}
+		if first == nil {
+			first = &sg
+			continue
+		}
+		if first.source != "" {
+			if err := fn(*first); err != nil {
+				return err
+			}
+			first.source = ""
+		}
+		if err := fn(sg); err != nil {
+			return err
+		}
+	}
+	if first != nil && first.source != "" {
+		// Not a collection
+		first.source = path
+		return fn(*first)
+	}
+	return nil
+}
+
+// readPack loads a pack written by the pack command
+func readPack(fname string) (*Pack, error) {
+	data, err := os.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	p := &Pack{}
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("%v: %v", fname, err)
+	}
+	return p, nil
+}
+
+// packJob is a puzzle read by the pack command, seq counts from 0 in input
+// order
+type packJob struct {
+	seq int
+	sg  sourcedGame
+}
+
+// packResult is a graded packJob, skip explains why it's left out of the pack
+type packResult struct {
+	PackPuzzle
+	seq       int
+	level     Difficulty
+	canonical string
+	skip      string
+}
+
+// gradePackPuzzle checks, grades, solves and tags a puzzle for the pack
+func gradePackPuzzle(job packJob) packResult {
+	g := job.sg.game
+	r := packResult{PackPuzzle: PackPuzzle{Puzzle: g.Line(), Source: job.sg.source}, seq: job.seq}
+	if n := g.CountSolutions(2); n != 1 {
+		r.skip = "puzzle does not have a unique solution"
+		return r
+	}
+	r.canonical = Canonical(g)
+	s := NewStrategist(g.Clone())
+	s.Solve()
+	solved := g.Clone()
+	recursiveSolver(solved, nil)
+	r.level = s.Difficulty()
+	r.Solution = solved.Line()
+	r.Difficulty = r.level.String()
+	r.Clues = DIM*DIM - g.remaining
+	r.Backtracks = solved.backtracks
+	r.Tags = puzzleTags(g, s)
+	if meta := job.sg.meta; !meta.IsZero() {
+		r.Metadata = &meta
+	}
+	return r
+}
+
+// puzzleTags lists the ids of the techniques the strategy engine used on g,
+// in order of difficulty, followed by the symmetries of its clues
+func puzzleTags(g *Game, s *Strategist) []string {
+	used := make(map[string]bool)
+	for _, step := range s.Steps {
+		used[step.Technique] = true
+	}
+	var tags []string
+	for _, t := range techniques {
+		if used[t.name] {
+			tags = append(tags, t.id)
+		}
+	}
+	var names []string
+	for name, sym := range symmetries {
+		if name != "none" && hasSymmetry(g, sym) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		tags = append(tags, name+"-symmetry")
+	}
+	return tags
+}
+
+// hasSymmetry is true if every cell related by sym to a clue is also a clue
+func hasSymmetry(g *Game, sym symmetry) bool {
+	for ri, cols := range g.board {
+		for ci, val := range cols {
+			if val == 0 {
+				continue
+			}
+			for _, c := range sym(Cell{ri, ci}) {
+				if g.board[c.Row][c.Col] == 0 {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// packCommand handles: pack [flags] <dir or file>..., grading, deduping and
+// sorting the puzzles into a single pack file
+func packCommand(args []string) error {
+	fs := flag.NewFlagSet("pack", flag.ExitOnError)
+	out := fs.String("o", "pack.json", "write the pack to `file`")
+	name := fs.String("name", "", "name of the pack, defaults to the output file name")
+	workers := fs.Int("workers", runtime.NumCPU(), "grade puzzles with `n` goroutines")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		return fmt.Errorf("Usage: pack [flags] <dir or file>...")
+	}
+	if *name == "" {
+		*name = strings.TrimSuffix(filepath.Base(*out), filepath.Ext(*out))
+	}
+
+	// Puzzles are read by one goroutine, graded by a pool of workers, and
+	// then put back in input order so the pack doesn't depend on scheduling
+	jobs := make(chan packJob)
+	results := make(chan packResult)
+	var readErr error
+	go func() {
+		defer close(jobs)
+		seq := 0
+		for _, path := range fs.Args() {
+			readErr = scanPuzzles(path, func(sg sourcedGame) error {
+				jobs <- packJob{seq, sg}
+				seq++
+				return nil
+			})
+			if readErr != nil {
+				return
+			}
+		}
+	}()
+	var wg sync.WaitGroup
+	for i := 0; i < max(1, *workers); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				results <- gradePackPuzzle(job)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	var graded []packResult
+	for r := range results {
+		graded = append(graded, r)
+	}
+	if readErr != nil {
+		return readErr
+	}
+	sort.Slice(graded, func(i, j int) bool { return graded[i].seq < graded[j].seq })
+
+	var puzzles []packResult
+	seen := make(map[string]string)
+	for _, r := range graded {
+		if r.skip != "" {
+			fmt.Fprintf(os.Stderr, "%v: skipping, %v\n", r.Source, r.skip)
+			continue
+		}
+		if first, ok := seen[r.canonical]; ok {
+			fmt.Fprintf(os.Stderr, "%v: skipping duplicate of %v\n", r.Source, first)
+			continue
+		}
+		seen[r.canonical] = r.Source
+		puzzles = append(puzzles, r)
+	}
+	sort.SliceStable(puzzles, func(i, j int) bool {
+		if puzzles[i].level != puzzles[j].level {
+			return puzzles[i].level < puzzles[j].level
+		}
+		return puzzles[i].Backtracks < puzzles[j].Backtracks
+	})
+
+	pack := Pack{Name: *name, Created: time.Now().UTC().Truncate(time.Second)}
+	for i, p := range puzzles {
+		p.ID = i + 1
+		pack.Puzzles = append(pack.Puzzles, p.PackPuzzle)
+	}
+	data, err := json.MarshalIndent(pack, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(*out, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %v puzzles to %v\n", len(pack.Puzzles), *out)
+	return nil
+}
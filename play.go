@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Campaign records a player's progress through the puzzles of packs, stored
+// as JSON
+type Campaign struct {
+	// Packs maps the name of each pack to its results by puzzle ID
+	Packs map[string]map[int]PlayResult `json:"packs"`
+	// History lists every completion in order, for the stats command
+	History []PlayRecord `json:"history,omitempty"`
+}
+
+// PlayRecord is a single completion of a puzzle of a pack
+type PlayRecord struct {
+	Pack       string `json:"pack"`
+	ID         int    `json:"id"`
+	Difficulty string `json:"difficulty"`
+	PlayResult
+}
+
+// PlayResult is the best completion of a single puzzle
+type PlayResult struct {
+	Completed time.Time `json:"completed"`
+	Seconds   float64   `json:"seconds"`
+	Hints     int       `json:"hints"`
+	Stars     int       `json:"stars"`
+}
+
+// starsFor awards 3 stars for a solve without hints, 2 for up to 3 hints,
+// and 1 otherwise
+func starsFor(hints int) int {
+	switch {
+	case hints == 0:
+		return 3
+	case hints <= 3:
+		return 2
+	}
+	return 1
+}
+
+// readCampaign loads the progress saved in fname, empty if it doesn't exist
+// yet
+func readCampaign(fname string) (*Campaign, error) {
+	c := &Campaign{}
+	data, err := os.ReadFile(fname)
+	if errors.Is(err, os.ErrNotExist) {
+		data, err = []byte("{}"), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("%v: %v", fname, err)
+	}
+	if c.Packs == nil {
+		c.Packs = make(map[string]map[int]PlayResult)
+	}
+	return c, nil
+}
+
+// save writes the progress to fname as JSON
+func (c *Campaign) save(fname string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fname, append(data, '\n'), 0644)
+}
+
+// record adds r for puzzle pz of pack to the history, and keeps it as the
+// result of the puzzle if it earns more stars than the best so far, or as
+// many in less time
+func (c *Campaign) record(pack string, pz PackPuzzle, r PlayResult) {
+	c.History = append(c.History, PlayRecord{Pack: pack, ID: pz.ID, Difficulty: pz.Difficulty, PlayResult: r})
+	results := c.Packs[pack]
+	if results == nil {
+		results = make(map[int]PlayResult)
+		c.Packs[pack] = results
+	}
+	best, ok := results[pz.ID]
+	if !ok || r.Stars > best.Stars || r.Stars == best.Stars && r.Seconds < best.Seconds {
+		results[pz.ID] = r
+	}
+}
+
+// formatStars draws a rating of up to 3 stars
+func formatStars(n int) string {
+	return strings.Repeat("*", n) + strings.Repeat(".", 3-n)
+}
+
+// playMenu lists the puzzles of the pack with the player's results, and
+// returns the index of the puzzle chosen, the next unsolved one by default,
+// or -1 to quit
+func playMenu(p *Pack, results map[int]PlayResult, in *bufio.Reader) int {
+	next := -1
+	fmt.Printf("\n%v:\n", p.Name)
+	for i, pz := range p.Puzzles {
+		status := "unsolved"
+		if r, ok := results[pz.ID]; ok {
+			taken := time.Duration(r.Seconds * float64(time.Second))
+			status = fmt.Sprintf("%v %v", formatStars(r.Stars), taken.Round(time.Second))
+		} else if next < 0 {
+			next = i
+		}
+		fmt.Printf("%4v. %-17v %v clues  %v\n", i+1, pz.Difficulty, pz.Clues, status)
+	}
+	for {
+		if next >= 0 {
+			fmt.Printf("Puzzle number, enter for %v, or q to quit: ", next+1)
+		} else {
+			fmt.Print("Every puzzle is solved!  Puzzle number to replay, or q to quit: ")
+		}
+		line, err := in.ReadString('\n')
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "q" || line == "" && err != nil:
+			return -1
+		case line == "" && next >= 0:
+			return next
+		}
+		if n, err := strconv.Atoi(line); err == nil && 1 <= n && n <= len(p.Puzzles) {
+			return n - 1
+		}
+		fmt.Printf("Expected a puzzle number from 1 to %v\n", len(p.Puzzles))
+	}
+}
+
+// playPuzzle lets the player fill in the puzzle move by move, returning the
+// result once it is solved, or false if they quit first
+func playPuzzle(pz PackPuzzle, in *bufio.Reader) (PlayResult, bool) {
+	g, err := ParseLine(pz.Puzzle)
+	if err != nil {
+		fmt.Printf("Puzzle %v: %v\n", pz.ID, err)
+		return PlayResult{}, false
+	}
+	solution, err := ParseLine(pz.Solution)
+	if err != nil {
+		fmt.Printf("Puzzle %v solution: %v\n", pz.ID, err)
+		return PlayResult{}, false
+	}
+	start := time.Now()
+	hints := 0
+	for {
+		fmt.Println()
+		fmt.Println(g.DiffString(false))
+		if g.ValidSolution() && len(invalidCells(*g)) == 0 {
+			r := PlayResult{Completed: time.Now(), Seconds: time.Since(start).Seconds(), Hints: hints,
+				Stars: starsFor(hints)}
+			fmt.Printf("\nSolved in %v with %v hints: %v\n",
+				time.Since(start).Round(time.Second), hints, formatStars(r.Stars))
+			return r, true
+		}
+		fmt.Print("Move such as r1c2=5, r1c2=0 to clear, ? for a hint, or q to quit: ")
+		line, err := in.ReadString('\n')
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "q" || line == "" && err != nil:
+			return PlayResult{}, false
+		case line == "":
+			continue
+		case line == "?":
+			hints++
+			fmt.Println(playHint(g, solution))
+			continue
+		}
+		if name, ok := strings.CutSuffix(line, "=0"); ok {
+			c, err := ParseCell(strings.TrimSpace(name))
+			switch {
+			case err != nil:
+				fmt.Println(err)
+			case g.given[c.Row][c.Col]:
+				fmt.Printf("%v is a given\n", c)
+			default:
+				g.UnmakeMove(c.Row, c.Col)
+			}
+			continue
+		}
+		move, eliminate, err := parseDeduction(line)
+		switch {
+		case err != nil:
+			fmt.Println(err)
+		case eliminate:
+			fmt.Println("Pencil marks aren't kept, enter a value such as r1c2=5")
+		case g.given[move.Row][move.Col]:
+			fmt.Printf("%v is a given\n", move.Cell)
+		default:
+			g.MakeMove(move.Row, move.Col, move.Value)
+		}
+	}
+}
+
+// playHint points out the first wrong entry, or the next step of the
+// strategy engine if there isn't one
+func playHint(g, solution *Game) string {
+	for ri, row := range g.board {
+		for ci, val := range row {
+			if val != 0 && val != solution.board[ri][ci] {
+				return fmt.Sprintf("%v is wrong", Cell{ri, ci})
+			}
+		}
+	}
+	if step := Hint(g); step != nil {
+		return step.String()
+	}
+	return "The strategy engine is stuck too, try a value"
+}
+
+// playCommand handles: play [flags], working through the puzzles of a pack
+// with completion, times and stars saved to a progress file
+func playCommand(args []string) error {
+	fs := flag.NewFlagSet("play", flag.ExitOnError)
+	packFile := fs.String("pack", "pack.json", "play the puzzles of the pack in `file`")
+	saveFile := fs.String("save", "progress.json", "keep progress in `file`")
+	fs.Parse(args)
+	if fs.NArg() != 0 {
+		return fmt.Errorf("Usage: play [flags]")
+	}
+	p, err := readPack(*packFile)
+	if err != nil {
+		return err
+	}
+	if len(p.Puzzles) == 0 {
+		return fmt.Errorf("%v: pack has no puzzles", *packFile)
+	}
+	c, err := readCampaign(*saveFile)
+	if err != nil {
+		return err
+	}
+	in := bufio.NewReader(os.Stdin)
+	for {
+		i := playMenu(p, c.Packs[p.Name], in)
+		if i < 0 {
+			return nil
+		}
+		r, ok := playPuzzle(p.Puzzles[i], in)
+		if !ok {
+			continue
+		}
+		c.record(p.Name, p.Puzzles[i], r)
+		if err := c.save(*saveFile); err != nil {
+			return err
+		}
+	}
+}
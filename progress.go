@@ -0,0 +1,77 @@
+package main
+
+import "encoding/json"
+
+// Progress summarizes how far a player has got with a board
+type Progress struct {
+	Filled, Total int
+	// Unique is false if the givens don't have exactly one solution, in which
+	// case Errors can't be checked
+	Unique bool
+	// Errors lists the filled cells which disagree with the solution
+	Errors []Cell
+}
+
+// Percent is the proportion of cells filled, from 0 to 100
+func (p Progress) Percent() float64 {
+	return 100 * float64(p.Filled) / float64(p.Total)
+}
+
+// Progress counts the filled cells, and checks the moves made so far against
+// the unique solution of the givens
+func (g *Game) Progress() Progress {
+	p := Progress{Filled: DIM*DIM - g.remaining, Total: DIM * DIM}
+	solution := NewGame()
+	for ri, cols := range g.board {
+		for ci, val := range cols {
+			if g.given[ri][ci] {
+				solution.MakeMove(ri, ci, val)
+			}
+		}
+	}
+	if solution.CountSolutions(2) != 1 {
+		return p
+	}
+	recursiveSolver(solution, nil)
+	p.Unique = true
+	for ri, cols := range g.board {
+		for ci, val := range cols {
+			if val != 0 && val != solution.board[ri][ci] {
+				p.Errors = append(p.Errors, Cell{ri, ci})
+			}
+		}
+	}
+	return p
+}
+
+// progressJSON is the JSON form of Progress, with cells in r1c1 notation
+type progressJSON struct {
+	Filled  int      `json:"filled"`
+	Total   int      `json:"total"`
+	Percent float64  `json:"percent"`
+	Unique  bool     `json:"unique"`
+	Errors  []string `json:"errors"`
+}
+
+func (p Progress) MarshalJSON() ([]byte, error) {
+	j := progressJSON{Filled: p.Filled, Total: p.Total, Percent: p.Percent(), Unique: p.Unique,
+		Errors: make([]string, len(p.Errors))}
+	for i, c := range p.Errors {
+		j.Errors[i] = c.String()
+	}
+	return json.Marshal(j)
+}
+
+// PlayerProgress reports the Progress of a player's grid at the puzzle.
+// Cells of the grid which are givens of the puzzle are ignored.
+func PlayerProgress(puzzle, grid *Game) Progress {
+	g := puzzle.Clone()
+	for ri, cols := range grid.board {
+		for ci, val := range cols {
+			if !puzzle.given[ri][ci] && val != 0 {
+				g.MakeMove(ri, ci, val)
+			}
+		}
+	}
+	return g.Progress()
+}
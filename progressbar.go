@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// isTerminal is true if f is a terminal rather than a file or pipe
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// progressBarInterval limits how often the progress bar is redrawn
+const progressBarInterval = 100 * time.Millisecond
+
+// progressBar draws the progress of a batch on stderr.  Progress is measured
+// in bytes of the input, as the number of puzzles isn't known in advance.  A
+// nil progressBar draws nothing, so callers needn't check.  It isn't safe
+// for concurrent use, batch updating it from the one loop solving puzzles.
+type progressBar struct {
+	total   int64
+	start   time.Time
+	drawn   time.Time
+	visible bool
+}
+
+// newProgressBar returns a progress bar for an input of total bytes, or nil
+// if stderr isn't a terminal
+func newProgressBar(total int64) *progressBar {
+	if total <= 0 || !isTerminal(os.Stderr) {
+		return nil
+	}
+	return &progressBar{total: total, start: time.Now()}
+}
+
+// update redraws the bar after done puzzles have been solved from the first
+// read bytes of the input, at most every progressBarInterval
+func (p *progressBar) update(done int, read int64) {
+	if p == nil || time.Since(p.drawn) < progressBarInterval {
+		return
+	}
+	p.drawn = time.Now()
+	elapsed := time.Since(p.start)
+	frac := float64(read) / float64(p.total)
+	if frac > 1 {
+		frac = 1
+	}
+	rate := float64(done) / elapsed.Seconds()
+	eta := "?"
+	if frac > 0 {
+		eta = (time.Duration(float64(elapsed)/frac) - elapsed).Round(time.Second).String()
+	}
+	width := int(frac * histogramWidth)
+	fmt.Fprintf(os.Stderr, "\r\x1b[K[%v%v] %3.0f%% %v puzzles, %.0f/s, ETA %v",
+		strings.Repeat("#", width), strings.Repeat(" ", histogramWidth-width), 100*frac, done, rate, eta)
+	p.visible = true
+}
+
+// clear erases the bar, so other output can be written to the terminal.  It
+// reappears with the next redraw.
+func (p *progressBar) clear() {
+	if p == nil || !p.visible {
+		return
+	}
+	fmt.Fprint(os.Stderr, "\r\x1b[K")
+	p.visible = false
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"math/bits"
+)
+
+// Domains holds the candidates of every cell as a bit set, bit val is set
+// when val is still possible, access as d[row][col]
+type Domains [DIM][DIM]uint16
+
+// cellUnits lists the indices into units of the row, column and box of each
+// cell, access as cellUnits[row][col]
+var cellUnits = buildCellUnits()
+
+func buildCellUnits() (result [DIM][DIM][]int) {
+	for i, unit := range units {
+		for _, c := range unit {
+			result[c.Row][c.Col] = append(result[c.Row][c.Col], i)
+		}
+	}
+	return
+}
+
+// newDomains sets the domain of each filled cell to its value and of each
+// empty cell to every value, then makes them consistent, false if the board
+// has a contradiction
+func newDomains(g *Game) (*Domains, bool) {
+	d := &Domains{}
+	var queue []Cell
+	for ri, cols := range g.board {
+		for ci, val := range cols {
+			if val == 0 {
+				d[ri][ci] = 1<<(DIM+1) - 2
+			} else {
+				d[ri][ci] = 1 << uint(val)
+			}
+			queue = append(queue, Cell{ri, ci})
+		}
+	}
+	_, ok := d.propagate(queue)
+	return d, ok
+}
+
+// singleton returns the value of a domain holding exactly one, or 0
+func singleton(domain uint16) int {
+	if bits.OnesCount16(domain) != 1 {
+		return 0
+	}
+	return bits.TrailingZeros16(domain)
+}
+
+// propagate revises the domains of the queued cells and of any cell whose
+// domain shrinks as a result, until nothing changes.  A cell with a single
+// value removes it from its peers, and a value with a single place in a unit
+// is assigned there.  Returns the number of revisions made, and false if some
+// domain or unit was left with no options.
+func (d *Domains) propagate(queue []Cell) (revisions int, ok bool) {
+	queued := make(map[Cell]bool, len(queue))
+	for _, c := range queue {
+		queued[c] = true
+	}
+	push := func(c Cell) {
+		if !queued[c] {
+			queued[c] = true
+			queue = append(queue, c)
+		}
+	}
+	for len(queue) > 0 {
+		c := queue[0]
+		queue = queue[1:]
+		delete(queued, c)
+
+		if val := singleton(d[c.Row][c.Col]); val != 0 {
+			for _, p := range peers[c.Row][c.Col] {
+				if d[p.Row][p.Col]&(1<<uint(val)) == 0 {
+					continue
+				}
+				d[p.Row][p.Col] &^= 1 << uint(val)
+				revisions++
+				if d[p.Row][p.Col] == 0 {
+					return revisions, false
+				}
+				push(p)
+			}
+		}
+
+		for _, ui := range cellUnits[c.Row][c.Col] {
+			for val := 1; val <= DIM; val++ {
+				var place Cell
+				count := 0
+				for _, u := range units[ui] {
+					if d[u.Row][u.Col]&(1<<uint(val)) != 0 {
+						place = u
+						count++
+					}
+				}
+				if count == 0 {
+					return revisions, false
+				}
+				if count == 1 && d[place.Row][place.Col] != 1<<uint(val) {
+					d[place.Row][place.Col] = 1 << uint(val)
+					revisions++
+					push(place)
+				}
+			}
+		}
+	}
+	return revisions, true
+}
+
+// Propagate fills every cell of the board forced by constraint propagation,
+// returning the number of cells placed and false if the board has no solution.
+// Any solver may be run on the board afterwards.
+func Propagate(g *Game) (placed int, ok bool) {
+	d, ok := newDomains(g)
+	if !ok {
+		return 0, false
+	}
+	for ri, cols := range g.board {
+		for ci, val := range cols {
+			if v := singleton(d[ri][ci]); val == 0 && v != 0 {
+				g.MakeMove(ri, ci, v)
+				g.record(Decision{Cell: Cell{ri, ci}.String(), Value: v, Reason: "constraint propagation"})
+				placed++
+			}
+		}
+	}
+	return placed, true
+}
+
+// Propagator is a Solver which backtracks while maintaining consistency of the
+// domains, propagating after every guess
+type Propagator struct{}
+
+// Solve completes g, counting each failed guess as a backtrack
+func (Propagator) Solve(g *Game, stats *Stats) bool {
+	start := stats.Backtracks
+	defer func() { g.backtracks += stats.Backtracks - start }()
+	d, ok := newDomains(g)
+	if !ok || !propagatingSearch(d, stats) {
+		return false
+	}
+	for ri, cols := range g.board {
+		for ci, val := range cols {
+			if val == 0 {
+				g.MakeMove(ri, ci, singleton(d[ri][ci]))
+				g.record(Decision{Cell: Cell{ri, ci}.String(), Value: g.board[ri][ci],
+					Reason: "propagating search"})
+			}
+		}
+	}
+	return true
+}
+
+// propagatingSearch guesses each value of the cell with the smallest domain,
+// propagating the guess and recursing
+func propagatingSearch(d *Domains, stats *Stats) bool {
+	if stats.expired() {
+		return false
+	}
+	best, min := Cell{-1, -1}, DIM+1
+	for ri := range d {
+		for ci := range d[ri] {
+			if n := bits.OnesCount16(d[ri][ci]); n > 1 && n < min {
+				best, min = Cell{ri, ci}, n
+			}
+		}
+	}
+	if min == DIM+1 {
+		// Every domain is a single value
+		return true
+	}
+	for val := 1; val <= DIM; val++ {
+		if d[best.Row][best.Col]&(1<<uint(val)) == 0 {
+			continue
+		}
+		guess := *d
+		guess[best.Row][best.Col] = 1 << uint(val)
+		if _, ok := guess.propagate([]Cell{best}); ok && propagatingSearch(&guess, stats) {
+			*d = guess
+			return true
+		}
+		stats.Backtracks++
+	}
+	return false
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// publishTimeout limits each request to a webhook
+const publishTimeout = 30 * time.Second
+
+// dailyPost is the puzzle of the day rendered for publishing
+type dailyPost struct {
+	date   string
+	puzzle PackPuzzle
+	board  *Game
+	// text is the plain text message, with the board in a code block
+	text string
+	png  []byte
+	html string
+}
+
+// newDailyPost renders the puzzle of the day graded d for date in style,
+// adding a share link if base is set
+func newDailyPost(pack *Pack, d Difficulty, date, base string, style BoardStyle) (*dailyPost, error) {
+	pz, ok := dailyPuzzle(pack, d, date)
+	if !ok {
+		return nil, fmt.Errorf("No %v puzzles in pack", d)
+	}
+	g, err := ParseLine(pz.Puzzle)
+	if err != nil {
+		return nil, fmt.Errorf("Puzzle %v: %v", pz.ID, err)
+	}
+	p := &dailyPost{date: date, puzzle: pz, board: g}
+	title := fmt.Sprintf("Puzzle of the day, %v (%v, %v clues)", date, pz.Difficulty, pz.Clues)
+	p.text = fmt.Sprintf("%v\n```\n%v```", title, g.GridString())
+	if base != "" {
+		p.text += fmt.Sprintf("\n%v#%v", base, EncodeShare(g))
+	}
+	var buf bytes.Buffer
+	if err := WriteBoardPNG(&buf, g, style); err != nil {
+		return nil, err
+	}
+	p.png = buf.Bytes()
+	var sb strings.Builder
+	writeBoardSVG(&sb, g, g, title, style)
+	p.html = fmt.Sprintf("<!DOCTYPE html>\n<html>\n<head>\n<title>%v</title>\n</head>\n<body>\n%v</body>\n</html>\n",
+		html.EscapeString(title), sb.String())
+	return p, nil
+}
+
+// webhookKind guesses the kind of webhook from its URL
+func webhookKind(url string) string {
+	switch {
+	case strings.Contains(url, "discord"):
+		return "discord"
+	case strings.Contains(url, "/_matrix/"):
+		return "matrix"
+	}
+	return "slack"
+}
+
+// send posts the puzzle to a webhook of kind slack, discord or matrix.
+// Discord receives the PNG as an attachment, Matrix the HTML body of the
+// message, and Slack only the text.
+func (p *dailyPost) send(kind, url, token string) error {
+	var req *http.Request
+	var err error
+	switch kind {
+	case "slack":
+		data, _ := json.Marshal(map[string]string{"text": p.text})
+		req, err = http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+	case "discord":
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		payload, _ := json.Marshal(map[string]string{"content": p.text})
+		mw.WriteField("payload_json", string(payload))
+		fw, _ := mw.CreateFormFile("files[0]", fmt.Sprintf("sudoku-%v.png", p.date))
+		fw.Write(p.png)
+		mw.Close()
+		req, err = http.NewRequest(http.MethodPost, url, &body)
+		if err == nil {
+			req.Header.Set("Content-Type", mw.FormDataContentType())
+		}
+	case "matrix":
+		data, _ := json.Marshal(map[string]string{
+			"msgtype":        "m.text",
+			"body":           p.text,
+			"format":         "org.matrix.custom.html",
+			"formatted_body": fmt.Sprintf("<pre>%v</pre>", html.EscapeString(p.text)),
+		})
+		// the transaction ID makes a retry on the same day idempotent
+		txn := fmt.Sprintf("sudoku-%v-%v", p.date, p.puzzle.ID)
+		req, err = http.NewRequest(http.MethodPut, strings.TrimSuffix(url, "/")+"/"+txn, bytes.NewReader(data))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/json")
+			if token != "" {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+		}
+	default:
+		return fmt.Errorf("Unknown webhook kind %q, expected slack, discord or matrix", kind)
+	}
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: publishTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		if len(bytes.TrimSpace(msg)) == 0 {
+			return fmt.Errorf("Webhook returned %v", resp.Status)
+		}
+		return fmt.Errorf("Webhook returned %v: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// nextPublish returns the first time after now at the UTC time of day at, as
+// HH:MM
+func nextPublish(now time.Time, at string) (time.Time, error) {
+	t, err := time.Parse("15:04", at)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Invalid time %q, expected HH:MM", at)
+	}
+	now = now.UTC()
+	next := time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, time.UTC)
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next, nil
+}
+
+// publishCommand handles: publish [flags], posting the puzzle of the day to
+// a webhook, once or every day with -at
+func publishCommand(args []string) error {
+	fs := flag.NewFlagSet("publish", flag.ExitOnError)
+	packFile := fs.String("pack", "pack.json", "publish the daily puzzle of the pack in `file`")
+	difficulty := fs.String("difficulty", Medium.String(), "difficulty of the daily puzzle")
+	webhook := fs.String("webhook", "", "post to the webhook at `url`, prints the message if empty")
+	kind := fs.String("kind", "", "webhook kind: slack, discord or matrix, guessed from the url by default")
+	token := fs.String("token", "", "Matrix access `token`")
+	base := fs.String("url", "", "add a share link prefixed with `url`")
+	pngFile := fs.String("png", "", "also write the rendered puzzle to the png `file`")
+	htmlFile := fs.String("html", "", "also write the rendered puzzle to the html `file`")
+	at := fs.String("at", "", "keep running, publishing every day at the UTC `time` HH:MM")
+	styleFlags := addBoardStyleFlags(fs)
+	fs.Parse(args)
+	if fs.NArg() != 0 {
+		return fmt.Errorf("Usage: publish [flags]")
+	}
+	style, err := styleFlags.Style()
+	if err != nil {
+		return err
+	}
+	d, err := ParseDifficulty(*difficulty)
+	if err != nil {
+		return err
+	}
+	switch *kind {
+	case "":
+		*kind = webhookKind(*webhook)
+	case "slack", "discord", "matrix":
+	default:
+		return fmt.Errorf("Unknown webhook kind %q, expected slack, discord or matrix", *kind)
+	}
+	if *at != "" {
+		if _, err := nextPublish(time.Now(), *at); err != nil {
+			return err
+		}
+	}
+	pack, err := readPack(*packFile)
+	if err != nil {
+		return err
+	}
+	publish := func(date string) error {
+		p, err := newDailyPost(pack, d, date, *base, style)
+		if err != nil {
+			return err
+		}
+		if *pngFile != "" {
+			if err := os.WriteFile(*pngFile, p.png, 0644); err != nil {
+				return err
+			}
+		}
+		if *htmlFile != "" {
+			if err := os.WriteFile(*htmlFile, []byte(p.html), 0644); err != nil {
+				return err
+			}
+		}
+		if *webhook == "" {
+			fmt.Println(p.text)
+			return nil
+		}
+		if err := p.send(*kind, *webhook, *token); err != nil {
+			return err
+		}
+		slog.Info("published", "date", date, "id", p.puzzle.ID, "kind", *kind)
+		return nil
+	}
+	if *at == "" {
+		return publish(time.Now().UTC().Format(time.DateOnly))
+	}
+	for {
+		next, _ := nextPublish(time.Now(), *at)
+		slog.Info("waiting to publish", "at", next)
+		time.Sleep(time.Until(next))
+		if err := publish(next.Format(time.DateOnly)); err != nil {
+			slog.Error("publishing", "err", err)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Metadata records where a puzzle came from, kept in puzzle files as comment
+// lines such as "# author: Jane Doe" ahead of the board
+type Metadata struct {
+	Author string `json:"author,omitempty"`
+	// Source names the publication or collection the puzzle appeared in
+	Source string `json:"source,omitempty"`
+	// Rating is the difficulty given by the source, in its own terms
+	Rating string   `json:"rating,omitempty"`
+	Tags   []string `json:"tags,omitempty"`
+}
+
+// Puzzle is a board along with its metadata
+type Puzzle struct {
+	Game *Game
+	Metadata
+}
+
+// IsZero is true if no metadata is set
+func (m Metadata) IsZero() bool {
+	return m.Author == "" && m.Source == "" && m.Rating == "" && len(m.Tags) == 0
+}
+
+// parseLine sets the field named by a comment line such as "# rating: 2.5",
+// returning false if the line isn't metadata
+func (m *Metadata) parseLine(line string) bool {
+	comment, ok := strings.CutPrefix(strings.TrimSpace(line), "#")
+	if !ok {
+		return false
+	}
+	key, value, ok := strings.Cut(comment, ":")
+	if !ok {
+		return false
+	}
+	value = strings.TrimSpace(value)
+	switch strings.ToLower(strings.TrimSpace(key)) {
+	case "author":
+		m.Author = value
+	case "source":
+		m.Source = value
+	case "rating":
+		m.Rating = value
+	case "tags":
+		m.Tags = nil
+		for _, t := range strings.Split(value, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				m.Tags = append(m.Tags, t)
+			}
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// write writes each field that is set as a line starting with prefix, which
+// is "# " for puzzle files
+func (m Metadata) write(w io.Writer, prefix string) error {
+	fields := []struct{ key, value string }{
+		{"author", m.Author},
+		{"source", m.Source},
+		{"rating", m.Rating},
+		{"tags", strings.Join(m.Tags, ", ")},
+	}
+	for _, f := range fields {
+		if f.value == "" {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "%v%v: %v\n", prefix, f.key, f.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readMetadata takes the leading blank and comment lines off text, returning
+// the metadata they hold and the rest of text
+func readMetadata(text string) (Metadata, string) {
+	var m Metadata
+	rest := text
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			break
+		}
+		m.parseLine(line)
+		_, rest, _ = strings.Cut(rest, scanner.Text())
+		rest = strings.TrimPrefix(strings.TrimPrefix(rest, "\r"), "\n")
+	}
+	return m, rest
+}
+
+// ParsePuzzle reads a single board from r as Parse does, along with the
+// metadata in the comment lines ahead of it
+func ParsePuzzle(r io.Reader, format Format) (*Puzzle, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	m, text := readMetadata(string(data))
+	sym, text, err := readSymbols(text)
+	if err != nil {
+		return nil, err
+	}
+	g, err := parseText(text, format)
+	if err != nil {
+		return nil, err
+	}
+	g.symbols = sym
+	return &Puzzle{Game: g, Metadata: m}, nil
+}
+
+// RenderPuzzle writes the metadata of p as comment lines followed by the
+// board in format, which ParsePuzzle can read back
+func RenderPuzzle(w io.Writer, p *Puzzle, format Format) error {
+	if err := p.Metadata.write(w, "# "); err != nil {
+		return err
+	}
+	return Render(w, p.Game, format)
+}
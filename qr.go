@@ -0,0 +1,408 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+)
+
+// This is a minimal QR code encoder: byte mode with error correction level L,
+// versions 1 through 6.  That holds up to 134 bytes, plenty for a share link,
+// and avoids the version information blocks of larger codes.
+
+// qrVersion describes the layout of one QR code version at level L
+type qrVersion struct {
+	blocks, dataPerBlock, ecPerBlock int
+	// align is the center coordinate of the bottom right alignment pattern,
+	// or 0 if the version has none
+	align int
+}
+
+var qrVersions = []qrVersion{
+	1: {1, 19, 7, 0},
+	2: {1, 34, 10, 18},
+	3: {1, 55, 15, 22},
+	4: {1, 80, 20, 26},
+	5: {1, 108, 26, 30},
+	6: {2, 68, 18, 34},
+}
+
+// qrCode holds the modules of a QR code, true is dark, access as
+// modules[y][x]
+type qrCode struct {
+	size     int
+	modules  [][]bool
+	function [][]bool
+}
+
+// qrEncode returns a QR code holding data
+func qrEncode(data []byte) (*qrCode, error) {
+	version := 0
+	for v := 1; v < len(qrVersions); v++ {
+		// Mode indicator and 8 bit length take 12 bits
+		if capacity := qrVersions[v].blocks*qrVersions[v].dataPerBlock - 2; len(data) <= capacity {
+			version = v
+			break
+		}
+	}
+	if version == 0 {
+		return nil, fmt.Errorf("%v bytes is too long for a QR code", len(data))
+	}
+	info := qrVersions[version]
+	q := &qrCode{size: version*4 + 17}
+	q.modules = make([][]bool, q.size)
+	q.function = make([][]bool, q.size)
+	for i := range q.modules {
+		q.modules[i] = make([]bool, q.size)
+		q.function[i] = make([]bool, q.size)
+	}
+	q.drawFunctionPatterns(info)
+	q.drawCodewords(qrCodewords(data, info))
+
+	// Pick the mask with the lowest penalty, masks are their own inverse
+	best, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		q.applyMask(mask)
+		q.drawFormat(mask)
+		if p := q.penalty(); bestPenalty < 0 || p < bestPenalty {
+			best, bestPenalty = mask, p
+		}
+		q.applyMask(mask)
+	}
+	q.applyMask(best)
+	q.drawFormat(best)
+	return q, nil
+}
+
+// qrCodewords encodes data in byte mode, pads it to the capacity of the
+// version, and appends the interleaved error correction codewords
+func qrCodewords(data []byte, info qrVersion) []byte {
+	capacity := info.blocks * info.dataPerBlock
+	var bits []bool
+	appendBits := func(val, n int) {
+		for i := n - 1; i >= 0; i-- {
+			bits = append(bits, (val>>uint(i))&1 != 0)
+		}
+	}
+	appendBits(0x4, 4)
+	appendBits(len(data), 8)
+	for _, b := range data {
+		appendBits(int(b), 8)
+	}
+	// Terminator, then pad to a whole byte
+	for i := 0; i < 4 && len(bits) < capacity*8; i++ {
+		bits = append(bits, false)
+	}
+	for len(bits)%8 != 0 {
+		bits = append(bits, false)
+	}
+	var codewords []byte
+	for i := 0; i < len(bits); i += 8 {
+		var b byte
+		for j := 0; j < 8; j++ {
+			if bits[i+j] {
+				b |= 0x80 >> uint(j)
+			}
+		}
+		codewords = append(codewords, b)
+	}
+	for pad := byte(0xEC); len(codewords) < capacity; pad ^= 0xEC ^ 0x11 {
+		codewords = append(codewords, pad)
+	}
+
+	// Split into blocks, and interleave the data and then the error correction
+	divisor := rsDivisor(info.ecPerBlock)
+	var blocks, ecs [][]byte
+	for i := 0; i < info.blocks; i++ {
+		block := codewords[i*info.dataPerBlock : (i+1)*info.dataPerBlock]
+		blocks = append(blocks, block)
+		ecs = append(ecs, rsRemainder(block, divisor))
+	}
+	var result []byte
+	for i := 0; i < info.dataPerBlock; i++ {
+		for _, block := range blocks {
+			result = append(result, block[i])
+		}
+	}
+	for i := 0; i < info.ecPerBlock; i++ {
+		for _, ec := range ecs {
+			result = append(result, ec[i])
+		}
+	}
+	return result
+}
+
+// gfMultiply multiplies in GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1
+func gfMultiply(x, y byte) byte {
+	var z int
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11D)
+		z ^= int((y>>uint(i))&1) * int(x)
+	}
+	return byte(z)
+}
+
+// rsDivisor returns the Reed-Solomon generator polynomial of degree n, highest
+// power first with the leading 1 omitted
+func rsDivisor(n int) []byte {
+	result := make([]byte, n)
+	result[n-1] = 1
+	root := byte(1)
+	for i := 0; i < n; i++ {
+		for j := range result {
+			result[j] = gfMultiply(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+	return result
+}
+
+// rsRemainder returns the error correction codewords for data
+func rsRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, d := range divisor {
+			result[i] ^= gfMultiply(d, factor)
+		}
+	}
+	return result
+}
+
+func (q *qrCode) setFunction(x, y int, dark bool) {
+	q.modules[y][x] = dark
+	q.function[y][x] = true
+}
+
+// drawFunctionPatterns draws the finder, timing and alignment patterns, and
+// reserves the format areas
+func (q *qrCode) drawFunctionPatterns(info qrVersion) {
+	for i := 0; i < q.size; i++ {
+		q.setFunction(6, i, i%2 == 0)
+		q.setFunction(i, 6, i%2 == 0)
+	}
+	q.drawFinder(3, 3)
+	q.drawFinder(q.size-4, 3)
+	q.drawFinder(3, q.size-4)
+	if info.align != 0 {
+		for dy := -2; dy <= 2; dy++ {
+			for dx := -2; dx <= 2; dx++ {
+				d := max(abs(dx), abs(dy))
+				q.setFunction(info.align+dx, info.align+dy, d != 1)
+			}
+		}
+	}
+	// Reserve the format areas until the mask is chosen
+	q.drawFormat(0)
+}
+
+// drawFinder draws a finder pattern and its separator centered on x, y
+func (q *qrCode) drawFinder(x, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			xx, yy := x+dx, y+dy
+			if xx < 0 || q.size <= xx || yy < 0 || q.size <= yy {
+				continue
+			}
+			d := max(abs(dx), abs(dy))
+			q.setFunction(xx, yy, d != 2 && d != 4)
+		}
+	}
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// drawFormat draws both copies of the format bits for level L and mask
+func (q *qrCode) drawFormat(mask int) {
+	// Level L is 01
+	data := 1<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+	bit := func(i int) bool { return (bits>>uint(i))&1 != 0 }
+
+	for i := 0; i <= 5; i++ {
+		q.setFunction(8, i, bit(i))
+	}
+	q.setFunction(8, 7, bit(6))
+	q.setFunction(8, 8, bit(7))
+	q.setFunction(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		q.setFunction(14-i, 8, bit(i))
+	}
+	for i := 0; i < 8; i++ {
+		q.setFunction(q.size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		q.setFunction(8, q.size-15+i, bit(i))
+	}
+	q.setFunction(8, q.size-8, true)
+}
+
+// drawCodewords places the codewords in the zig zag pattern, two columns at
+// a time from the right, skipping function modules
+func (q *qrCode) drawCodewords(codewords []byte) {
+	i := 0
+	for right := q.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			// Skip the vertical timing pattern
+			right = 5
+		}
+		for vert := 0; vert < q.size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					y = q.size - 1 - vert
+				}
+				if !q.function[y][x] && i < len(codewords)*8 {
+					q.modules[y][x] = (codewords[i>>3]>>uint(7-i&7))&1 != 0
+					i++
+				}
+			}
+		}
+	}
+}
+
+// applyMask inverts the data modules selected by mask pattern 0 through 7
+func (q *qrCode) applyMask(mask int) {
+	for y := 0; y < q.size; y++ {
+		for x := 0; x < q.size; x++ {
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert && !q.function[y][x] {
+				q.modules[y][x] = !q.modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty scores how hard the code may be to scan: long runs of one color,
+// 2x2 blocks, and imbalance between dark and light
+func (q *qrCode) penalty() int {
+	result, dark := 0, 0
+	for a := 0; a < q.size; a++ {
+		rowRun, colRun := 1, 1
+		for b := 0; b < q.size; b++ {
+			if q.modules[a][b] {
+				dark++
+			}
+			if b == 0 {
+				continue
+			}
+			rowRun = runPenalty(q.modules[a][b] == q.modules[a][b-1], rowRun, &result)
+			colRun = runPenalty(q.modules[b][a] == q.modules[b-1][a], colRun, &result)
+		}
+	}
+	for y := 0; y+1 < q.size; y++ {
+		for x := 0; x+1 < q.size; x++ {
+			c := q.modules[y][x]
+			if c == q.modules[y][x+1] && c == q.modules[y+1][x] && c == q.modules[y+1][x+1] {
+				result += 3
+			}
+		}
+	}
+	total := q.size * q.size
+	result += abs(dark*20-total*10) / total * 10
+	return result
+}
+
+// runPenalty extends a run of same colored modules, adding 3 to the penalty
+// when it reaches 5 and 1 more for each module after
+func runPenalty(same bool, run int, penalty *int) int {
+	if !same {
+		return 1
+	}
+	run++
+	if run == 5 {
+		*penalty += 3
+	} else if run > 5 {
+		*penalty++
+	}
+	return run
+}
+
+// qrQuietZone is the width of the light border around a QR code, in modules
+const qrQuietZone = 4
+
+// dark reports the module at x, y, allowing for the quiet zone
+func (q *qrCode) dark(x, y int) bool {
+	x, y = x-qrQuietZone, y-qrQuietZone
+	if x < 0 || q.size <= x || y < 0 || q.size <= y {
+		return false
+	}
+	return q.modules[y][x]
+}
+
+// String renders the code with unicode half blocks, two rows of modules per
+// line of text.  Light modules are drawn as blocks, which suits a terminal
+// with light text on a dark background.
+func (q *qrCode) String() string {
+	var sb strings.Builder
+	full := q.size + 2*qrQuietZone
+	for y := 0; y < full; y += 2 {
+		for x := 0; x < full; x++ {
+			top, bottom := !q.dark(x, y), !q.dark(x, y+1) && y+1 < full
+			switch {
+			case top && bottom:
+				sb.WriteRune('█')
+			case top:
+				sb.WriteRune('▀')
+			case bottom:
+				sb.WriteRune('▄')
+			default:
+				sb.WriteRune(' ')
+			}
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// WritePNG renders the code as a PNG image with scale pixels per module
+func (q *qrCode) WritePNG(w io.Writer, scale int) error {
+	full := q.size + 2*qrQuietZone
+	img := image.NewGray(image.Rect(0, 0, full*scale, full*scale))
+	for y := 0; y < full*scale; y++ {
+		for x := 0; x < full*scale; x++ {
+			c := color.Gray{255}
+			if q.dark(x/scale, y/scale) {
+				c = color.Gray{0}
+			}
+			img.SetGray(x, y, c)
+		}
+	}
+	return png.Encode(w, img)
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// PuzzleQuery selects puzzles of a pack, zero fields match anything
+type PuzzleQuery struct {
+	Difficulty string
+	// MinClues and MaxClues bound the number of clues, inclusive
+	MinClues, MaxClues int
+	// Tag is a comma separated list of tag filters the puzzle must all
+	// match, see puzzleFacets.matches
+	Tag string
+	// Limit caps the number of puzzles returned
+	Limit int
+}
+
+// matches is true if p satisfies every condition of q
+func (q PuzzleQuery) matches(p PackPuzzle) bool {
+	if q.Difficulty != "" && !strings.EqualFold(p.Difficulty, q.Difficulty) {
+		return false
+	}
+	if p.Clues < q.MinClues || (q.MaxClues > 0 && p.Clues > q.MaxClues) {
+		return false
+	}
+	return packFacets(p).matchesAll(q.Tag)
+}
+
+// puzzleFacets is what a tag filter can select a puzzle by
+type puzzleFacets struct {
+	// tags are the technique and symmetry tags given by the grader
+	tags  []string
+	grade string
+	// source is where the puzzle was read from
+	source string
+	meta   Metadata
+}
+
+// packFacets returns the facets of a puzzle of a pack
+func packFacets(p PackPuzzle) puzzleFacets {
+	f := puzzleFacets{tags: p.Tags, grade: p.Difficulty, source: p.Source}
+	if p.Metadata != nil {
+		f.meta = *p.Metadata
+	}
+	return f
+}
+
+// gradeFacets grades g to find its facets, for puzzles not read from a pack
+func gradeFacets(g *Game, source string, meta Metadata) puzzleFacets {
+	s := NewStrategist(g.Clone())
+	s.Solve()
+	return puzzleFacets{tags: puzzleTags(g, s), grade: s.Difficulty().String(), source: source, meta: meta}
+}
+
+// matches is true if the puzzle has the tag filter.  A plain filter such as
+// x-chain is one of the tags of the grader or the metadata.  A filter of the
+// form key:value selects by grade, such as grade:hard, or by the metadata
+// author, source or rating, where source:nyt matches any source naming nyt.
+// Any other key:value filter is matched as a plain tag, so variant:x selects
+// puzzles whose metadata is tagged variant:x.
+func (f puzzleFacets) matches(filter string) bool {
+	for _, tags := range [][]string{f.tags, f.meta.Tags} {
+		for _, t := range tags {
+			if strings.EqualFold(t, filter) {
+				return true
+			}
+		}
+	}
+	key, value, ok := strings.Cut(filter, ":")
+	if !ok {
+		return false
+	}
+	contains := func(s string) bool {
+		return value != "" && strings.Contains(strings.ToLower(s), strings.ToLower(value))
+	}
+	switch strings.ToLower(key) {
+	case "grade":
+		return strings.EqualFold(f.grade, strings.ReplaceAll(value, "-", " "))
+	case "source":
+		return contains(f.meta.Source) || contains(f.source)
+	case "author":
+		return contains(f.meta.Author)
+	case "rating":
+		return strings.EqualFold(f.meta.Rating, value)
+	}
+	return false
+}
+
+// matchesAll is true if the puzzle has every filter of the comma separated
+// list, which may be empty
+func (f puzzleFacets) matchesAll(list string) bool {
+	for _, filter := range strings.Split(list, ",") {
+		if filter = strings.TrimSpace(filter); filter != "" && !f.matches(filter) {
+			return false
+		}
+	}
+	return true
+}
+
+// Query returns the puzzles of the pack matching q, in pack order
+func (p *Pack) Query(q PuzzleQuery) []PackPuzzle {
+	var result []PackPuzzle
+	for _, puzzle := range p.Puzzles {
+		if q.Limit > 0 && len(result) == q.Limit {
+			break
+		}
+		if q.matches(puzzle) {
+			result = append(result, puzzle)
+		}
+	}
+	return result
+}
+
+// Random returns a puzzle of the pack chosen uniformly from those matching q,
+// ignoring its Limit, and false if none match.  rng may be nil to use the
+// global source.
+func (p *Pack) Random(q PuzzleQuery, rng *rand.Rand) (PackPuzzle, bool) {
+	q.Limit = 0
+	matches := p.Query(q)
+	if len(matches) == 0 {
+		return PackPuzzle{}, false
+	}
+	if rng == nil {
+		return matches[rand.Intn(len(matches))], true
+	}
+	return matches[rng.Intn(len(matches))], true
+}
+
+// parseRange reads an inclusive range of the form 24..26, 24.., ..26 or just
+// 24, where a missing bound is returned as zero
+func parseRange(s string) (low, high int, err error) {
+	lowStr, highStr, isRange := strings.Cut(s, "..")
+	if !isRange {
+		highStr = lowStr
+	}
+	if lowStr != "" {
+		if low, err = strconv.Atoi(lowStr); err != nil {
+			return 0, 0, fmt.Errorf("Invalid range %q, expected low..high", s)
+		}
+	}
+	if highStr != "" {
+		if high, err = strconv.Atoi(highStr); err != nil {
+			return 0, 0, fmt.Errorf("Invalid range %q, expected low..high", s)
+		}
+	}
+	if high > 0 && low > high {
+		return 0, 0, fmt.Errorf("Invalid range %q, low is above high", s)
+	}
+	return low, high, nil
+}
+
+// queryCommand handles: query [flags], printing the puzzles of a pack which
+// match the given criteria
+func queryCommand(args []string) error {
+	fs := flag.NewFlagSet("query", flag.ExitOnError)
+	packFile := fs.String("pack", "pack.json", "query the pack in `file`")
+	difficulty := fs.String("difficulty", "", "only puzzles graded `level`")
+	clues := fs.String("clues", "", "only puzzles with a number of clues in `range`, such as 24..26")
+	tag := fs.String("tag", "", "only puzzles matching the comma separated tag `filters`, such as x-chain or grade:hard")
+	limit := fs.Int("limit", 0, "print at most `n` puzzles, 0 for all")
+	formatName := fs.String("format", "line", "output `format`: "+strings.Join(formatNames, ", "))
+	fs.Parse(args)
+	if fs.NArg() != 0 {
+		return fmt.Errorf("Usage: query [flags]")
+	}
+	q := PuzzleQuery{Tag: *tag, Limit: *limit}
+	if *difficulty != "" {
+		d, err := ParseDifficulty(*difficulty)
+		if err != nil {
+			return err
+		}
+		q.Difficulty = d.String()
+	}
+	if *clues != "" {
+		var err error
+		if q.MinClues, q.MaxClues, err = parseRange(*clues); err != nil {
+			return err
+		}
+	}
+	format, err := ParseFormat(*formatName)
+	if err != nil {
+		return err
+	}
+	pack, err := readPack(*packFile)
+	if err != nil {
+		return err
+	}
+	for i, p := range pack.Query(q) {
+		g, err := ParseLine(p.Puzzle)
+		if err != nil {
+			return fmt.Errorf("Puzzle %v: %v", p.ID, err)
+		}
+		if i > 0 && format == GridFormat {
+			fmt.Println()
+		}
+		pz := &Puzzle{Game: g}
+		if p.Metadata != nil {
+			pz.Metadata = *p.Metadata
+		}
+		if err := RenderPuzzle(os.Stdout, pz, format); err != nil {
+			return err
+		}
+	}
+	return nil
+}
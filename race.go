@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// raceRestart is the pause between a win and the start of the next race
+const raceRestart = 10 * time.Second
+
+// RaceMessage is a JSON message of the race WebSocket.  Clients send join
+// with their name, then progress with their grid in the 81 digit line format
+// as they fill it in.  The server sends puzzle to every player when a race
+// starts, progress with the number of cells each player has filled, leave
+// when a player disconnects, winner for the first correct completion, and
+// error for a message it couldn't accept.
+type RaceMessage struct {
+	Type       string `json:"type"`
+	Name       string `json:"name,omitempty"`
+	ID         int    `json:"id,omitempty"`
+	Puzzle     string `json:"puzzle,omitempty"`
+	Difficulty string `json:"difficulty,omitempty"`
+	Grid       string `json:"grid,omitempty"`
+	Filled     int    `json:"filled,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// racer is a player connected to the race
+type racer struct {
+	conn *wsConn
+	name string
+	// grid is the latest progress of the player on the current puzzle
+	grid *Game
+}
+
+// race is the single race of serve mode, which every connected player takes
+// part in
+type race struct {
+	pack *Pack
+	mu   sync.Mutex
+	// racers holds the players which have joined
+	racers  map[*racer]bool
+	current PackPuzzle
+	given   *Game
+	winner  string
+}
+
+func newRace(pack *Pack) *race {
+	r := &race{pack: pack, racers: make(map[*racer]bool)}
+	r.start()
+	return r
+}
+
+// start picks a new puzzle and sends it to every player.  If there is no
+// puzzle to race the failure is logged and the race waits with no puzzle,
+// r.given being nil.
+func (r *race) start() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p, ok := r.pack.Random(PuzzleQuery{}, nil)
+	if !ok {
+		slog.Error("starting race", "err", "pack has no puzzles")
+		return
+	}
+	g, err := ParseLine(p.Puzzle)
+	if err != nil {
+		slog.Error("starting race", "id", p.ID, "err", err)
+		return
+	}
+	r.current, r.given, r.winner = p, g, ""
+	for p := range r.racers {
+		p.grid = nil
+	}
+	slog.Info("race started", "id", p.ID, "racers", len(r.racers))
+	r.broadcast(r.puzzleMessage())
+}
+
+func (r *race) puzzleMessage() RaceMessage {
+	return RaceMessage{Type: "puzzle", ID: r.current.ID, Puzzle: r.current.Puzzle,
+		Difficulty: r.current.Difficulty}
+}
+
+// broadcast sends msg to every player, the lock must be held
+func (r *race) broadcast(msg RaceMessage) {
+	data, _ := json.Marshal(msg)
+	for p := range r.racers {
+		if err := p.conn.WriteMessage(data); err != nil {
+			slog.Debug("race write", "name", p.name, "err", err)
+		}
+	}
+}
+
+// send writes msg to a single player
+func (p *racer) send(msg RaceMessage) error {
+	data, _ := json.Marshal(msg)
+	return p.conn.WriteMessage(data)
+}
+
+// join adds a player under a name no other player is using, failing if
+// there is no puzzle to race
+func (r *race) join(p *racer, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.given == nil {
+		return fmt.Errorf("No puzzle is being raced")
+	}
+	if name == "" {
+		name = "player"
+	}
+	p.name = name
+	for n := 2; r.nameTaken(p.name); n++ {
+		p.name = fmt.Sprintf("%v %v", name, n)
+	}
+	r.racers[p] = true
+	p.send(RaceMessage{Type: "join", Name: p.name})
+	p.send(r.puzzleMessage())
+	return nil
+}
+
+func (r *race) nameTaken(name string) bool {
+	for p := range r.racers {
+		if p.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// leave removes a player, telling the others
+func (r *race) leave(p *racer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.racers[p] {
+		delete(r.racers, p)
+		r.broadcast(RaceMessage{Type: "leave", Name: p.name})
+	}
+}
+
+// progress checks the grid of a player against the current puzzle, sharing
+// how far they have got, and ends the race if they are the first to solve it
+func (r *race) progress(p *racer, id int, line string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.given == nil {
+		return fmt.Errorf("No puzzle is being raced")
+	}
+	if id != 0 && id != r.current.ID {
+		return fmt.Errorf("Puzzle %v is not being raced", id)
+	}
+	g, err := ParseLine(line)
+	if err != nil {
+		return err
+	}
+	for ri, row := range r.given.board {
+		for ci, val := range row {
+			if val != 0 && g.board[ri][ci] != val {
+				return fmt.Errorf("Grid changes the given at %v", Cell{ri, ci})
+			}
+		}
+	}
+	p.grid = g
+	r.broadcast(RaceMessage{Type: "progress", Name: p.name, Filled: DIM*DIM - g.remaining})
+	if r.winner == "" && g.Line() == r.current.Solution {
+		r.winner = p.name
+		slog.Info("race won", "id", r.current.ID, "name", p.name)
+		r.broadcast(RaceMessage{Type: "winner", Name: p.name, ID: r.current.ID})
+		time.AfterFunc(raceRestart, r.start)
+	}
+	return nil
+}
+
+// handleRace serves GET /race, upgrading to a WebSocket which takes part in
+// the race using RaceMessage
+func (r *race) handleRace(w http.ResponseWriter, req *http.Request) {
+	conn, err := upgradeWebSocket(w, req, "")
+	if err != nil {
+		slog.Debug("race upgrade", "err", err)
+		return
+	}
+	defer conn.Close()
+	p := &racer{conn: conn}
+	defer r.leave(p)
+	for {
+		data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var msg RaceMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			p.send(RaceMessage{Type: "error", Error: fmt.Sprintf("Invalid message: %v", err)})
+			continue
+		}
+		switch {
+		case msg.Type == "join" && p.name == "":
+			if err := r.join(p, msg.Name); err != nil {
+				p.send(RaceMessage{Type: "error", Error: err.Error()})
+			}
+		case msg.Type == "progress" && p.name != "":
+			if err := r.progress(p, msg.ID, msg.Grid); err != nil {
+				p.send(RaceMessage{Type: "error", Error: err.Error()})
+			}
+		default:
+			p.send(RaceMessage{Type: "error", Error: fmt.Sprintf("Unexpected %q message", msg.Type)})
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// rpcRequest is a JSON-RPC 2.0 request, or a notification if ID is absent
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+// rpcResponse is a JSON-RPC 2.0 response, holding either Result or Error
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// JSON-RPC 2.0 error codes
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+)
+
+// rpcPuzzleParams are the parameters of every method, the board is in the
+// 81 digit line format
+type rpcPuzzleParams struct {
+	Puzzle string `json:"puzzle"`
+	// Algo selects the solver for solve, backtrack by default
+	Algo string `json:"algo,omitempty"`
+	// Grid is the player's attempt for validate and progress, which may be
+	// incomplete
+	Grid string `json:"grid,omitempty"`
+	// Cell is the cell explained by why, in r1c1 notation
+	Cell string `json:"cell,omitempty"`
+}
+
+type rpcSolveResult struct {
+	Solved     bool   `json:"solved"`
+	Solution   string `json:"solution,omitempty"`
+	Checksum   string `json:"checksum,omitempty"`
+	Backtracks int    `json:"backtracks"`
+}
+
+type rpcHintResult struct {
+	// Found is false if the strategy engine has no step to offer
+	Found     bool   `json:"found"`
+	Technique string `json:"technique,omitempty"`
+	// Cell and Value are set when the step places a value
+	Cell        string   `json:"cell,omitempty"`
+	Value       int      `json:"value,omitempty"`
+	Elimination []string `json:"eliminations,omitempty"`
+	Description string   `json:"description,omitempty"`
+}
+
+// rpcWhyResult is a CellReport with its cells in r1c1 notation
+type rpcWhyResult struct {
+	Cell string `json:"cell"`
+	// Value is set if the cell is already filled
+	Value      int   `json:"value,omitempty"`
+	Candidates []int `json:"candidates,omitempty"`
+	// Eliminated maps each ruled out value to the peers holding it
+	Eliminated map[int][]rpcPeer `json:"eliminated,omitempty"`
+	// Resolution is the step filling the cell, absent if the strategy engine
+	// gets stuck first
+	Resolution  *rpcHintResult `json:"resolution,omitempty"`
+	StepsBefore int            `json:"steps_before,omitempty"`
+}
+
+type rpcPeer struct {
+	Cell string `json:"cell"`
+	Unit string `json:"unit"`
+}
+
+type rpcGradeResult struct {
+	Difficulty string `json:"difficulty"`
+	// Solutions counts the solutions, up to 2
+	Solutions int `json:"solutions"`
+	Steps     int `json:"steps"`
+}
+
+// rpcMethods maps the methods of the stdio mode to their handlers
+var rpcMethods = map[string]func(p rpcPuzzleParams, g *Game) (interface{}, error){
+	"solve":    rpcSolve,
+	"hint":     rpcHint,
+	"validate": rpcValidate,
+	"grade":    rpcGrade,
+	"progress": rpcProgress,
+	"why":      rpcWhy,
+}
+
+func rpcSolve(p rpcPuzzleParams, g *Game) (interface{}, error) {
+	name := p.Algo
+	if name == "" {
+		name = "backtrack"
+	}
+	solver, ok := solvers[name]
+	if !ok {
+		return nil, fmt.Errorf("Unknown algorithm %q", name)
+	}
+	var stats Stats
+	r := rpcSolveResult{Solved: solver.Solve(g, &stats), Backtracks: stats.Backtracks}
+	if r.Solved {
+		r.Solution = g.Line()
+		r.Checksum = g.Checksum()
+	}
+	return r, nil
+}
+
+func rpcHint(p rpcPuzzleParams, g *Game) (interface{}, error) {
+	step := Hint(g)
+	if step == nil {
+		return rpcHintResult{}, nil
+	}
+	return newRPCHintResult(step), nil
+}
+
+func newRPCHintResult(step *Step) rpcHintResult {
+	r := rpcHintResult{Found: true, Technique: step.Technique, Value: step.Value, Description: step.String()}
+	if step.Value != 0 {
+		r.Cell = step.Cell.String()
+	}
+	for _, e := range step.Eliminations {
+		r.Elimination = append(r.Elimination, e.String())
+	}
+	return r
+}
+
+func rpcWhy(p rpcPuzzleParams, g *Game) (interface{}, error) {
+	c, err := ParseCell(p.Cell)
+	if err != nil {
+		return nil, err
+	}
+	return newRPCWhyResult(Why(g, c)), nil
+}
+
+func newRPCWhyResult(report *CellReport) rpcWhyResult {
+	r := rpcWhyResult{Cell: report.Cell.String(), Value: report.Value, Candidates: report.Candidates,
+		StepsBefore: report.StepsBefore}
+	for val, peers := range report.Eliminated {
+		if r.Eliminated == nil {
+			r.Eliminated = make(map[int][]rpcPeer)
+		}
+		for _, p := range peers {
+			r.Eliminated[val] = append(r.Eliminated[val], rpcPeer{p.Cell.String(), p.Unit})
+		}
+	}
+	if report.Resolution != nil {
+		step := newRPCHintResult(report.Resolution)
+		r.Resolution = &step
+	}
+	return r
+}
+
+func rpcValidate(p rpcPuzzleParams, g *Game) (interface{}, error) {
+	grid, err := ParseLine(p.Grid)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid grid: %v", err)
+	}
+	return GradeSolution(g, grid), nil
+}
+
+func rpcProgress(p rpcPuzzleParams, g *Game) (interface{}, error) {
+	grid, err := ParseLine(p.Grid)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid grid: %v", err)
+	}
+	return PlayerProgress(g, grid), nil
+}
+
+func rpcGrade(p rpcPuzzleParams, g *Game) (interface{}, error) {
+	r := rpcGradeResult{Solutions: g.CountSolutions(2)}
+	s := NewStrategist(g)
+	s.Solve()
+	r.Difficulty = s.Difficulty().String()
+	r.Steps = len(s.Steps)
+	return r, nil
+}
+
+// call runs a single request, returning its response
+func (req rpcRequest) call() *rpcResponse {
+	resp := &rpcResponse{JSONRPC: "2.0", ID: req.ID}
+	fail := func(code int, err error) *rpcResponse {
+		resp.Error = &rpcError{Code: code, Message: err.Error()}
+		return resp
+	}
+	if req.JSONRPC != "2.0" {
+		return fail(rpcInvalidRequest, fmt.Errorf("Expected jsonrpc version 2.0"))
+	}
+	method, ok := rpcMethods[req.Method]
+	if !ok {
+		return fail(rpcMethodNotFound, fmt.Errorf("Unknown method %q", req.Method))
+	}
+	var params rpcPuzzleParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return fail(rpcInvalidParams, err)
+	}
+	g, err := ParseLine(params.Puzzle)
+	if err != nil {
+		return fail(rpcInvalidParams, fmt.Errorf("Invalid puzzle: %v", err))
+	}
+	result, err := method(params, g)
+	if err != nil {
+		return fail(rpcInvalidParams, err)
+	}
+	resp.Result = result
+	return resp
+}
+
+// serveRPC answers JSON-RPC 2.0 requests read from r, one JSON value per
+// request, writing each response to w as a line of JSON, until r is closed
+func serveRPC(r io.Reader, w io.Writer) error {
+	dec := json.NewDecoder(bufio.NewReader(r))
+	enc := json.NewEncoder(w)
+	for {
+		var raw json.RawMessage
+		err := dec.Decode(&raw)
+		if err == io.EOF {
+			return nil
+		}
+		var resp *rpcResponse
+		if err != nil {
+			if _, ok := err.(*json.SyntaxError); !ok {
+				return err
+			}
+			// The stream can't be resynchronized after bad JSON
+			resp = &rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"),
+				Error: &rpcError{Code: rpcParseError, Message: err.Error()}}
+			enc.Encode(resp)
+			return err
+		}
+		// Valid JSON which isn't a request, such as a batch, is answered
+		// without stopping
+		var req rpcRequest
+		if raw[0] != '{' {
+			resp = &rpcResponse{Error: &rpcError{Code: rpcInvalidRequest, Message: "Expected a request object"}}
+		} else if err := json.Unmarshal(raw, &req); err != nil {
+			resp = &rpcResponse{Error: &rpcError{Code: rpcInvalidRequest,
+				Message: fmt.Sprintf("Invalid request: %v", err)}}
+		}
+		if resp != nil {
+			resp.JSONRPC, resp.ID = "2.0", json.RawMessage("null")
+			if err := enc.Encode(resp); err != nil {
+				return err
+			}
+			continue
+		}
+		if resp = req.call(); req.ID != nil {
+			// Notifications aren't answered, even on error
+			if err := enc.Encode(resp); err != nil {
+				return err
+			}
+		}
+	}
+}
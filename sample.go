@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/bits"
+	"math/rand"
+	"time"
+)
+
+// sampleCountLimit caps the solutions counted below each value tried by
+// SampleSolution.  Branches with fewer solutions are weighted exactly, so
+// sampling is uniform once the board is constrained enough.
+const sampleCountLimit = 256
+
+// SampleSolution returns a random solution of the board, or nil if it has
+// none.  Each empty cell takes a value with probability proportional to the
+// solutions left by it, counted up to sampleCountLimit, so the solutions
+// are close to uniformly chosen.  The board is left unchanged.
+func (g *Game) SampleSolution(rng *rand.Rand) *Game {
+	c := g.Clone()
+	for !c.ValidSolution() {
+		row, col := c.NextEmptyCell()
+		var values, weights []int
+		total := 0
+		for mask := c.candidateMask(row, col); mask != 0; mask &= mask - 1 {
+			val := bits.TrailingZeros16(mask)
+			c.MakeMove(row, col, val)
+			if n := c.CountSolutions(sampleCountLimit); n > 0 {
+				values = append(values, val)
+				weights = append(weights, n)
+				total += n
+			}
+			c.UnmakeMove(row, col)
+		}
+		if total == 0 {
+			return nil
+		}
+		pick := rng.Intn(total)
+		for i, w := range weights {
+			if pick < w {
+				c.MakeMove(row, col, values[i])
+				break
+			}
+			pick -= w
+		}
+	}
+	return c
+}
+
+// sampleCommand handles: sample [flags] <puzzle file>, printing random
+// solutions of an under-constrained puzzle, one per line
+func sampleCommand(args []string) error {
+	fs := flag.NewFlagSet("sample", flag.ExitOnError)
+	n := fs.Int("n", 5, "print `n` solutions, which may repeat if there are few")
+	seed := fs.Int64("seed", time.Now().UnixNano(), "random seed, for repeatable samples")
+	fs.Parse(args)
+	if fs.NArg() != 1 || *n < 1 {
+		return fmt.Errorf("Usage: sample [-n count] [-seed n] <puzzle file>")
+	}
+	board, err := readGame(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if len(Violations(board, false)) > 0 {
+		return fmt.Errorf("Puzzle has conflicting givens")
+	}
+	rng := rand.New(rand.NewSource(*seed))
+	for i := 0; i < *n; i++ {
+		s := board.SampleSolution(rng)
+		if s == nil {
+			return fmt.Errorf("Puzzle has no solution")
+		}
+		fmt.Println(s.Line())
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// selfTest is a puzzle with a known outcome, for the selftest command
+type selfTest struct {
+	name   string
+	puzzle string
+	// solutions is the number of solutions, counting up to 2
+	solutions int
+	// solution is only set for puzzles with one solution
+	solution string
+	// difficulty is the expected grade from the strategy engine, if any
+	difficulty Difficulty
+}
+
+// selfTests are the puzzles checked by selftest, covering each grade of the
+// strategy engine along with puzzles which are broken
+var selfTests = []selfTest{
+	{
+		name:       "easy",
+		puzzle:     "006007300018009050500000064920080000000763000000090075630000008090300520002400600",
+		solutions:  1,
+		solution:   "246157389318649257579832164927581436485763912163294875631925748894376521752418693",
+		difficulty: Easy,
+	},
+	{
+		name:       "long search",
+		puzzle:     "000000012000035000000600070700000300000400800100000000000120000080000040050000600",
+		solutions:  1,
+		solution:   "673894512912735486845612973798261354526473891134589267469128735287356149351947628",
+		difficulty: Easy,
+	},
+	{
+		name:       "coloring",
+		puzzle:     "300207000800056074020000500040060100000009040700000800002004708000001006006070005",
+		solutions:  1,
+		solution:   "354297681819356274627148593948763152265819347731425869592634718473581926186972435",
+		difficulty: Hard,
+	},
+	{
+		name:       "forcing chain",
+		puzzle:     "000700800006000031040002000024070000010030080000060290000800070860000500002006000",
+		solutions:  1,
+		solution:   "159743862276589431348612759624978315917235684583164297435821976861497523792356148",
+		difficulty: RequiresChains,
+	},
+	{
+		name:       "extreme",
+		puzzle:     "800000000003600000070090200050007000000045700000100030001000068008500010090000400",
+		solutions:  1,
+		solution:   "812753649943682175675491283154237896369845721287169534521974368438526917796318452",
+		difficulty: RequiresGuessing,
+	},
+	{
+		name:      "empty board",
+		puzzle:    strings.Repeat("0", DIM*DIM),
+		solutions: 2,
+	},
+	{
+		name:      "no candidates",
+		puzzle:    "123456780000000900" + strings.Repeat("0", DIM*DIM-2*DIM),
+		solutions: 0,
+	},
+}
+
+// runSelfTest checks solver and the strategy engine against t, returning a
+// description of each failure
+func runSelfTest(t selfTest, solver Solver) []string {
+	var failures []string
+	g, err := ParseLine(t.puzzle)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	if n := g.CountSolutions(2); n != t.solutions {
+		failures = append(failures, fmt.Sprintf("counted %v solutions, expected %v", n, t.solutions))
+	}
+	if t.solution != "" {
+		s := NewStrategist(g.Clone())
+		s.Solve()
+		if s.Difficulty() != t.difficulty {
+			failures = append(failures, fmt.Sprintf("graded %v, expected %v", s.Difficulty(), t.difficulty))
+		}
+	}
+	var stats Stats
+	solved := solver.Solve(g, &stats)
+	switch {
+	case solved != (t.solutions > 0):
+		failures = append(failures, fmt.Sprintf("solved? %v, expected %v", solved, t.solutions > 0))
+	case solved && len(invalidCells(*g)) > 0:
+		failures = append(failures, "invalid solution "+g.Line())
+	case t.solution != "" && g.Line() != t.solution:
+		failures = append(failures, "wrong solution "+g.Line())
+	}
+	return failures
+}
+
+// selfTestCommand handles: selftest [flags], checking the solvers against
+// puzzles with known solutions
+func selfTestCommand(args []string) error {
+	fs := flag.NewFlagSet("selftest", flag.ExitOnError)
+	algo := fs.String("algo", strings.Join(verifiers, ","), "comma separated solving algorithms to test: "+solverNames())
+	fs.Parse(args)
+	if fs.NArg() != 0 {
+		return fmt.Errorf("Usage: selftest [flags]")
+	}
+	passed, total := 0, 0
+	for _, name := range strings.Split(*algo, ",") {
+		solver, ok := solvers[name]
+		if !ok {
+			return fmt.Errorf("Unknown algorithm %q", name)
+		}
+		for _, t := range selfTests {
+			total++
+			failures := runSelfTest(t, solver)
+			if len(failures) == 0 {
+				passed++
+				fmt.Printf("PASS %v: %v\n", name, t.name)
+				continue
+			}
+			fmt.Printf("FAIL %v: %v: %v\n", name, t.name, strings.Join(failures, "; "))
+		}
+	}
+	fmt.Printf("\nPassed %v of %v\n", passed, total)
+	if passed != total {
+		return fmt.Errorf("Self test failed")
+	}
+	return nil
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// server answers HTTP requests for puzzles drawn from a pack
+type server struct {
+	pack *Pack
+	// dailyDifficulty is used by /daily when the request doesn't specify one
+	dailyDifficulty Difficulty
+	race            *race
+	coop            *coop
+	feed            *feed
+}
+
+// newServer returns the HTTP handler for serve mode, base is the address of
+// the web UI for links to puzzles
+func newServer(pack *Pack, dailyDifficulty Difficulty, base string) http.Handler {
+	s := &server{pack: pack, dailyDifficulty: dailyDifficulty, race: newRace(pack),
+		coop: newCoop(pack), feed: newFeed(base)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/daily", onlyMethod(http.MethodGet, s.handleDaily))
+	mux.HandleFunc("/puzzles/random", onlyMethod(http.MethodGet, s.handleRandom))
+	mux.HandleFunc("/grade-solution", onlyMethod(http.MethodPost, handleGradeSolution))
+	mux.HandleFunc("/progress", onlyMethod(http.MethodGet, handleProgress))
+	mux.HandleFunc("/why", onlyMethod(http.MethodGet, handleWhy))
+	mux.HandleFunc("/race", onlyMethod(http.MethodGet, s.race.handleRace))
+	mux.HandleFunc("/race/overlay", onlyMethod(http.MethodGet, s.race.handleOverlay))
+	mux.HandleFunc("/coop", onlyMethod(http.MethodGet, s.coop.handleCoop))
+	mux.HandleFunc("/feed.atom", onlyMethod(http.MethodGet, s.feed.handleFeed))
+	mux.HandleFunc("/graphql", s.handleGraphQL)
+	return mux
+}
+
+// PuzzleResponse is the JSON body returned for a puzzle, leaving out the
+// solution
+type PuzzleResponse struct {
+	ID         int    `json:"id"`
+	Puzzle     string `json:"puzzle"`
+	Difficulty string `json:"difficulty"`
+	Clues      int    `json:"clues"`
+	// Date is only set for the daily puzzle
+	Date     string    `json:"date,omitempty"`
+	Metadata *Metadata `json:"metadata,omitempty"`
+}
+
+func newPuzzleResponse(p PackPuzzle) PuzzleResponse {
+	return PuzzleResponse{ID: p.ID, Puzzle: p.Puzzle, Difficulty: p.Difficulty, Clues: p.Clues,
+		Metadata: p.Metadata}
+}
+
+// puzzlesOf returns the puzzles of the pack graded d
+func puzzlesOf(pack *Pack, d Difficulty) []PackPuzzle {
+	var result []PackPuzzle
+	for _, p := range pack.Puzzles {
+		if p.Difficulty == d.String() {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// dailyPuzzle returns the puzzle of the day graded d for a date as
+// YYYY-MM-DD, false if the pack has none of that difficulty
+func dailyPuzzle(pack *Pack, d Difficulty, date string) (PackPuzzle, bool) {
+	puzzles := puzzlesOf(pack, d)
+	if len(puzzles) == 0 {
+		return PackPuzzle{}, false
+	}
+	return puzzles[dailyIndex(date+"/"+d.String(), len(puzzles))], true
+}
+
+// requestDifficulty reads the difficulty query parameter, def if absent
+func requestDifficulty(r *http.Request, def Difficulty) (Difficulty, error) {
+	if v := r.URL.Query().Get("difficulty"); v != "" {
+		return ParseDifficulty(v)
+	}
+	return def, nil
+}
+
+// dailyIndex deterministically picks one of n puzzles for a date
+func dailyIndex(date string, n int) int {
+	h := fnv.New64a()
+	h.Write([]byte(date))
+	return int(h.Sum64() % uint64(n))
+}
+
+// renderPuzzle sets the puzzle of resp in the format query parameter, if
+// given, rather than as a line
+func renderPuzzle(r *http.Request, resp *PuzzleResponse) error {
+	v := r.URL.Query().Get("format")
+	if v == "" {
+		return nil
+	}
+	format, err := ParseFormat(v)
+	if err != nil {
+		return err
+	}
+	g, err := Parse(strings.NewReader(resp.Puzzle), LineFormat)
+	if err != nil {
+		return err
+	}
+	var sb strings.Builder
+	if err := Render(&sb, g, format); err != nil {
+		return err
+	}
+	resp.Puzzle = strings.TrimSuffix(sb.String(), "\n")
+	return nil
+}
+
+// handleDaily serves GET /daily, the same puzzle for everyone on a given UTC
+// date.  Optional query parameters: difficulty, date as YYYY-MM-DD, and the
+// format of the puzzle.
+func (s *server) handleDaily(w http.ResponseWriter, r *http.Request) {
+	d, err := requestDifficulty(r, s.dailyDifficulty)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	date := time.Now().UTC().Format(time.DateOnly)
+	if v := r.URL.Query().Get("date"); v != "" {
+		if _, err := time.Parse(time.DateOnly, v); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid date %q, expected YYYY-MM-DD", v), http.StatusBadRequest)
+			return
+		}
+		date = v
+	}
+	p, ok := dailyPuzzle(s.pack, d, date)
+	if !ok {
+		http.Error(w, fmt.Sprintf("No %v puzzles in pack", d), http.StatusNotFound)
+		return
+	}
+	resp := newPuzzleResponse(p)
+	resp.Date = date
+	if err := renderPuzzle(r, &resp); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, resp)
+}
+
+// handleRandom serves GET /puzzles/random, a puzzle chosen at random on each
+// request.  Optional query parameters: difficulty, clues as a range such as
+// 24..26, tag, and the format of the puzzle.
+func (s *server) handleRandom(w http.ResponseWriter, r *http.Request) {
+	var q PuzzleQuery
+	if v := r.URL.Query().Get("difficulty"); v != "" {
+		d, err := ParseDifficulty(v)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		q.Difficulty = d.String()
+	}
+	if v := r.URL.Query().Get("clues"); v != "" {
+		var err error
+		if q.MinClues, q.MaxClues, err = parseRange(v); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	q.Tag = r.URL.Query().Get("tag")
+	p, ok := s.pack.Random(q, nil)
+	if !ok {
+		http.Error(w, "No puzzles in pack match", http.StatusNotFound)
+		return
+	}
+	resp := newPuzzleResponse(p)
+	if err := renderPuzzle(r, &resp); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, resp)
+}
+
+// GradeRequest is the JSON body of POST /grade-solution, boards are in the 81
+// digit line format
+type GradeRequest struct {
+	Puzzle string `json:"puzzle"`
+	// Grid is the player's attempt, which may be incomplete
+	Grid string `json:"grid"`
+}
+
+// maxRequestBytes limits the size of request bodies
+const maxRequestBytes = 1 << 16
+
+// handleGradeSolution serves POST /grade-solution, checking a player's grid
+// against the puzzle and returning a SolutionGrade
+func handleGradeSolution(w http.ResponseWriter, r *http.Request) {
+	var req GradeRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes)).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	puzzle, err := ParseLine(req.Puzzle)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid puzzle: %v", err), http.StatusBadRequest)
+		return
+	}
+	grid, err := ParseLine(req.Grid)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid grid: %v", err), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, GradeSolution(puzzle, grid))
+}
+
+// handleProgress serves GET /progress, reporting how far the grid query
+// parameter has got with the puzzle parameter, both in the 81 digit line
+// format, as a Progress
+func handleProgress(w http.ResponseWriter, r *http.Request) {
+	puzzle, err := ParseLine(r.URL.Query().Get("puzzle"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid puzzle: %v", err), http.StatusBadRequest)
+		return
+	}
+	grid, err := ParseLine(r.URL.Query().Get("grid"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid grid: %v", err), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, PlayerProgress(puzzle, grid))
+}
+
+// handleWhy serves GET /why, explaining the candidates of the cell query
+// parameter, in r1c1 notation, of the puzzle parameter in the 81 digit line
+// format as the why command does
+func handleWhy(w http.ResponseWriter, r *http.Request) {
+	puzzle, err := ParseLine(r.URL.Query().Get("puzzle"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid puzzle: %v", err), http.StatusBadRequest)
+		return
+	}
+	c, err := ParseCell(r.URL.Query().Get("cell"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, newRPCWhyResult(Why(puzzle, c)))
+}
+
+// onlyMethod rejects requests to h which don't use method
+func onlyMethod(method string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			w.Header().Set("Allow", method)
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h(w, r)
+	}
+}
+
+// writeJSON encodes v as the response body
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("writing response", "err", err)
+	}
+}
+
+// serveCommand handles: serve [flags], running the HTTP API
+func serveCommand(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", ":8080", "listen on `address`")
+	packFile := fs.String("pack", "pack.json", "serve puzzles from the pack in `file`")
+	daily := fs.String("daily-difficulty", Medium.String(), "difficulty of the daily puzzle")
+	base := fs.String("url", "", "link feed entries to the web UI at `url` with their share code")
+	fs.Parse(args)
+	if fs.NArg() != 0 {
+		return fmt.Errorf("Usage: serve [flags]")
+	}
+	d, err := ParseDifficulty(*daily)
+	if err != nil {
+		return err
+	}
+	pack, err := readPack(*packFile)
+	if err != nil {
+		return err
+	}
+	slog.Info("serving", "puzzles", len(pack.Puzzles), "pack", *packFile, "addr", *addr)
+	return http.ListenAndServe(*addr, logRequests(newServer(pack, d, *base)))
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// shard is the part of a batch solved by one machine, given as i/n on the
+// command line: every nth puzzle of the input starting from the ith
+type shard struct {
+	index, count int
+}
+
+// parseShard reads a shard in i/n form, the empty string being the whole
+// batch
+func parseShard(s string) (shard, error) {
+	if s == "" {
+		return shard{1, 1}, nil
+	}
+	is, ns, ok := strings.Cut(s, "/")
+	i, ierr := strconv.Atoi(is)
+	n, nerr := strconv.Atoi(ns)
+	if !ok || ierr != nil || nerr != nil || n < 1 || i < 1 || i > n {
+		return shard{}, fmt.Errorf("Invalid shard %q, expected i/n with i from 1 to n, such as 2/4", s)
+	}
+	return shard{i, n}, nil
+}
+
+// has is true if the puzzle numbered i, from 0 in the order of the input,
+// belongs to the shard
+func (s shard) has(i int) bool {
+	return i%s.count == s.index-1
+}
+
+// mergeReportsCommand handles: merge-reports [flags] <report>..., combining
+// the reports of the shards of a batch into one with its summary
+func mergeReportsCommand(args []string) error {
+	fs := flag.NewFlagSet("merge-reports", flag.ExitOnError)
+	output := fs.String("o", "", "write the merged JSON Lines report to `file`")
+	techniqueCSV := fs.String("technique-csv", "", "write the breakdown of the techniques used as CSV to `file`")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		return fmt.Errorf("Usage: merge-reports [flags] <report>...")
+	}
+	var results []Result
+	// from records the report each line came from, to catch overlapping
+	// shards
+	from := make(map[int]string)
+	for _, name := range fs.Args() {
+		start := len(results)
+		file, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		err = readResults(bufio.NewReader(file), func(r Result) {
+			results = append(results, r)
+		})
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("%v: %v", name, err)
+		}
+		for _, r := range results[start:] {
+			if prev, ok := from[r.Line]; ok {
+				return fmt.Errorf("Line %v is in both %v and %v, are they shards of the same batch?", r.Line, prev, name)
+			}
+			from[r.Line] = name
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Line < results[j].Line
+	})
+
+	sum := newSummary()
+	for _, r := range results {
+		sum.add(r)
+	}
+	if *output != "" {
+		out, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+		w := bufio.NewWriter(out)
+		enc := json.NewEncoder(w)
+		for _, r := range results {
+			if err := enc.Encode(r); err != nil {
+				return err
+			}
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	if *techniqueCSV != "" {
+		out, err := os.Create(*techniqueCSV)
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+		if err := sum.writeTechniqueCSV(out); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Merged %v reports\n\n%v\n", fs.NArg(), sum)
+	return nil
+}
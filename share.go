@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// shareVersion is the first byte of every share code, so the format can change
+// without breaking old links
+const shareVersion = 1
+
+// EncodeShare packs the filled cells of the board into a short string safe to
+// embed in a URL fragment.  Each clue is a varint of gap*9 + value-1, where
+// gap counts the empty cells since the previous clue, and the bytes are then
+// base64 encoded.  A typical puzzle needs a single byte per clue.
+func EncodeShare(g *Game) string {
+	buf := []byte{shareVersion}
+	gap := 0
+	for _, row := range g.board {
+		for _, val := range row {
+			if val == 0 {
+				gap++
+				continue
+			}
+			buf = binary.AppendUvarint(buf, uint64(gap*DIM+val-1))
+			gap = 0
+		}
+	}
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+// DecodeShare reads a board produced by EncodeShare, marking the clues as
+// givens
+func DecodeShare(code string) (*Game, error) {
+	buf, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(code, "#"))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid share code: %v", err)
+	}
+	if len(buf) == 0 || buf[0] != shareVersion {
+		return nil, fmt.Errorf("Invalid share code: unsupported version")
+	}
+	buf = buf[1:]
+	g := NewGame()
+	cell := -1
+	for len(buf) > 0 {
+		v, n := binary.Uvarint(buf)
+		if n <= 0 {
+			return nil, fmt.Errorf("Invalid share code: truncated clue")
+		}
+		buf = buf[n:]
+		cell += int(v/DIM) + 1
+		if cell >= DIM*DIM {
+			return nil, fmt.Errorf("Invalid share code: clue beyond the last cell")
+		}
+		g.MakeMove(cell/DIM, cell%DIM, int(v%DIM)+1)
+	}
+	g.MarkGivens()
+	return g, nil
+}
+
+// shareCommand handles: share [flags] <puzzle file>, printing a link to the
+// puzzle, or with -decode the puzzle for a share code
+func shareCommand(args []string) error {
+	fs := flag.NewFlagSet("share", flag.ExitOnError)
+	base := fs.String("url", "", "prefix the share code with `url` to print a link")
+	decode := fs.Bool("decode", false, "print the puzzle for a share code instead")
+	output := fs.String("o", "link", "output format: link, qr for a terminal QR code, or png")
+	pngFile := fs.String("png", "puzzle.png", "write the png QR code to `file`")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("Usage: share [flags] <puzzle file>, or share -decode <code>")
+	}
+	if *decode {
+		code := fs.Arg(0)
+		if i := strings.LastIndex(code, "#"); i >= 0 {
+			code = code[i+1:]
+		}
+		g, err := DecodeShare(code)
+		if err != nil {
+			return err
+		}
+		fmt.Print(g.GridString())
+		return nil
+	}
+	board, err := readGame(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	link := fmt.Sprintf("%v#%v", *base, EncodeShare(board))
+	switch *output {
+	case "link":
+		fmt.Println(link)
+		return nil
+	case "qr", "png":
+	default:
+		return fmt.Errorf("Unknown output format %q", *output)
+	}
+	q, err := qrEncode([]byte(link))
+	if err != nil {
+		return err
+	}
+	if *output == "qr" {
+		fmt.Print(q)
+		fmt.Println(link)
+		return nil
+	}
+	out, err := os.Create(*pngFile)
+	if err != nil {
+		return err
+	}
+	if err := q.WritePNG(out, 8); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
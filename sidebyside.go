@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// sideBySideGap separates the two grids of -o side-by-side
+const sideBySideGap = "    "
+
+// sideBySide joins the lines of left and right into columns, padding left to
+// its widest line.  Only right may hold terminal escapes, which would throw
+// off the padding.
+func sideBySide(left, right string) string {
+	l := strings.Split(strings.TrimSuffix(left, "\n"), "\n")
+	r := strings.Split(strings.TrimSuffix(right, "\n"), "\n")
+	width := 0
+	for _, line := range l {
+		width = max(width, utf8.RuneCountInString(line))
+	}
+	var sb strings.Builder
+	for i := 0; i < max(len(l), len(r)); i++ {
+		var a, b string
+		if i < len(l) {
+			a = l[i]
+		}
+		if i < len(r) {
+			b = r[i]
+		}
+		sb.WriteString(a)
+		if b != "" {
+			sb.WriteString(strings.Repeat(" ", width-utf8.RuneCountInString(a)) + sideBySideGap + b)
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// SideBySideString formats the starting board and the solved board next to
+// each other, with the cells filled in by the solver highlighted if diff is
+// true, or alone if mask is true
+func SideBySideString(start, solved *Game, diff, mask bool) string {
+	var left, right strings.Builder
+	left.WriteString("Starting configuration:\n")
+	start.writeGrid(&left, nil)
+	switch {
+	case mask:
+		right.WriteString("Answer key:\n")
+		right.WriteString(solved.DiffString(true))
+	case diff:
+		right.WriteString("Ending configuration:\n")
+		right.WriteString(solved.DiffString(false))
+	default:
+		right.WriteString("Ending configuration:\n")
+		solved.writeGrid(&right, nil)
+	}
+	return strings.TrimSuffix(sideBySide(left.String(), right.String()), "\n")
+}
+
+// writeSideBySideHTML writes an HTML page showing the puzzle and its solution
+// next to each other, for printing the problem and answer together.  solved
+// may be an AnswerKey, labeled by label.
+func writeSideBySideHTML(w io.Writer, p *Puzzle, solved *Game, label string) {
+	title := "Sudoku"
+	if p.Source != "" {
+		title = p.Source
+	}
+	fmt.Fprintf(w, "<!DOCTYPE html>\n<html>\n<head>\n<title>%v</title>\n", html.EscapeString(title))
+	fmt.Fprintf(w, "<style>\nbody { display: flex; flex-wrap: wrap; gap: 2em; background: %v; }\n</style>\n</head>\n<body>\n",
+		htmlColor(activeTheme.Background))
+	writeBoardSVG(w, p.Game, p.Game, "Puzzle", activeBoardStyle())
+	writeBoardSVG(w, p.Game, solved, label, activeBoardStyle())
+	fmt.Fprint(w, "</body>\n</html>\n")
+}
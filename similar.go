@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// sharedGivens returns the most givens a and b can have in common, the same
+// digit in the same cell, over every transformation of b used by Canonical
+// together with any relabeling of its digits.  The relabeling is only
+// searched when the bound from matching each digit to its most common
+// partner could beat the best so far.
+func sharedGivens(a, b *Game) int {
+	type given struct{ row, col, val int }
+	var givens []given
+	for ri, row := range a.board {
+		for ci, val := range row {
+			if val != 0 {
+				givens = append(givens, given{ri, ci, val})
+			}
+		}
+	}
+	most := min(len(givens), DIM*DIM-b.remaining)
+	best := 0
+	orders := lineOrders()
+	for _, transpose := range []bool{false, true} {
+		for _, rows := range orders {
+			for _, cols := range orders {
+				var counts [DIM + 1][DIM + 1]int
+				for _, x := range givens {
+					r, c := rows[x.row], cols[x.col]
+					if transpose {
+						r, c = c, r
+					}
+					if y := b.board[r][c]; y != 0 {
+						counts[x.val][y]++
+					}
+				}
+				// Each digit of a matching its most common partner bounds
+				// the match of any relabeling
+				bound := 0
+				for x := 1; x <= DIM; x++ {
+					m := 0
+					for _, n := range counts[x][1:] {
+						m = max(m, n)
+					}
+					bound += m
+				}
+				if bound > best {
+					best = max(best, bestRelabel(&counts))
+					if best == most {
+						return best
+					}
+				}
+			}
+		}
+	}
+	return best
+}
+
+// bestRelabel returns the most cells which a one to one relabeling of the
+// digits can match, where counts[x][y] cells hold x on one board and y on the
+// other
+func bestRelabel(counts *[DIM + 1][DIM + 1]int) int {
+	// most[used] is the best match of the first popcount(used) digits to the
+	// set of digits used
+	var most [1 << DIM]int
+	for used := 1; used < len(most); used++ {
+		x := bits.OnesCount(uint(used))
+		for y := 0; y < DIM; y++ {
+			if bit := 1 << uint(y); used&bit != 0 {
+				most[used] = max(most[used], most[used&^bit]+counts[x][y+1])
+			}
+		}
+	}
+	return most[len(most)-1]
+}
+
+// similarCommand handles: similar <puzzle> <puzzle>, measuring how far apart
+// two puzzles are to find near duplicates which Canonical misses.  The
+// distance counts the givens which don't match up once the second puzzle is
+// lined up with the first as closely as it can be, so puzzles which are the
+// same in disguise are at distance 0, and one with an extra clue at 1.
+func similarCommand(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("Usage: similar <puzzle> <puzzle>")
+	}
+	a, err := readGame(args[0])
+	if err != nil {
+		return err
+	}
+	b, err := readGame(args[1])
+	if err != nil {
+		return err
+	}
+	shared := sharedGivens(a, b)
+	clues := [2]int{DIM*DIM - a.remaining, DIM*DIM - b.remaining}
+	fmt.Printf("Shared givens: %v of %v and %v\n", shared, clues[0], clues[1])
+	fmt.Printf("Distance: %v\n", clues[0]+clues[1]-2*shared)
+	return nil
+}
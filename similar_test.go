@@ -0,0 +1,25 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// A disguised copy with one given removed shares every other given
+func TestSharedGivensTransformed(t *testing.T) {
+	g, err := readGame("hard.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := transformed(g, rand.New(rand.NewSource(2)))
+	for i := 0; i < DIM*DIM; i++ {
+		if h.board[i/DIM][i%DIM] != 0 {
+			h.UnmakeMove(i/DIM, i%DIM)
+			break
+		}
+	}
+	clues := DIM*DIM - g.remaining
+	if got := sharedGivens(g, h); got != clues-1 {
+		t.Errorf("sharedGivens = %v, want %v", got, clues-1)
+	}
+}
@@ -2,14 +2,28 @@ package main
 
 import (
 	"fmt"
+	"io"
+	"math/bits"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode/utf8"
 )
 
 // Game represents a sudoku board
 type Game struct {
 	// board represents the game board, access as board[row][col]
-	board      [][]int
+	board [][]int
+	// given marks the cells filled in by the puzzle, access as given[row][col]
+	given      [][]bool
 	remaining  int
 	backtracks int
+	// trace records the moves made if not nil, see StartTrace
+	trace *Trace
+	// symbols prints the values with an alphabet other than digits if not
+	// nil, see Symbols
+	symbols *Symbols
 }
 
 // NewGame creates an empty sudoku board
@@ -17,21 +31,148 @@ func NewGame() *Game {
 	g := &Game{}
 	// Build empty (zero) board matrix
 	g.board = make([][]int, DIM)
+	g.given = make([][]bool, DIM)
 	for i := range g.board {
 		g.board[i] = make([]int, DIM)
+		g.given[i] = make([]bool, DIM)
 	}
 	g.remaining = DIM * DIM
 	return g
 }
 
+// Clone returns a deep copy of the board, including its givens and
+// statistics
+func (g *Game) Clone() *Game {
+	c := NewGame()
+	for ri := range g.board {
+		copy(c.board[ri], g.board[ri])
+		copy(c.given[ri], g.given[ri])
+	}
+	c.remaining = g.remaining
+	c.backtracks = g.backtracks
+	c.symbols = g.symbols
+	return c
+}
+
+// Equal is true if both boards hold the same values and givens, regardless of
+// the work done to reach them
+func (g *Game) Equal(other *Game) bool {
+	for ri := range g.board {
+		for ci := range g.board[ri] {
+			if g.board[ri][ci] != other.board[ri][ci] || g.given[ri][ci] != other.given[ri][ci] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Hash returns the 64-bit FNV-1a hash of the values of the board in reading
+// order, the same on every run and platform, for caches, transposition tables
+// and dedupe.  Givens and statistics are ignored, so Equal boards always hash
+// the same.  As 81 cells hold far more than 64 bits, different boards do
+// collide: any pair with a chance of about 1 in 2^64, so a collection of n
+// boards holds a collision with a chance of about n^2 / 2^65, around 1 in 37
+// million for a million boards.  Compare the boards themselves where that
+// matters.
+func (g *Game) Hash() uint64 {
+	const (
+		offset = 14695981039346656037
+		prime  = 1099511628211
+	)
+	h := uint64(offset)
+	for _, row := range g.board {
+		for _, val := range row {
+			h ^= uint64(val)
+			h *= prime
+		}
+	}
+	return h
+}
+
+// cellWidth is the number of characters needed for the largest value
+var cellWidth = len(strconv.Itoa(DIM))
+
+// writeGrid writes column and row numbers around the values of the board,
+// written with its symbols and padded to cellWidth.  cell formats the padded
+// value of each cell, and may wrap it in terminal escapes.
+func (g *Game) writeGrid(sb *strings.Builder, cell func(row, col int, s string) string) {
+	padded := func(s string) string {
+		return strings.Repeat(" ", max(cellWidth-utf8.RuneCountInString(s), 0)) + s
+	}
+	pad := func(s string) {
+		sb.WriteString(padded(s))
+	}
+	sb.WriteString(strings.Repeat(" ", cellWidth+2))
+	for i := 1; i <= DIM; i++ {
+		sb.WriteByte(' ')
+		pad(strconv.Itoa(i))
+	}
+	sb.WriteByte('\n')
+	for ri, row := range g.board {
+		pad(strconv.Itoa(ri + 1))
+		sb.WriteString(": [")
+		for ci, val := range row {
+			if ci > 0 {
+				sb.WriteByte(' ')
+			}
+			s := padded(g.symbols.format(val))
+			if cell != nil {
+				s = cell(ri, ci, s)
+			}
+			sb.WriteString(s)
+		}
+		sb.WriteString("]\n")
+	}
+}
+
+// WriteTo writes the board for human consumption to w, as String does
+func (g *Game) WriteTo(w io.Writer) (int64, error) {
+	n, err := io.WriteString(w, g.String())
+	return int64(n), err
+}
+
 // String formats the board for human consumption
 func (g *Game) String() string {
-	var result = "    1 2 3 4 5 6 7 8 9\n"
-	for i, row := range g.board {
-		result += fmt.Sprintf("%v: %v\n", i+1, row)
+	var sb strings.Builder
+	sb.Grow((DIM + 1) * (DIM*(cellWidth+1) + cellWidth + 4))
+	g.writeGrid(&sb, nil)
+	fmt.Fprintf(&sb, "Remaining: %v, Backtracks: %v", g.remaining, g.backtracks)
+	return sb.String()
+}
+
+// DiffString formats the board highlighting the cells filled in by the solver
+// rather than the puzzle, in the colors of the -theme.  If addedOnly is true
+// the givens are blanked out instead, leaving just the added digits.
+func (g *Game) DiffString(addedOnly bool) string {
+	var sb strings.Builder
+	g.writeGrid(&sb, func(row, col int, s string) string {
+		switch {
+		case g.given[row][col] && addedOnly:
+			return strings.Repeat(" ", cellWidth-1) + "."
+		case g.board[row][col] == 0 || addedOnly:
+			return s
+		case g.given[row][col]:
+			return sgr(activeTheme.TerminalGiven, s)
+		}
+		return sgr(activeTheme.TerminalFilled, s)
+	})
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// AnswerKey returns a copy of the board with the givens blanked, leaving only
+// the cells filled in by the solver
+func (g *Game) AnswerKey() *Game {
+	key := g.Clone()
+	for ri, cols := range g.given {
+		for ci, given := range cols {
+			if given {
+				key.UnmakeMove(ri, ci)
+			}
+		}
 	}
-	result += fmt.Sprintf("Remaining: %v, Backtracks: %v", g.remaining, g.backtracks)
-	return result
+	key.backtracks = g.backtracks
+	return key
 }
 
 // ValidSolution is true if remaining == 0
@@ -39,7 +180,9 @@ func (g *Game) ValidSolution() bool {
 	return g.remaining == 0
 }
 
-// MakeMove adds a number to the board, row and col indices are 0 based
+// MakeMove adds a number to the board, row and col indices are 0 based.  Like
+// the other move methods it panics on out of range indices, which are
+// programmer errors.
 func (g *Game) MakeMove(row, col, val int) {
 	if g.board[row][col] == 0 && val != 0 {
 		g.remaining--
@@ -47,6 +190,16 @@ func (g *Game) MakeMove(row, col, val int) {
 	g.board[row][col] = val
 }
 
+// MarkGivens records every filled cell as part of the puzzle, rather than a
+// move made while solving it
+func (g *Game) MarkGivens() {
+	for ri, cols := range g.board {
+		for ci, val := range cols {
+			g.given[ri][ci] = val != 0
+		}
+	}
+}
+
 // UnmakeMove removes a number from the board, row and col indices are 0 based
 func (g *Game) UnmakeMove(row, col int) {
 	if g.board[row][col] != 0 {
@@ -56,88 +209,234 @@ func (g *Game) UnmakeMove(row, col int) {
 	g.backtracks++
 }
 
-// NextEmptyCell tells our solver which cell to work on next
+// Checkpoint is a saved copy of the cells of a board, see Snapshot
+type Checkpoint struct {
+	cells     [DIM * DIM]uint8
+	remaining int
+}
+
+// Snapshot saves the cells of the board, so that a group of moves can be made
+// speculatively and then rolled back together with Restore
+func (g *Game) Snapshot() Checkpoint {
+	var cp Checkpoint
+	for ri, cols := range g.board {
+		for ci, val := range cols {
+			cp.cells[ri*DIM+ci] = uint8(val)
+		}
+	}
+	cp.remaining = g.remaining
+	return cp
+}
+
+// Restore returns the cells of the board to a Checkpoint taken by Snapshot.
+// Givens and the backtrack count are left alone.
+func (g *Game) Restore(cp Checkpoint) {
+	for ri, cols := range g.board {
+		for ci := range cols {
+			cols[ci] = int(cp.cells[ri*DIM+ci])
+		}
+	}
+	g.remaining = cp.remaining
+}
+
+// NextEmptyCell tells our solver which cell to work on next, the first empty
+// cell with the fewest candidates
 func (g *Game) NextEmptyCell() (row, col int) {
 	min := DIM + 1
 	for ri, cols := range g.board {
 		for ci, val := range cols {
 			if val == 0 {
-				cur := 0
-				candidates := g.CellCandidates(ri, ci)
-				// Count candidates
-				for i := 1; i <= DIM; i++ {
-					if candidates[i] {
-						cur++
-					}
-				}
+				cur := bits.OnesCount16(g.candidateMask(ri, ci))
 				if cur < min {
 					row, col = ri, ci
 					min = cur
+					if min == 0 {
+						// Dead end, no need to look further
+						return
+					}
 				}
 			}
 		}
 	}
-	// Return row, col
 	return
 }
 
-// CellCandidates returns a list of legal moves for specified cell
+// CellCandidates returns a list of legal moves for specified cell.  It panics
+// if row or col is out of range, use CandidatesChecked for unvalidated input.
 func (g *Game) CellCandidates(row, col int) []bool {
-	if row < 0 || DIM < row {
-		panic(fmt.Sprintf("Invalid row passed: %v", row))
-	}
-	if col < 0 || DIM < col {
-		panic(fmt.Sprintf("Invalid col passed: %v", col))
+	if err := checkCell(row, col); err != nil {
+		panic(err.Error())
 	}
 	// Will we use a 1-based slice for readability, 0 will always be false
 	candidates := make([]bool, DIM+1)
-	// Set everything to valid (except 0)
+	mask := g.candidateMask(row, col)
 	for i := 1; i <= DIM; i++ {
-		candidates[i] = true
-	}
-	// Check row
-	for i := 0; i < DIM; i++ {
-		candidates[g.board[row][i]] = false
-	}
-	// Check column
-	for i := 0; i < DIM; i++ {
-		candidates[g.board[i][col]] = false
-	}
-	// Check section
-	rowStart := row / 3 * 3
-	rowEnd := rowStart + DIM/3
-	colStart := col / 3 * 3
-	colEnd := colStart + DIM/3
-	for ri := rowStart; ri < rowEnd; ri++ {
-		for ci := colStart; ci < colEnd; ci++ {
-			candidates[g.board[ri][ci]] = false
-		}
+		candidates[i] = mask&(1<<uint(i)) != 0
 	}
 	return candidates
 }
 
+// candidateMask returns the legal moves for the specified cell as a bit set,
+// bit val is set when val is legal.  Used by the solver hot path, so it
+// doesn't allocate or check its arguments.
+func (g *Game) candidateMask(row, col int) uint16 {
+	var used uint16
+	for _, p := range peers[row][col] {
+		used |= 1 << uint(g.board[p.Row][p.Col])
+	}
+	// Everything is valid except 0 and the values seen
+	return (1<<(DIM+1) - 2) &^ used
+}
+
+// peers lists the 20 cells sharing a row, column or box with each cell,
+// access as peers[row][col]
+var peers = buildPeers()
+
+func buildPeers() (result [DIM][DIM][]Cell) {
+	for ri := 0; ri < DIM; ri++ {
+		for ci := 0; ci < DIM; ci++ {
+			c := Cell{ri, ci}
+			for pi := 0; pi < DIM; pi++ {
+				for pj := 0; pj < DIM; pj++ {
+					if p := (Cell{pi, pj}); c.sees(p) {
+						result[ri][ci] = append(result[ri][ci], p)
+					}
+				}
+			}
+		}
+	}
+	return
+}
+
+// CandidatesChecked returns the legal moves for the specified cell as a bit
+// set, bit val is set when val is legal.  Unlike CellCandidates it returns an
+// error for out of range indices.
+func (g *Game) CandidatesChecked(row, col int) (uint16, error) {
+	if err := checkCell(row, col); err != nil {
+		return 0, err
+	}
+	return g.candidateMask(row, col), nil
+}
+
+// checkCell returns an error if row or col, 0 based, is off the board
+func checkCell(row, col int) error {
+	if row < 0 || DIM <= row {
+		return fmt.Errorf("Invalid row passed: %v", row)
+	}
+	if col < 0 || DIM <= col {
+		return fmt.Errorf("Invalid col passed: %v", col)
+	}
+	return nil
+}
+
+// Solver is an algorithm which fills in the empty cells of a board
+type Solver interface {
+	// Solve completes g in place, recording the work done in stats, true if
+	// a solution was found
+	Solve(g *Game, stats *Stats) bool
+}
+
+// solvers maps the names accepted by -algo to solvers
+var solvers = map[string]Solver{
+	"backtrack": Backtracker{},
+	"anneal":    &Annealer{Temperature: 0.5, Cooling: 0.99999, Iterations: 500000, Restarts: 20},
+	"propagate": Propagator{},
+}
+
+// Backtracker is the recursive backtracking Solver
+type Backtracker struct{}
+
+// Solve completes g with recursiveSolver
+func (Backtracker) Solve(g *Game, stats *Stats) bool {
+	start := g.backtracks
+	solved := recursiveSolver(g, stats)
+	stats.Backtracks += g.backtracks - start
+	return solved
+}
+
 // recursiveSolver tries to solve the board using a recursive backtracking
-// algorithm
-func recursiveSolver(g *Game) (solved bool) {
+// algorithm, giving up if stats has a deadline which expires.  stats may be
+// nil.
+func recursiveSolver(g *Game, stats *Stats) (solved bool) {
 	if g.ValidSolution() {
 		return true
 	}
+	if stats.expired() {
+		return false
+	}
 
 	row, col := g.NextEmptyCell()
-	candidates := g.CellCandidates(row, col)
-
-	// Try each candidate
-	for val, avail := range candidates {
-		if avail {
-			g.MakeMove(row, col, val)
-			solved = recursiveSolver(g)
-			if solved {
-				break
+
+	// Try each candidate, lowest first
+	candidates := g.candidateMask(row, col)
+	for mask := candidates; mask != 0; mask &= mask - 1 {
+		val := bits.TrailingZeros16(mask)
+		g.MakeMove(row, col, val)
+		if g.trace != nil {
+			reason := "only candidate"
+			if n := bits.OnesCount16(candidates); n > 1 {
+				reason = fmt.Sprintf("guess, %v candidates", n)
 			}
-			// Move was incorrect
-			g.UnmakeMove(row, col)
+			g.record(Decision{Cell: Cell{row, col}.String(), Value: val, Reason: reason})
+		}
+		solved = recursiveSolver(g, stats)
+		if solved {
+			break
+		}
+		// Move was incorrect
+		g.UnmakeMove(row, col)
+		if g.trace != nil {
+			g.record(Decision{Cell: Cell{row, col}.String(), Value: val, Reason: "dead end", Backtrack: true})
 		}
 	}
 
 	return solved
 }
+
+// CountSolutions counts the solutions of the board by backtracking, stopping
+// once limit have been found.  Each candidate of the first cell is searched
+// by its own goroutine, all stopping as soon as the limit is reached.  The
+// board is left unchanged.
+func (g *Game) CountSolutions(limit int) int {
+	if limit <= 0 {
+		return 0
+	}
+	if g.ValidSolution() {
+		return 1
+	}
+	var found atomic.Int64
+	var wg sync.WaitGroup
+	row, col := g.NextEmptyCell()
+	for mask := g.candidateMask(row, col); mask != 0; mask &= mask - 1 {
+		branch := g.Clone()
+		branch.MakeMove(row, col, bits.TrailingZeros16(mask))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			countSolutions(branch, int64(limit), &found)
+		}()
+	}
+	wg.Wait()
+	return int(min(found.Load(), int64(limit)))
+}
+
+// countSolutions adds the solutions of the board to found, until it reaches
+// limit
+func countSolutions(g *Game, limit int64, found *atomic.Int64) {
+	if found.Load() >= limit {
+		return
+	}
+	if g.ValidSolution() {
+		found.Add(1)
+		return
+	}
+	row, col := g.NextEmptyCell()
+	for mask := g.candidateMask(row, col); mask != 0; mask &= mask - 1 {
+		g.MakeMove(row, col, bits.TrailingZeros16(mask))
+		countSolutions(g, limit, found)
+		g.UnmakeMove(row, col)
+		if found.Load() >= limit {
+			break
+		}
+	}
+}
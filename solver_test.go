@@ -0,0 +1,127 @@
+package main
+
+import "testing"
+
+func BenchmarkSolve(b *testing.B) {
+	g, err := readGame("hard.txt")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		// Only the solver is measured, not copying the board for it
+		b.StopTimer()
+		c := g.Clone()
+		b.StartTimer()
+		if !recursiveSolver(c, nil) {
+			b.Fatal("hard.txt not solved")
+		}
+	}
+}
+
+// The solver calls candidateMask and NextEmptyCell for every move, so they
+// must not allocate
+func TestCandidatesAllocationFree(t *testing.T) {
+	g, err := readGame("hard.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	allocs := testing.AllocsPerRun(100, func() {
+		for ri := 0; ri < DIM; ri++ {
+			for ci := 0; ci < DIM; ci++ {
+				g.candidateMask(ri, ci)
+			}
+		}
+		g.NextEmptyCell()
+	})
+	if allocs != 0 {
+		t.Errorf("candidateMask and NextEmptyCell made %v allocations, want 0", allocs)
+	}
+}
+
+func TestHashIgnoresGivensAndStats(t *testing.T) {
+	g, err := readGame("hard.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := g.Clone()
+	if !c.Equal(g) || c.Hash() != g.Hash() {
+		t.Fatalf("Clone hashes %x, want %x", c.Hash(), g.Hash())
+	}
+	// The same values without givens
+	plain := NewGame()
+	for ri, row := range g.board {
+		for ci, val := range row {
+			if val != 0 {
+				plain.MakeMove(ri, ci, val)
+			}
+		}
+	}
+	if plain.Hash() != g.Hash() {
+		t.Errorf("Board without givens hashes %x, want %x", plain.Hash(), g.Hash())
+	}
+	// Backtracking back to the same values
+	row, col := c.NextEmptyCell()
+	for val, ok := range c.CellCandidates(row, col) {
+		if ok {
+			c.MakeMove(row, col, val)
+			c.UnmakeMove(row, col)
+		}
+	}
+	if c.backtracks == g.backtracks || c.Hash() != g.Hash() {
+		t.Errorf("Board after backtracking hashes %x, want %x", c.Hash(), g.Hash())
+	}
+}
+
+// Hash must be the same on every run, as hashes are stored by caches.  The
+// values were computed independently of the code under test.
+func TestHashStable(t *testing.T) {
+	g, err := readGame("hard.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name string
+		g    *Game
+		want uint64
+	}{
+		{"empty", NewGame(), 0x0edbe9edbe9a769f},
+		{"hard.txt", g, 0xa0d3ba07e2eda04f},
+	} {
+		if got := tt.g.Hash(); got != tt.want {
+			t.Errorf("%v hashes %#x, want %#x", tt.name, got, tt.want)
+		}
+	}
+}
+
+// A million boards collide with a chance of about 1 in 37 million, so any
+// collision among distinct boards points to a weak hash
+func TestHashCollisions(t *testing.T) {
+	solutions, _ := NewGame().EnumerateSolutions(100000, 0)
+	seen := make(map[uint64]string)
+	add := func(g *Game) {
+		line := g.Line()
+		if prev, ok := seen[g.Hash()]; ok && prev != line {
+			t.Fatalf("%v and %v both hash %#x", prev, line, g.Hash())
+		}
+		seen[g.Hash()] = line
+	}
+	for i, s := range solutions {
+		g, err := ParseLine(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		add(g)
+		// Boards differing from a solution in a single cell
+		if i < 1000 {
+			for c := 0; c < DIM*DIM; c++ {
+				g.UnmakeMove(c/DIM, c%DIM)
+				add(g)
+				g.MakeMove(c/DIM, c%DIM, int(s[c]-'0'))
+			}
+		}
+	}
+	if want := len(solutions) + 1000*DIM*DIM; len(seen) != want {
+		t.Errorf("Hashed %v distinct boards, want %v", len(seen), want)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// numberWords spells out the values for screen readers, with blank for an
+// empty cell
+var numberWords = [DIM + 1]string{
+	"blank", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine",
+}
+
+// spokenValue spells out val, or gives its symbol if the board has them
+func (g *Game) spokenValue(val int) string {
+	if g.symbols == nil || val == 0 {
+		return numberWords[val]
+	}
+	return g.symbols.format(val)
+}
+
+// SpokenString describes the board row by row in words, as in "Row one: five,
+// blank, blank, seven, ..." for screen readers
+func (g *Game) SpokenString() string {
+	var sb strings.Builder
+	for ri, row := range g.board {
+		words := make([]string, len(row))
+		for ci, val := range row {
+			words[ci] = g.spokenValue(val)
+		}
+		fmt.Fprintf(&sb, "Row %v: %v.\n", numberWords[ri+1], strings.Join(words, ", "))
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// SpokenPlacements describes the cells filled in by the solver rather than
+// the puzzle, one placement per line in reading order, as in "Row one, column
+// two: six."
+func (g *Game) SpokenPlacements() string {
+	var lines []string
+	for ri, row := range g.board {
+		for ci, val := range row {
+			if val != 0 && !g.given[ri][ci] {
+				lines = append(lines, fmt.Sprintf("Row %v, column %v: %v.",
+					numberWords[ri+1], numberWords[ci+1], g.spokenValue(val)))
+			}
+		}
+	}
+	if len(lines) == 0 {
+		return "No placements."
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// playStats summarizes the completions of one difficulty
+type playStats struct {
+	solved  int
+	best    float64
+	seconds float64
+	hints   int
+	// days holds the local dates with a completion, as YYYY-MM-DD
+	days map[string]bool
+}
+
+func (s *playStats) add(r PlayRecord) {
+	if s.solved == 0 || r.Seconds < s.best {
+		s.best = r.Seconds
+	}
+	s.solved++
+	s.seconds += r.Seconds
+	s.hints += r.Hints
+	if s.days == nil {
+		s.days = make(map[string]bool)
+	}
+	s.days[r.Completed.Local().Format(time.DateOnly)] = true
+}
+
+// streaks returns the number of days in a row ending today, or yesterday if
+// nothing has been solved yet today, with a completion, and the longest run
+// of such days
+func (s *playStats) streaks(today time.Time) (current, longest int) {
+	var days []string
+	for day := range s.days {
+		days = append(days, day)
+	}
+	sort.Strings(days)
+	run := 0
+	var prev time.Time
+	for _, day := range days {
+		t, _ := time.ParseInLocation(time.DateOnly, day, time.Local)
+		if run > 0 && t.Equal(prev.AddDate(0, 0, 1)) {
+			run++
+		} else {
+			run = 1
+		}
+		longest = max(longest, run)
+		prev = t
+	}
+	day := today
+	if !s.days[day.Format(time.DateOnly)] {
+		day = day.AddDate(0, 0, -1)
+	}
+	for s.days[day.Format(time.DateOnly)] {
+		current++
+		day = day.AddDate(0, 0, -1)
+	}
+	return current, longest
+}
+
+// formatSeconds rounds a duration in seconds for the stats table
+func formatSeconds(seconds float64) string {
+	return time.Duration(seconds * float64(time.Second)).Round(time.Second).String()
+}
+
+// statsCommand handles: stats [flags], showing personal bests, averages and
+// streaks for each difficulty from the history kept by play
+func statsCommand(args []string) error {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	saveFile := fs.String("save", "progress.json", "read progress from `file`, as kept by play")
+	fs.Parse(args)
+	if fs.NArg() != 0 {
+		return fmt.Errorf("Usage: stats [flags]")
+	}
+	c, err := readCampaign(*saveFile)
+	if err != nil {
+		return err
+	}
+	if len(c.History) == 0 {
+		fmt.Println("No puzzles solved yet, see play")
+		return nil
+	}
+
+	byDifficulty := make(map[string]*playStats)
+	byMonth := make(map[string]map[string]*playStats)
+	for _, r := range c.History {
+		if byDifficulty[r.Difficulty] == nil {
+			byDifficulty[r.Difficulty] = &playStats{}
+		}
+		byDifficulty[r.Difficulty].add(r)
+		month := r.Completed.Local().Format("2006-01")
+		if byMonth[month] == nil {
+			byMonth[month] = make(map[string]*playStats)
+		}
+		if byMonth[month][r.Difficulty] == nil {
+			byMonth[month][r.Difficulty] = &playStats{}
+		}
+		byMonth[month][r.Difficulty].add(r)
+	}
+
+	now := time.Now().Local()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	fmt.Printf("%-18v %6v %8v %8v %6v %7v %8v\n", "Difficulty", "Solved", "Best", "Average", "Hints",
+		"Streak", "Longest")
+	for d := Easy; d <= RequiresGuessing; d++ {
+		s := byDifficulty[d.String()]
+		if s == nil {
+			continue
+		}
+		current, longest := s.streaks(today)
+		fmt.Printf("%-18v %6v %8v %8v %6.1f %7v %8v\n", d, s.solved, formatSeconds(s.best),
+			formatSeconds(s.seconds/float64(s.solved)), float64(s.hints)/float64(s.solved), current, longest)
+	}
+
+	var months []string
+	for month := range byMonth {
+		months = append(months, month)
+	}
+	sort.Strings(months)
+	fmt.Println("\nAverage time by month:")
+	for _, month := range months {
+		var cols []string
+		for d := Easy; d <= RequiresGuessing; d++ {
+			if s := byMonth[month][d.String()]; s != nil {
+				cols = append(cols, fmt.Sprintf("%v %v (%v)", d, formatSeconds(s.seconds/float64(s.solved)), s.solved))
+			}
+		}
+		fmt.Printf("  %v  %v\n", month, strings.Join(cols, ", "))
+	}
+	return nil
+}
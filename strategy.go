@@ -0,0 +1,794 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Difficulty grades a puzzle by the hardest technique required to solve it
+type Difficulty int
+
+const (
+	Easy Difficulty = iota
+	Medium
+	Hard
+	RequiresChains
+	RequiresGuessing
+)
+
+// String formats the difficulty for human consumption
+func (d Difficulty) String() string {
+	switch d {
+	case Easy:
+		return "easy"
+	case Medium:
+		return "medium"
+	case Hard:
+		return "hard"
+	case RequiresChains:
+		return "requires chains"
+	case RequiresGuessing:
+		return "requires guessing"
+	}
+	return fmt.Sprintf("Difficulty(%d)", int(d))
+}
+
+// Cell identifies a board position, row and col indices are 0 based
+type Cell struct {
+	Row, Col int
+}
+
+// String formats the cell in the common r1c1 notation, which is 1 based
+func (c Cell) String() string {
+	return fmt.Sprintf("r%vc%v", c.Row+1, c.Col+1)
+}
+
+// box returns the index of the 3x3 section containing the cell
+func (c Cell) box() int {
+	return c.Row/3*3 + c.Col/3
+}
+
+// sees is true if the cells are distinct and share a row, column or section
+func (c Cell) sees(o Cell) bool {
+	if c == o {
+		return false
+	}
+	return c.Row == o.Row || c.Col == o.Col || c.box() == o.box()
+}
+
+// Candidate is a single pencil mark, a value that may occupy a cell
+type Candidate struct {
+	Cell
+	Value int
+}
+
+// String formats the candidate as an elimination, eg r1c1<>5
+func (c Candidate) String() string {
+	return fmt.Sprintf("%v<>%v", c.Cell, c.Value)
+}
+
+// Link connects two cells in a single digit chain.  A strong link means one
+// of the two cells must hold the digit, a weak link means they can't both.
+type Link struct {
+	From, To Cell
+	Strong   bool
+}
+
+// Step is a single deduction made by the strategy engine.  It either places
+// Value in Cell, or removes each of Eliminations from the pencil marks.
+type Step struct {
+	Technique    string
+	Cell         Cell
+	Value        int
+	Eliminations []Candidate
+	// Digit and Chain describe the links used by chain and coloring techniques
+	Digit int
+	Chain []Link
+	// Reason describes the deduction when it can't be expressed as a chain
+	Reason string
+}
+
+// String formats the step for the explain mode
+func (s Step) String() string {
+	result := s.Technique + ": "
+	if len(s.Chain) > 0 {
+		result += fmt.Sprintf("(%v) %v => ", s.Digit, formatChain(s.Chain))
+	}
+	if s.Reason != "" {
+		result += s.Reason + " => "
+	}
+	if s.Value != 0 {
+		return result + fmt.Sprintf("%v=%v", s.Cell, s.Value)
+	}
+	elims := make([]string, len(s.Eliminations))
+	for i, e := range s.Eliminations {
+		elims[i] = e.String()
+	}
+	return result + strings.Join(elims, ", ")
+}
+
+// formatChain renders links in Eureka notation, '=' for strong and '-' for weak.
+// Links that don't continue from the previous one start a new segment.
+func formatChain(chain []Link) string {
+	var sb strings.Builder
+	for i, l := range chain {
+		if i == 0 || chain[i-1].To != l.From {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(l.From.String())
+		}
+		if l.Strong {
+			sb.WriteString("=")
+		} else {
+			sb.WriteString("-")
+		}
+		sb.WriteString(l.To.String())
+	}
+	return sb.String()
+}
+
+// technique is a human style solving technique, find returns nil if the
+// technique makes no progress on the current pencil marks.  id is the name
+// used to enable or disable the technique from the command line.
+type technique struct {
+	id    string
+	name  string
+	level Difficulty
+	find  func(s *Strategist) *Step
+}
+
+// techniques are tried in order, easiest first
+var techniques = []technique{
+	{"naked-single", "Naked Single", Easy, findNakedSingle},
+	{"hidden-single", "Hidden Single", Easy, findHiddenSingle},
+	{"locked-candidates", "Locked Candidates", Medium, findLockedCandidates},
+	{"simple-coloring", "Simple Coloring", Hard, findSimpleColoring},
+	{"x-chain", "X-Chain", Hard, findXChain},
+	{"forcing-chain", "Forcing Chain", RequiresChains, findForcingChain},
+}
+
+// disabledTechniques holds the ids of techniques the strategy engine won't
+// use, puzzles needing them are graded as if the solver doesn't know them
+var disabledTechniques = make(map[string]bool)
+
+// techniqueIDs lists the id of every technique, easiest first
+func techniqueIDs() []string {
+	ids := make([]string, len(techniques))
+	for i, t := range techniques {
+		ids[i] = t.id
+	}
+	return ids
+}
+
+// disableTechniques parses a comma separated list of technique ids
+func disableTechniques(list string) error {
+	ids, err := parseTechniques(list)
+	for id := range ids {
+		disabledTechniques[id] = true
+	}
+	return err
+}
+
+// parseTechniques returns the set of ids in a comma separated list
+func parseTechniques(list string) (map[string]bool, error) {
+	ids := make(map[string]bool)
+	for _, id := range strings.Split(list, ",") {
+		id = strings.ToLower(strings.TrimSpace(id))
+		if id == "" {
+			continue
+		}
+		known := false
+		for _, t := range techniques {
+			if t.id == id {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("Unknown technique %q, expected one of: %v",
+				id, strings.Join(techniqueIDs(), ", "))
+		}
+		ids[id] = true
+	}
+	return ids, nil
+}
+
+// units holds every row, column and section of the board
+var units = buildUnits()
+
+func buildUnits() [][]Cell {
+	var result [][]Cell
+	for i := 0; i < DIM; i++ {
+		row := make([]Cell, 0, DIM)
+		col := make([]Cell, 0, DIM)
+		box := make([]Cell, 0, DIM)
+		for j := 0; j < DIM; j++ {
+			row = append(row, Cell{i, j})
+			col = append(col, Cell{j, i})
+			box = append(box, Cell{i/3*3 + j/3, i%3*3 + j%3})
+		}
+		result = append(result, row, col, box)
+	}
+	return result
+}
+
+// unitName describes units[i] for human consumption, eg "row 1"
+func unitName(i int) string {
+	return fmt.Sprintf("%v %v", [...]string{"row", "column", "box"}[i%3], i/3+1)
+}
+
+// Strategist solves a board the way a human would, by applying techniques to
+// pencil marks rather than guessing
+type Strategist struct {
+	game *Game
+	// cands holds the pencil marks, access as cands[row][col][val]
+	cands   [][][]bool
+	hardest Difficulty
+	// Steps records each deduction in the order it was made
+	Steps []Step
+}
+
+// NewStrategist fills in pencil marks for each empty cell of the board
+func NewStrategist(g *Game) *Strategist {
+	s := &Strategist{game: g}
+	s.cands = make([][][]bool, DIM)
+	for row := range s.cands {
+		s.cands[row] = make([][]bool, DIM)
+		for col := range s.cands[row] {
+			if g.board[row][col] == 0 {
+				s.cands[row][col] = g.CellCandidates(row, col)
+			} else {
+				s.cands[row][col] = make([]bool, DIM+1)
+			}
+		}
+	}
+	return s
+}
+
+// Solve applies techniques until the board is solved or no technique makes
+// progress, true if the board was solved
+func (s *Strategist) Solve() bool {
+	for !s.game.ValidSolution() {
+		if !s.Advance() {
+			return false
+		}
+	}
+	return true
+}
+
+// Advance applies the easiest technique that makes progress, false if none did
+func (s *Strategist) Advance() bool {
+	for _, t := range techniques {
+		if !disabledTechniques[t.id] && s.try(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// try applies technique t if it makes progress, false if it didn't
+func (s *Strategist) try(t technique) bool {
+	step := t.find(s)
+	if step == nil {
+		return false
+	}
+	step.Technique = t.name
+	s.apply(*step)
+	if t.level > s.hardest {
+		s.hardest = t.level
+	}
+	return true
+}
+
+// Hint returns the next step the strategy engine would take on g, or nil if
+// it is stuck.  g is left unchanged.
+func Hint(g *Game) *Step {
+	s := NewStrategist(g.Clone())
+	if !s.Advance() {
+		return nil
+	}
+	return &s.Steps[0]
+}
+
+// Difficulty grades the board by the hardest technique applied so far
+func (s *Strategist) Difficulty() Difficulty {
+	if !s.game.ValidSolution() {
+		return RequiresGuessing
+	}
+	return s.hardest
+}
+
+// apply makes the placement or eliminations of step and records it
+func (s *Strategist) apply(step Step) {
+	if step.Value != 0 {
+		s.place(step.Cell, step.Value)
+		s.game.record(Decision{Cell: step.Cell.String(), Value: step.Value, Reason: step.String()})
+	}
+	for _, e := range step.Eliminations {
+		s.cands[e.Row][e.Col][e.Value] = false
+	}
+	s.Steps = append(s.Steps, step)
+}
+
+// place makes a move and removes the value from the pencil marks of its peers
+func (s *Strategist) place(c Cell, val int) {
+	s.game.MakeMove(c.Row, c.Col, val)
+	s.cands[c.Row][c.Col] = make([]bool, DIM+1)
+	for _, p := range peers[c.Row][c.Col] {
+		s.cands[p.Row][p.Col][val] = false
+	}
+}
+
+// PencilMarks formats the board with the pencil marks of each empty cell in
+// place of its value, each column padded to its widest cell and the boxes
+// separated by lines
+func (s *Strategist) PencilMarks() string {
+	g := s.game
+	var cells [DIM][DIM]string
+	var width [DIM]int
+	for ri, row := range g.board {
+		for ci, val := range row {
+			if val != 0 {
+				cells[ri][ci] = g.symbols.format(val)
+			} else {
+				var marks strings.Builder
+				for v := 1; v <= DIM; v++ {
+					if s.cands[ri][ci][v] {
+						marks.WriteString(g.symbols.format(v))
+					}
+				}
+				cells[ri][ci] = marks.String()
+			}
+			width[ci] = max(width[ci], utf8.RuneCountInString(cells[ri][ci]))
+		}
+	}
+	var lines []string
+	for ri, row := range cells {
+		if ri > 0 && ri%3 == 0 {
+			var rule []string
+			for b := 0; b < DIM; b += 3 {
+				rule = append(rule, strings.Repeat("-", width[b]+width[b+1]+width[b+2]+4))
+			}
+			lines = append(lines, strings.Join(rule, "+"))
+		}
+		var line strings.Builder
+		for ci, cell := range row {
+			if ci > 0 && ci%3 == 0 {
+				line.WriteString(" |")
+			}
+			line.WriteByte(' ')
+			line.WriteString(cell)
+			line.WriteString(strings.Repeat(" ", width[ci]-utf8.RuneCountInString(cell)))
+		}
+		lines = append(lines, strings.TrimRight(line.String(), " "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// has is true if val is a pencil mark of cell c
+func (s *Strategist) has(c Cell, val int) bool {
+	return s.cands[c.Row][c.Col][val]
+}
+
+// cellsWith returns the cells of unit that have val as a pencil mark
+func (s *Strategist) cellsWith(unit []Cell, val int) []Cell {
+	var result []Cell
+	for _, c := range unit {
+		if s.has(c, val) {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// eliminations returns the pencil marks for val that see every cell of
+// targets, excluding the cells in skip
+func (s *Strategist) eliminations(val int, targets []Cell, skip map[Cell]bool) []Candidate {
+	var result []Candidate
+	for ri := range s.cands {
+	next:
+		for ci := range s.cands[ri] {
+			c := Cell{ri, ci}
+			if !s.has(c, val) || skip[c] {
+				continue
+			}
+			for _, t := range targets {
+				if !c.sees(t) {
+					continue next
+				}
+			}
+			result = append(result, Candidate{c, val})
+		}
+	}
+	return result
+}
+
+// findNakedSingle looks for a cell with only one pencil mark
+func findNakedSingle(s *Strategist) *Step {
+	for ri := range s.cands {
+		for ci := range s.cands[ri] {
+			only, count := 0, 0
+			for val := 1; val <= DIM; val++ {
+				if s.cands[ri][ci][val] {
+					only = val
+					count++
+				}
+			}
+			if count == 1 {
+				return &Step{Cell: Cell{ri, ci}, Value: only}
+			}
+		}
+	}
+	return nil
+}
+
+// findHiddenSingle looks for a value that fits in only one cell of a unit
+func findHiddenSingle(s *Strategist) *Step {
+	for _, unit := range units {
+		for val := 1; val <= DIM; val++ {
+			if cells := s.cellsWith(unit, val); len(cells) == 1 {
+				return &Step{Cell: cells[0], Value: val}
+			}
+		}
+	}
+	return nil
+}
+
+// findLockedCandidates looks for a value confined to the intersection of two
+// units, which can then be removed from the remainder of the other unit
+func findLockedCandidates(s *Strategist) *Step {
+	for _, unit := range units {
+		for val := 1; val <= DIM; val++ {
+			cells := s.cellsWith(unit, val)
+			if len(cells) < 2 {
+				continue
+			}
+			skip := make(map[Cell]bool)
+			for _, c := range cells {
+				skip[c] = true
+			}
+			if elims := s.eliminations(val, cells, skip); len(elims) > 0 {
+				return &Step{Eliminations: elims}
+			}
+		}
+	}
+	return nil
+}
+
+// conjugates returns the strong links for val, pairs of cells that are the
+// only two places for val within some unit
+func (s *Strategist) conjugates(val int) map[Cell][]Cell {
+	result := make(map[Cell][]Cell)
+	for _, unit := range units {
+		cells := s.cellsWith(unit, val)
+		if len(cells) != 2 {
+			continue
+		}
+		a, b := cells[0], cells[1]
+		if !containsCell(result[a], b) {
+			result[a] = append(result[a], b)
+			result[b] = append(result[b], a)
+		}
+	}
+	return result
+}
+
+func containsCell(cells []Cell, c Cell) bool {
+	for _, o := range cells {
+		if o == c {
+			return true
+		}
+	}
+	return false
+}
+
+// findSimpleColoring two-colors each cluster of strong links for a value.  If
+// two cells of the same color see each other that color is false (color wrap),
+// and any cell seeing both colors can't hold the value (color trap).
+func findSimpleColoring(s *Strategist) *Step {
+	for val := 1; val <= DIM; val++ {
+		links := s.conjugates(val)
+		colored := make(map[Cell]bool)
+		for ri := 0; ri < DIM; ri++ {
+			for ci := 0; ci < DIM; ci++ {
+				start := Cell{ri, ci}
+				if colored[start] || len(links[start]) == 0 {
+					continue
+				}
+				// Breadth first walk of the cluster, alternating colors
+				color := map[Cell]int{start: 0}
+				var chain []Link
+				queue := []Cell{start}
+				for len(queue) > 0 {
+					c := queue[0]
+					queue = queue[1:]
+					colored[c] = true
+					for _, n := range links[c] {
+						if _, ok := color[n]; !ok {
+							color[n] = 1 - color[c]
+							chain = append(chain, Link{c, n, true})
+							queue = append(queue, n)
+						}
+					}
+				}
+				if len(color) < 3 {
+					// A lone conjugate pair is covered by simpler techniques
+					continue
+				}
+				if step := colorWrap(val, color, chain); step != nil {
+					return step
+				}
+				if step := colorTrap(s, val, color, chain); step != nil {
+					return step
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func colorWrap(val int, color map[Cell]int, chain []Link) *Step {
+	for a, ca := range color {
+		for b, cb := range color {
+			if ca != cb || !a.sees(b) {
+				continue
+			}
+			var elims []Candidate
+			for c, cc := range color {
+				if cc == ca {
+					elims = append(elims, Candidate{c, val})
+				}
+			}
+			sortCandidates(elims)
+			return &Step{Eliminations: elims, Digit: val, Chain: chain}
+		}
+	}
+	return nil
+}
+
+func colorTrap(s *Strategist, val int, color map[Cell]int, chain []Link) *Step {
+	var elims []Candidate
+	for ri := 0; ri < DIM; ri++ {
+		for ci := 0; ci < DIM; ci++ {
+			c := Cell{ri, ci}
+			if _, ok := color[c]; ok || !s.has(c, val) {
+				continue
+			}
+			var sees [2]bool
+			for o, co := range color {
+				if c.sees(o) {
+					sees[co] = true
+				}
+			}
+			if sees[0] && sees[1] {
+				elims = append(elims, Candidate{c, val})
+			}
+		}
+	}
+	if len(elims) == 0 {
+		return nil
+	}
+	return &Step{Eliminations: elims, Digit: val, Chain: chain}
+}
+
+// sortCandidates orders candidates by row then column, for stable output
+func sortCandidates(cands []Candidate) {
+	for i := 1; i < len(cands); i++ {
+		for j := i; j > 0; j-- {
+			a, b := cands[j-1], cands[j]
+			if a.Row < b.Row || (a.Row == b.Row && a.Col <= b.Col) {
+				break
+			}
+			cands[j-1], cands[j] = b, a
+		}
+	}
+}
+
+// maxChainLinks bounds the length of the X-Chains we search for
+const maxChainLinks = 9
+
+// findXChain looks for an alternating chain of strong and weak links on a
+// single value, beginning and ending with a strong link.  One end must hold
+// the value, so any cell seeing both ends can't.
+func findXChain(s *Strategist) *Step {
+	for val := 1; val <= DIM; val++ {
+		strong := s.conjugates(val)
+		for ri := 0; ri < DIM; ri++ {
+			for ci := 0; ci < DIM; ci++ {
+				start := Cell{ri, ci}
+				if len(strong[start]) == 0 {
+					continue
+				}
+				inChain := map[Cell]bool{start: true}
+				if step := s.extendXChain(val, strong, start, nil, inChain); step != nil {
+					return step
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// extendXChain continues chain from end with a strong link, and then a weak
+// link, searching depth first
+func (s *Strategist) extendXChain(val int, strong map[Cell][]Cell, end Cell,
+	chain []Link, inChain map[Cell]bool) *Step {
+	if len(chain)+1 > maxChainLinks {
+		return nil
+	}
+	for _, next := range strong[end] {
+		if inChain[next] {
+			continue
+		}
+		chain := append(chain[:len(chain):len(chain)], Link{end, next, true})
+		inChain[next] = true
+		start := chain[0].From
+		if len(chain) >= 3 {
+			elims := s.eliminations(val, []Cell{start, next}, inChain)
+			if len(elims) > 0 {
+				return &Step{Eliminations: elims, Digit: val, Chain: chain}
+			}
+		}
+		if len(chain)+2 <= maxChainLinks {
+			// Any peer holding the value makes a weak link
+			for ri := 0; ri < DIM; ri++ {
+				for ci := 0; ci < DIM; ci++ {
+					weak := Cell{ri, ci}
+					if inChain[weak] || !s.has(weak, val) || !next.sees(weak) {
+						continue
+					}
+					inChain[weak] = true
+					step := s.extendXChain(val, strong, weak,
+						append(chain[:len(chain):len(chain)], Link{next, weak, false}), inChain)
+					delete(inChain, weak)
+					if step != nil {
+						return step
+					}
+				}
+			}
+		}
+		delete(inChain, next)
+	}
+	return nil
+}
+
+// forcingDepth bounds the number of singles a forcing chain may follow from
+// its starting assumption
+var forcingDepth = 20
+
+// maxForcingCandidates bounds the size of the cells forcing chains start from
+const maxForcingCandidates = 3
+
+// clone returns a copy of the strategist, for speculative solving
+func (s *Strategist) clone() *Strategist {
+	c := &Strategist{game: s.game.Clone(), cands: make([][][]bool, DIM)}
+	for ri := range s.cands {
+		c.cands[ri] = make([][]bool, DIM)
+		for ci := range s.cands[ri] {
+			c.cands[ri][ci] = append([]bool(nil), s.cands[ri][ci]...)
+		}
+	}
+	return c
+}
+
+// contradiction returns a description of why the pencil marks can't lead to
+// a solution, or the empty string if they still might
+func (s *Strategist) contradiction() string {
+	for ri := range s.cands {
+		for ci := range s.cands[ri] {
+			if s.game.board[ri][ci] != 0 {
+				continue
+			}
+			empty := true
+			for val := 1; val <= DIM; val++ {
+				if s.cands[ri][ci][val] {
+					empty = false
+					break
+				}
+			}
+			if empty {
+				return fmt.Sprintf("no candidates remain for %v", Cell{ri, ci})
+			}
+		}
+	}
+	for i, unit := range units {
+		var placed [DIM + 1]bool
+		for _, c := range unit {
+			placed[s.game.board[c.Row][c.Col]] = true
+		}
+		for val := 1; val <= DIM; val++ {
+			if !placed[val] && len(s.cellsWith(unit, val)) == 0 {
+				return fmt.Sprintf("no place for %v in %v", val, unitName(i))
+			}
+		}
+	}
+	return ""
+}
+
+// propagate applies singles until stuck or forcingDepth placements have been
+// made, returning the cells placed and any contradiction found
+func (s *Strategist) propagate() (placed map[Cell]int, contradiction string) {
+	placed = make(map[Cell]int)
+	for i := 0; i < forcingDepth; i++ {
+		if contradiction = s.contradiction(); contradiction != "" {
+			return
+		}
+		step := findNakedSingle(s)
+		if step == nil {
+			step = findHiddenSingle(s)
+		}
+		if step == nil {
+			return
+		}
+		s.place(step.Cell, step.Value)
+		placed[step.Cell] = step.Value
+	}
+	contradiction = s.contradiction()
+	return
+}
+
+// findForcingChain assumes each candidate of a cell in turn, following singles
+// from it.  A candidate leading to a contradiction is eliminated, and a
+// placement made by every candidate of the cell must be true.
+func findForcingChain(s *Strategist) *Step {
+	for size := 2; size <= maxForcingCandidates; size++ {
+		for ri := range s.cands {
+			for ci := range s.cands[ri] {
+				start := Cell{ri, ci}
+				var vals []int
+				for val := 1; val <= DIM; val++ {
+					if s.has(start, val) {
+						vals = append(vals, val)
+					}
+				}
+				if len(vals) != size {
+					continue
+				}
+				if step := s.forceCell(start, vals); step != nil {
+					return step
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Strategist) forceCell(start Cell, vals []int) *Step {
+	var common map[Cell]int
+	for _, val := range vals {
+		branch := s.clone()
+		branch.place(start, val)
+		placed, contradiction := branch.propagate()
+		if contradiction != "" {
+			return &Step{
+				Eliminations: []Candidate{{start, val}},
+				Reason:       fmt.Sprintf("%v=%v leads to %v", start, val, contradiction),
+			}
+		}
+		placed[start] = val
+		if common == nil {
+			common = placed
+			continue
+		}
+		for c, v := range common {
+			if placed[c] != v {
+				delete(common, c)
+			}
+		}
+	}
+	for ri := 0; ri < DIM; ri++ {
+		for ci := 0; ci < DIM; ci++ {
+			c := Cell{ri, ci}
+			if v, ok := common[c]; ok && c != start {
+				return &Step{
+					Cell:   c,
+					Value:  v,
+					Reason: fmt.Sprintf("every candidate of %v", start),
+				}
+			}
+		}
+	}
+	return nil
+}
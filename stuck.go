@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// defaultKnown lists the techniques stuck assumes a player knows
+const defaultKnown = "naked-single,hidden-single"
+
+// stuckSamples is the number of solutions sampled for the best guess when
+// there are too many to count, fewer than guess uses to keep stuck quick on
+// near empty boards
+const stuckSamples = 20
+
+// stuckCommand handles: stuck [flags] <puzzle>, solving with only the known
+// techniques and reporting where that stalls, along with the easiest
+// technique of the catalog which makes progress from there
+func stuckCommand(args []string) error {
+	fs := flag.NewFlagSet("stuck", flag.ExitOnError)
+	list := fs.String("known", defaultKnown,
+		"comma separated `techniques` the player knows: "+strings.Join(techniqueIDs(), ", "))
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("Usage: stuck [flags] <puzzle>")
+	}
+	known, err := parseTechniques(*list)
+	if err != nil {
+		return err
+	}
+	board, err := readGame(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	s := NewStrategist(board)
+	for progress := true; progress && !board.ValidSolution(); {
+		progress = false
+		for _, t := range techniques {
+			if known[t.id] && s.try(t) {
+				progress = true
+				break
+			}
+		}
+	}
+	if board.ValidSolution() {
+		fmt.Printf("Solved with the known techniques in %v steps\n", len(s.Steps))
+		return nil
+	}
+
+	fmt.Printf("Stuck after %v steps with %v cells empty:\n", len(s.Steps), board.remaining)
+	fmt.Println(board.DiffString(false))
+	fmt.Println("\nCandidates:")
+	fmt.Println(s.PencilMarks())
+	for _, t := range techniques {
+		if known[t.id] {
+			continue
+		}
+		if step := t.find(s); step != nil {
+			step.Technique = t.name
+			fmt.Printf("\nNext technique to learn: %v (%v)\n", t.name, t.id)
+			fmt.Println(*step)
+			return nil
+		}
+	}
+	fmt.Println("\nNo technique in the catalog makes progress, the puzzle requires guessing")
+	if board.CountSolutions(2) > 1 {
+		d := board.SolutionDistribution(distributionLimit, stuckSamples, rand.New(rand.NewSource(time.Now().UnixNano())))
+		if guesses := d.Guesses(board); len(guesses) > 0 {
+			fmt.Printf("Best guess, splitting the solutions most evenly: %v\n", guesses[0])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image/color"
+)
+
+// BoardStyle sets how the PNG and SVG renderers draw a board
+type BoardStyle struct {
+	// Cell is the size of each cell in pixels
+	Cell int
+	// Font is the font family of SVG boards and LaTeX worksheets, PNG boards
+	// always draw their own bitmap digits
+	Font string
+	// Line and BoxLine are the widths of the lines between cells and boxes
+	Line, BoxLine int
+	Theme
+}
+
+// DefaultBoardStyle is the style of boards without style flags, in the light
+// theme
+var DefaultBoardStyle = BoardStyle{Cell: 40, Font: "sans-serif", Line: 1, BoxLine: 3, Theme: themes["light"]}
+
+// activeBoardStyle is DefaultBoardStyle in the theme chosen by -theme
+func activeBoardStyle() BoardStyle {
+	s := DefaultBoardStyle
+	s.Theme = activeTheme
+	return s
+}
+
+// HighContrast returns the style with every value in black on white and the
+// lines twice as thick, for readers with low vision
+func (s BoardStyle) HighContrast() BoardStyle {
+	s.Background, s.Grid, s.Given, s.Filled = white, black, black, black
+	s.Line *= 2
+	s.BoxLine *= 2
+	return s
+}
+
+// htmlColor formats c as an HTML color
+func htmlColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// boardStyleFlags are the command line flags of the commands which draw
+// boards, see addBoardStyleFlags
+type boardStyleFlags struct {
+	style        BoardStyle
+	highContrast bool
+}
+
+// addBoardStyleFlags adds the flags for large print and high contrast boards
+// to fs
+func addBoardStyleFlags(fs *flag.FlagSet) *boardStyleFlags {
+	f := &boardStyleFlags{style: activeBoardStyle()}
+	fs.IntVar(&f.style.Cell, "cell-size", f.style.Cell, "draw cells `pixels` wide, larger for large print")
+	fs.StringVar(&f.style.Font, "font", f.style.Font, "font `family` of the digits of SVG and HTML boards and LaTeX worksheets")
+	fs.IntVar(&f.style.Line, "line-width", f.style.Line, "width in `pixels` of the lines between cells")
+	fs.IntVar(&f.style.BoxLine, "box-line-width", f.style.BoxLine, "width in `pixels` of the lines between boxes")
+	fs.BoolVar(&f.highContrast, "high-contrast", false, "draw every value black on white with thicker lines")
+	return f
+}
+
+// Style returns the style chosen by the flags after parsing, or an error if
+// the sizes can't be drawn
+func (f *boardStyleFlags) Style() (BoardStyle, error) {
+	s := f.style
+	if f.highContrast {
+		s = s.HighContrast()
+	}
+	if s.Cell < 12 {
+		return s, fmt.Errorf("-cell-size must be at least 12")
+	}
+	if s.Line < 1 || s.BoxLine < 1 || s.BoxLine > s.Cell/2 {
+		return s, fmt.Errorf("Line widths must be at least 1 and at most half of -cell-size")
+	}
+	return s, nil
+}
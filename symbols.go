@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Symbols is the alphabet of a puzzle written with symbols other than digits,
+// such as the letters of a Wordoku.  Each symbol stands for its position in
+// the alphabet plus one, so the puzzle is solved numerically underneath.
+type Symbols [DIM]rune
+
+// symbolsPrefix starts the line declaring the alphabet of a puzzle file,
+// ahead of the puzzle itself
+const symbolsPrefix = "symbols:"
+
+// ParseSymbols reads an alphabet of DIM distinct symbols, such as a word of
+// nine letters without repeats
+func ParseSymbols(s string) (*Symbols, error) {
+	runes := []rune(strings.TrimSpace(s))
+	if len(runes) != DIM {
+		return nil, fmt.Errorf("Expected %v symbols, found %v in %q", DIM, len(runes), s)
+	}
+	var sym Symbols
+	for i, r := range runes {
+		switch {
+		case r == '0' || r == '.':
+			return nil, fmt.Errorf("Symbol %q is reserved for empty cells", r)
+		case unicode.IsSpace(r) || !unicode.IsPrint(r):
+			return nil, fmt.Errorf("Symbol %q can't be used", r)
+		case strings.ContainsRune(string(runes[:i]), r):
+			return nil, fmt.Errorf("Symbol %q appears more than once", r)
+		}
+		sym[i] = r
+	}
+	return &sym, nil
+}
+
+// String returns the alphabet in order
+func (s *Symbols) String() string {
+	return string(s[:])
+}
+
+// Decode replaces each symbol of text with its digit, and digits which aren't
+// symbols with spaces so they can't be mistaken for values.  0 and . still
+// mark empty cells, and are both decoded to 0.
+func (s *Symbols) Decode(text string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '.' {
+			return '0'
+		}
+		for i, sym := range s {
+			if r == sym {
+				return rune('1' + i)
+			}
+		}
+		if '1' <= r && r <= '9' {
+			return ' '
+		}
+		return r
+	}, text)
+}
+
+// format returns the symbol for val, or . for an empty cell.  s may be nil,
+// giving the digit.
+func (s *Symbols) format(val int) string {
+	switch {
+	case s == nil:
+		return strconv.Itoa(val)
+	case val == 0:
+		return "."
+	}
+	return string(s[val-1])
+}
+
+// readSymbols takes the alphabet declared by a leading symbols: line off text,
+// returning the rest of text decoded to digits.  The alphabet is nil and text
+// is returned unchanged if there is no declaration.
+func readSymbols(text string) (*Symbols, string, error) {
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(strings.ToLower(line), symbolsPrefix) {
+			break
+		}
+		sym, err := ParseSymbols(line[len(symbolsPrefix):])
+		if err != nil {
+			return nil, "", err
+		}
+		_, rest, _ := strings.Cut(text, scanner.Text())
+		rest = strings.TrimPrefix(strings.TrimPrefix(rest, "\r"), "\n")
+		return sym, sym.Decode(rest), nil
+	}
+	return nil, text, nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// TemplateData is the data model for -o template, documented in the README
+type TemplateData struct {
+	// Puzzle is the starting board, Solution the board after solving
+	Puzzle, Solution TemplateBoard
+	Solved           bool
+	// Checksum is the Checksum of the solution, empty if unsolved
+	Checksum   string
+	Difficulty string
+	// Steps describes each deduction of the strategy engine, in order
+	Steps []string
+	Algo  string
+	Stats Stats
+	Metadata
+}
+
+// TemplateBoard is a board as seen by templates
+type TemplateBoard struct {
+	// Line is the board in the 81 digit line format, Grid as 9 rows
+	Line, Grid string
+	// Rows holds the value of each cell, 0 if empty, as Rows[row][col]
+	Rows [][]int
+	// Givens marks the cells given by the puzzle, as Givens[row][col]
+	Givens [][]bool
+	Clues  int
+}
+
+func newTemplateBoard(g *Game) TemplateBoard {
+	b := TemplateBoard{Line: g.Line(), Grid: g.GridString(), Clues: DIM*DIM - g.remaining}
+	for ri, row := range g.board {
+		b.Rows = append(b.Rows, append([]int(nil), row...))
+		b.Givens = append(b.Givens, append([]bool(nil), g.given[ri]...))
+	}
+	return b
+}
+
+// templateFuncs are the functions available to templates in addition to the
+// builtins of text/template
+var templateFuncs = template.FuncMap{
+	"join": strings.Join,
+	"add":  func(a, b int) int { return a + b },
+	// cell formats a value, with empty for 0
+	"cell": func(val int, empty string) string {
+		if val == 0 {
+			return empty
+		}
+		return strconv.Itoa(val)
+	},
+	"repeat": strings.Repeat,
+}
+
+// newTemplateData grades and solves the puzzle, collecting everything a
+// template can show.  The board is left solved.
+func newTemplateData(p *Puzzle, algo string) (*TemplateData, error) {
+	solver, ok := solvers[algo]
+	if !ok {
+		return nil, fmt.Errorf("Unknown algorithm %q", algo)
+	}
+	d := &TemplateData{Puzzle: newTemplateBoard(p.Game), Algo: algo, Metadata: p.Metadata}
+	s := NewStrategist(p.Game.Clone())
+	s.Solve()
+	d.Difficulty = s.Difficulty().String()
+	for _, step := range s.Steps {
+		d.Steps = append(d.Steps, step.String())
+	}
+	start := time.Now()
+	d.Solved = solver.Solve(p.Game, &d.Stats)
+	d.Stats.Millis = float64(time.Since(start).Microseconds()) / 1000
+	if d.Solved {
+		d.Checksum = p.Game.Checksum()
+	}
+	d.Solution = newTemplateBoard(p.Game)
+	return d, nil
+}
+
+// writeTemplate solves the puzzle and renders it through the text/template
+// in fname
+func writeTemplate(w io.Writer, p *Puzzle, fname, algo string) error {
+	text, err := os.ReadFile(fname)
+	if err != nil {
+		return err
+	}
+	t, err := template.New(filepath.Base(fname)).Funcs(templateFuncs).Parse(string(text))
+	if err != nil {
+		return err
+	}
+	d, err := newTemplateData(p, algo)
+	if err != nil {
+		return err
+	}
+	return t.Execute(w, d)
+}
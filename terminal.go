@@ -0,0 +1,118 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// boxedWidth is the number of columns needed for the boxed grid of
+// TerminalString, narrower terminals get the compact grid
+var boxedWidth = 4 + 3*(1+3*(cellWidth+1))
+
+// noColor is true if the NO_COLOR environment variable asks for output
+// without colors, see https://no-color.org
+func noColor() bool {
+	return os.Getenv("NO_COLOR") != ""
+}
+
+// unicodeLocale is true if the locale can display Unicode, judged by the
+// charset of the first of LC_ALL, LC_CTYPE and LANG that is set
+func unicodeLocale() bool {
+	for _, name := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if v := os.Getenv(name); v != "" {
+			v = strings.ToLower(v)
+			return strings.Contains(v, "utf-8") || strings.Contains(v, "utf8")
+		}
+	}
+	return false
+}
+
+// terminalWidth returns the number of columns of the terminal f, from the
+// COLUMNS environment variable or else the terminal itself, 0 if unknown
+func terminalWidth(f *os.File) int {
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+	return windowWidth(f)
+}
+
+// boxLines are the characters drawing the boxed grid: the horizontal and
+// vertical lines, then the corners and junctions of the top, middle and
+// bottom lines from left to right
+type boxLines struct {
+	horizontal, vertical string
+	top, middle, bottom  [3]string
+}
+
+var (
+	unicodeBox = boxLines{"─", "│", [3]string{"┌", "┬", "┐"}, [3]string{"├", "┼", "┤"}, [3]string{"└", "┴", "┘"}}
+	asciiBox   = boxLines{"-", "|", [3]string{"+", "+", "+"}, [3]string{"+", "+", "+"}, [3]string{"+", "+", "+"}}
+)
+
+// TerminalString formats the board for the terminal f, with box drawing
+// lines if it is wide enough and the values alone otherwise.  The lines
+// degrade to ASCII if the locale can't display Unicode.  If diff is true the
+// cells filled in by the solver are highlighted as in DiffString.
+func (g *Game) TerminalString(f *os.File, diff bool) string {
+	lines := asciiBox
+	if unicodeLocale() {
+		lines = unicodeBox
+	}
+	pad := " "
+	if width := terminalWidth(f); width > 0 && width < boxedWidth {
+		pad = ""
+	}
+	var sb strings.Builder
+	rule := func(ends [3]string, between bool) {
+		if pad == "" {
+			// The compact grid only has lines between boxes
+			if !between {
+				return
+			}
+			ends = [3]string{"", ends[1], ""}
+		}
+		sb.WriteString(ends[0])
+		for box := 0; box < 3; box++ {
+			if box > 0 {
+				sb.WriteString(ends[1])
+			}
+			sb.WriteString(strings.Repeat(lines.horizontal, len(pad)+3*(cellWidth+len(pad))))
+		}
+		sb.WriteString(ends[2] + "\n")
+	}
+	rule(lines.top, false)
+	for ri, row := range g.board {
+		if ri > 0 && ri%3 == 0 {
+			rule(lines.middle, true)
+		}
+		if pad != "" {
+			sb.WriteString(lines.vertical)
+		}
+		for ci, val := range row {
+			if ci > 0 && ci%3 == 0 {
+				sb.WriteString(pad + lines.vertical)
+			}
+			s := "."
+			if val != 0 {
+				s = g.symbols.format(val)
+			}
+			s = strings.Repeat(" ", max(cellWidth-utf8.RuneCountInString(s), 0)) + s
+			switch {
+			case !diff || val == 0:
+			case g.given[ri][ci]:
+				s = sgr(activeTheme.TerminalGiven, s)
+			default:
+				s = sgr(activeTheme.TerminalFilled, s)
+			}
+			sb.WriteString(pad + s)
+		}
+		if pad != "" {
+			sb.WriteString(pad + lines.vertical)
+		}
+		sb.WriteByte('\n')
+	}
+	rule(lines.bottom, false)
+	return strings.TrimSuffix(sb.String(), "\n")
+}
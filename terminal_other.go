@@ -0,0 +1,11 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package main
+
+import "os"
+
+// windowWidth is unknown on platforms without the TIOCGWINSZ ioctl, leaving
+// COLUMNS to set the width
+func windowWidth(f *os.File) int {
+	return 0
+}
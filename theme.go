@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/color"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Theme colors boards the same way in every renderer: PNG and SVG images,
+// HTML pages, and the terminal
+type Theme struct {
+	// Given colors the givens and Filled the values added by the solver or
+	// player
+	Background, Grid, Given, Filled color.RGBA
+	// TerminalGiven and TerminalFilled are the SGR parameters, such as "1"
+	// for bold or "7" for reverse video, of values printed to the terminal,
+	// empty for none
+	TerminalGiven, TerminalFilled string
+}
+
+var (
+	white = color.RGBA{255, 255, 255, 255}
+	black = color.RGBA{0, 0, 0, 255}
+)
+
+// themes are the built in themes, light is the default
+var themes = map[string]Theme{
+	"light": {Background: white, Grid: black, Given: black, Filled: color.RGBA{0x15, 0x65, 0xc0, 255},
+		TerminalFilled: "7"},
+	"dark": {Background: color.RGBA{0x1e, 0x1e, 0x1e, 255}, Grid: color.RGBA{0xc8, 0xc8, 0xc8, 255}, Given: white,
+		Filled: color.RGBA{0x7c, 0xb8, 0xff, 255}, TerminalGiven: "1;97", TerminalFilled: "96"},
+	"newspaper": {Background: color.RGBA{0xf4, 0xf1, 0xea, 255}, Grid: black, Given: black,
+		Filled: color.RGBA{0x55, 0x55, 0x55, 255}, TerminalGiven: "1", TerminalFilled: "2"},
+	// colorblind-safe uses the blue of the Okabe-Ito palette, and underlines
+	// solved values in the terminal so they don't rely on color alone
+	"colorblind-safe": {Background: white, Grid: black, Given: black, Filled: color.RGBA{0x00, 0x72, 0xb2, 255},
+		TerminalFilled: "4;34"},
+}
+
+// activeTheme is the theme chosen by the -theme flag
+var activeTheme = themes["light"]
+
+// themeConfig is a theme defined in the config file, whose colors are HTML
+// hex colors such as "#1565c0".  Base names the theme it starts from, light
+// by default, so only the fields which differ need to be set.
+type themeConfig struct {
+	Base           string `json:"base"`
+	Background     string `json:"background"`
+	Grid           string `json:"grid"`
+	Given          string `json:"given"`
+	Filled         string `json:"filled"`
+	TerminalGiven  string `json:"terminal_given"`
+	TerminalFilled string `json:"terminal_filled"`
+}
+
+// Config is the user's config file, see configPath
+type Config struct {
+	Themes map[string]themeConfig `json:"themes"`
+	// Emoji holds the sets of -o emoji, see LookupEmoji
+	Emoji map[string][]string `json:"emoji"`
+}
+
+// configPath is config.json in the sudoku-solver directory of the user's
+// config directory, such as ~/.config/sudoku-solver/config.json
+func configPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "sudoku-solver", "config.json"), nil
+}
+
+// readConfig reads the config file, empty if there isn't one
+func readConfig() (*Config, error) {
+	c := &Config{}
+	fname, err := configPath()
+	if err != nil {
+		return c, nil
+	}
+	data, err := os.ReadFile(fname)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("%v: %v", fname, err)
+	}
+	return c, nil
+}
+
+// parseHexColor parses an HTML color of the form #rrggbb
+func parseHexColor(s string) (color.RGBA, error) {
+	c := color.RGBA{A: 255}
+	if len(s) != 7 || s[0] != '#' {
+		return c, fmt.Errorf("Invalid color %q, expected #rrggbb", s)
+	}
+	if _, err := fmt.Sscanf(s[1:], "%02x%02x%02x", &c.R, &c.G, &c.B); err != nil {
+		return c, fmt.Errorf("Invalid color %q, expected #rrggbb", s)
+	}
+	return c, nil
+}
+
+// theme builds the theme defined by tc
+func (tc themeConfig) theme() (Theme, error) {
+	base := tc.Base
+	if base == "" {
+		base = "light"
+	}
+	t, ok := themes[base]
+	if !ok {
+		return t, fmt.Errorf("Unknown base theme %q", base)
+	}
+	colors := []struct {
+		value string
+		field *color.RGBA
+	}{
+		{tc.Background, &t.Background},
+		{tc.Grid, &t.Grid},
+		{tc.Given, &t.Given},
+		{tc.Filled, &t.Filled},
+	}
+	for _, c := range colors {
+		if c.value == "" {
+			continue
+		}
+		var err error
+		if *c.field, err = parseHexColor(c.value); err != nil {
+			return t, err
+		}
+	}
+	if tc.TerminalGiven != "" {
+		t.TerminalGiven = tc.TerminalGiven
+	}
+	if tc.TerminalFilled != "" {
+		t.TerminalFilled = tc.TerminalFilled
+	}
+	return t, nil
+}
+
+// LookupTheme returns the theme called name, either built in or defined in
+// the config file
+func LookupTheme(name string) (Theme, error) {
+	config, err := readConfig()
+	if err != nil {
+		return Theme{}, err
+	}
+	if tc, ok := config.Themes[name]; ok {
+		t, err := tc.theme()
+		if err != nil {
+			return t, fmt.Errorf("Theme %q: %v", name, err)
+		}
+		return t, nil
+	}
+	if t, ok := themes[name]; ok {
+		return t, nil
+	}
+	names := themeNames()
+	for name := range config.Themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return Theme{}, fmt.Errorf("Unknown theme %q, expected one of: %v", name, strings.Join(names, ", "))
+}
+
+// themeNames returns the names of the built in themes, sorted
+func themeNames() []string {
+	var names []string
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sgr wraps s in the terminal escapes for the SGR parameters, if any and
+// colors aren't turned off by NO_COLOR
+func sgr(params, s string) string {
+	if params == "" || noColor() {
+		return s
+	}
+	return "\x1b[" + params + "m" + s + "\x1b[0m"
+}
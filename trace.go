@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// Decision is a single move recorded in a Trace
+type Decision struct {
+	// Cell is in r1c1 notation
+	Cell  string `json:"cell"`
+	Value int    `json:"value,omitempty"`
+	// Reason explains why the value was chosen, or for a backtrack why it was
+	// taken back
+	Reason    string `json:"reason"`
+	Backtrack bool   `json:"backtrack,omitempty"`
+}
+
+// Trace records the decisions made while solving a puzzle, in order
+type Trace struct {
+	Puzzle    string     `json:"puzzle"`
+	Decisions []Decision `json:"decisions"`
+	// Checksum is the Checksum of the solution reached, which replay checks
+	Checksum string `json:"checksum,omitempty"`
+}
+
+// StartTrace begins recording the moves made on the board by the strategy
+// engine and the backtracking and propagating solvers
+func (g *Game) StartTrace() *Trace {
+	g.trace = &Trace{Puzzle: g.Line()}
+	return g.trace
+}
+
+// record appends d to the trace of the board, if it is being traced
+func (g *Game) record(d Decision) {
+	if g.trace != nil {
+		g.trace.Decisions = append(g.trace.Decisions, d)
+	}
+}
+
+// writeTrace saves t as JSON to the file named fname
+func writeTrace(fname string, t *Trace) error {
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fname, append(data, '\n'), 0644)
+}
+
+// readTrace loads a trace written by -trace
+func readTrace(fname string) (*Trace, error) {
+	data, err := os.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	t := &Trace{}
+	if err := json.Unmarshal(data, t); err != nil {
+		return nil, fmt.Errorf("%v: %v", fname, err)
+	}
+	return t, nil
+}
+
+// replayCommand handles: replay [flags] <trace file>, re-applying each
+// decision of a trace to its starting board
+func replayCommand(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	step := fs.Bool("step", false, "print the board after each decision and wait for enter")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("Usage: replay [flags] <trace file>")
+	}
+	t, err := readTrace(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	g, err := ParseLine(t.Puzzle)
+	if err != nil {
+		return fmt.Errorf("Trace puzzle: %v", err)
+	}
+	fmt.Println("Starting configuration:")
+	fmt.Println(g.DiffString(false))
+	stdin := bufio.NewReader(os.Stdin)
+	for i, d := range t.Decisions {
+		c, err := ParseCell(d.Cell)
+		if err != nil {
+			return fmt.Errorf("Decision %v: %v", i+1, err)
+		}
+		if d.Backtrack {
+			if g.given[c.Row][c.Col] {
+				return fmt.Errorf("Decision %v: can't take back the given at %v", i+1, c)
+			}
+			g.UnmakeMove(c.Row, c.Col)
+			fmt.Printf("%3v. backtrack %v: %v\n", i+1, c, d.Reason)
+		} else {
+			if g.board[c.Row][c.Col] != 0 || !g.CellCandidates(c.Row, c.Col)[d.Value] {
+				return fmt.Errorf("Decision %v: %v is not a candidate of %v", i+1, d.Value, c)
+			}
+			g.MakeMove(c.Row, c.Col, d.Value)
+			fmt.Printf("%3v. %v = %v: %v\n", i+1, c, d.Value, d.Reason)
+		}
+		if *step {
+			fmt.Println(g.DiffString(false))
+			stdin.ReadString('\n')
+		}
+	}
+	fmt.Println("\nEnding configuration:")
+	fmt.Println(g.DiffString(false))
+	fmt.Printf("\nSolved? %v\n", g.ValidSolution())
+	if t.Checksum != "" {
+		if sum := g.Checksum(); sum != t.Checksum {
+			return fmt.Errorf("Checksum %v of the replayed board doesn't match %v in the trace", sum, t.Checksum)
+		}
+		fmt.Printf("Checksum: %v, matches the trace\n", t.Checksum)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import "sort"
+
+// uniqueCountLimit caps the solutions counted when comparing clues to add,
+// beyond which they are all equally far from unique
+const uniqueCountLimit = 200
+
+// SuggestUniqueClues returns clues which, added to a puzzle with more than
+// one solution, leave only one, or nil if it has at most one already.  The
+// clues all come from the same solution, and are chosen greedily: each is
+// the cell which rules out a second solution while leaving the fewest
+// solutions.  So the set is small, though not always the smallest.  The
+// clues are returned in board order.
+func SuggestUniqueClues(g *Game) []Candidate {
+	p := g.Clone()
+	solutions, _ := p.EnumerateSolutions(2, 0)
+	if len(solutions) < 2 {
+		return nil
+	}
+	ref := solutions[0]
+	var clues []Candidate
+	for {
+		solutions, _ := p.EnumerateSolutions(2, 0)
+		if len(solutions) < 2 {
+			sort.Slice(clues, func(i, j int) bool {
+				return clues[i].Row*DIM+clues[i].Col < clues[j].Row*DIM+clues[j].Col
+			})
+			return clues
+		}
+		other := solutions[0]
+		if other == ref {
+			other = solutions[1]
+		}
+		var best Candidate
+		bestCount := uniqueCountLimit + 1
+		for i := range ref {
+			c := Cell{i / DIM, i % DIM}
+			if p.board[c.Row][c.Col] != 0 || ref[i] == other[i] {
+				continue
+			}
+			val := int(ref[i] - '0')
+			p.MakeMove(c.Row, c.Col, val)
+			if n := p.CountSolutions(uniqueCountLimit); n < bestCount {
+				best, bestCount = Candidate{c, val}, n
+			}
+			p.UnmakeMove(c.Row, c.Col)
+		}
+		p.MakeMove(best.Row, best.Col, best.Value)
+		clues = append(clues, best)
+	}
+}
@@ -0,0 +1,597 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// VariantSpec is the description file format for variant puzzles: a JSON
+// object holding the givens along with the constraints added to the classic
+// rules.  Cells are named in r1c1 notation.
+//
+//	{
+//	  "grid": "000000010400000000020000000000050407008000300001090000300400200050100000000806000",
+//	  "diagonals": true,
+//	  "cages": [{"sum": 10, "cells": ["r1c1", "r1c2", "r2c1"]}],
+//	  "thermos": [["r9c1", "r8c1", "r7c1"]],
+//	  "dots": [{"color": "white", "cells": ["r5c5", "r5c6"]}],
+//	  "little_killers": [{"sum": 23, "start": "r1c2", "direction": "down-right"}]
+//	}
+type VariantSpec struct {
+	// Grid holds the givens as a line of 81 cells, 0 or . for empty
+	Grid string `json:"grid"`
+	// Symbols is an alphabet of 9 symbols which Grid is written with instead
+	// of digits, as in Wordoku
+	Symbols string `json:"symbols,omitempty"`
+	// LatinSquare drops the boxes of the classic rules, leaving only rows
+	// and columns
+	LatinSquare bool `json:"latin_square,omitempty"`
+	// Diagonals requires both main diagonals to hold distinct values, as in
+	// X sudoku
+	Diagonals bool `json:"diagonals,omitempty"`
+	// DisjointGroups requires the cells in the same position of each box to
+	// hold distinct values
+	DisjointGroups bool `json:"disjoint_groups,omitempty"`
+	// Windoku requires the four extra boxes between the boxes of the grid,
+	// with top left cells r2c2, r2c6, r6c2 and r6c6, to hold distinct values
+	Windoku bool `json:"windoku,omitempty"`
+	// AntiKnight requires cells a knight's move apart to hold different
+	// values
+	AntiKnight bool `json:"anti_knight,omitempty"`
+	// Cages are killer cages, whose cells hold distinct values adding up to
+	// the sum
+	Cages []CageSpec `json:"cages,omitempty"`
+	// Thermos are thermometers listed bulb first, values strictly increase
+	// away from the bulb
+	Thermos [][]string `json:"thermos,omitempty"`
+	// Dots are Kropki dots between two orthogonally adjacent cells
+	Dots []DotSpec `json:"dots,omitempty"`
+	// Renbans are lines of touching cells holding a set of consecutive
+	// values, in any order
+	Renbans [][]string `json:"renbans,omitempty"`
+	// Whispers are German whisper lines of touching cells, where neighbors
+	// on the line differ by at least 5
+	Whispers [][]string `json:"whispers,omitempty"`
+	// KenKen are arithmetic cages, whose values combine to the target with
+	// the operation, as in KenKen and Calcudoku.  Values may repeat unless
+	// the other rules forbid it.
+	KenKen []KenKenSpec `json:"kenken,omitempty"`
+	// Palindromes are lines of touching cells which read the same from
+	// either end
+	Palindromes [][]string `json:"palindromes,omitempty"`
+	// Arrows are circles holding the sum of the cells along their arrow
+	Arrows []ArrowSpec `json:"arrows,omitempty"`
+	// Quadruples are clues at the corner shared by four cells, listing
+	// values which the four must contain
+	Quadruples []QuadrupleSpec `json:"quadruples,omitempty"`
+	// Even and Odd list the shaded cells which must hold an even or an odd
+	// value
+	Even []string `json:"even,omitempty"`
+	Odd  []string `json:"odd,omitempty"`
+	// LittleKillers are sums along diagonals, given outside the grid
+	LittleKillers []LittleKillerSpec `json:"little_killers,omitempty"`
+}
+
+// CageSpec describes a killer cage
+type CageSpec struct {
+	Sum   int      `json:"sum"`
+	Cells []string `json:"cells"`
+}
+
+// KenKenSpec describes an arithmetic cage.  Op is one of + - * or /, where
+// - and / need exactly 2 cells and give the difference or quotient of the
+// larger value by the smaller.  A cage of one cell may leave Op empty, its
+// value is the target.
+type KenKenSpec struct {
+	Op     string   `json:"op"`
+	Target int      `json:"target"`
+	Cells  []string `json:"cells"`
+}
+
+// DotSpec describes a Kropki dot, white for consecutive values or black for
+// values where one is double the other
+type DotSpec struct {
+	Color string   `json:"color"`
+	Cells []string `json:"cells"`
+}
+
+// ArrowSpec describes an arrow: the value in the Circle cell is the sum of
+// the values along the Arrow, a line of touching cells starting next to the
+// circle.  Values on the arrow may repeat unless the classic rules forbid it.
+type ArrowSpec struct {
+	Circle string   `json:"circle"`
+	Arrow  []string `json:"arrow"`
+}
+
+// QuadrupleSpec describes a quadruple clue at the bottom right corner of
+// Cell: the cell, its neighbors to the right and below, and the cell
+// diagonally between them contain each of Values, which may repeat a value
+// twice
+type QuadrupleSpec struct {
+	Cell   string `json:"cell"`
+	Values []int  `json:"values"`
+}
+
+// LittleKillerSpec describes a little killer clue: the values along the
+// diagonal starting at the edge cell Start and heading in Direction, one of
+// down-right, down-left, up-right or up-left, add up to Sum.  Values may
+// repeat unless the classic rules forbid it.
+type LittleKillerSpec struct {
+	Sum       int    `json:"sum"`
+	Start     string `json:"start"`
+	Direction string `json:"direction"`
+}
+
+// diagonalSteps maps the directions of a little killer to their step
+var diagonalSteps = map[string]Cell{
+	"down-right": {1, 1},
+	"down-left":  {1, -1},
+	"up-right":   {-1, 1},
+	"up-left":    {-1, -1},
+}
+
+// ParseVariantSpec reads a description file, rejecting unknown fields so that
+// a misspelt constraint isn't silently ignored
+func ParseVariantSpec(r io.Reader) (*VariantSpec, error) {
+	var spec VariantSpec
+	if err := decodeStrict(r, &spec); err != nil {
+		return nil, err
+	}
+	return &spec, nil
+}
+
+// decodeStrict decodes a JSON description file into v, rejecting unknown
+// fields.  Syntax errors give the line and column.
+func decodeStrict(r io.Reader, v interface{}) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		var offset int64 = -1
+		var syntax *json.SyntaxError
+		var typ *json.UnmarshalTypeError
+		if errors.As(err, &syntax) {
+			offset = syntax.Offset
+		} else if errors.As(err, &typ) {
+			offset = typ.Offset
+		}
+		if offset >= 0 {
+			before := data[:min(offset, int64(len(data)))]
+			line := bytes.Count(before, []byte("\n")) + 1
+			col := len(before) - bytes.LastIndexByte(before, '\n') - 1
+			return fmt.Errorf("Invalid description at line %v, column %v: %v", line, col, err)
+		}
+		return fmt.Errorf("Invalid description: %v", err)
+	}
+	return nil
+}
+
+// readVariantSpec reads the description file fname
+func readVariantSpec(fname string) (*VariantSpec, error) {
+	file, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	spec, err := ParseVariantSpec(file)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", fname, err)
+	}
+	return spec, nil
+}
+
+// disjointGroups returns the groups of cells in the same position of each box
+func disjointGroups() [][]Cell {
+	result := make([][]Cell, DIM)
+	for b := 0; b < DIM; b++ {
+		for pos := range result {
+			result[pos] = append(result[pos], Cell{b/3*3 + pos/3, b%3*3 + pos%3})
+		}
+	}
+	return result
+}
+
+// windokuGroups returns the four extra boxes of windoku
+func windokuGroups() [][]Cell {
+	var result [][]Cell
+	for _, top := range []Cell{{1, 1}, {1, 5}, {5, 1}, {5, 5}} {
+		var box []Cell
+		for i := 0; i < DIM; i++ {
+			box = append(box, Cell{top.Row + i/3, top.Col + i%3})
+		}
+		result = append(result, box)
+	}
+	return result
+}
+
+// knightPairs returns every pair of cells a knight's move apart, as groups of
+// two
+func knightPairs() [][]Cell {
+	var result [][]Cell
+	for ri := 0; ri < DIM; ri++ {
+		for ci := 0; ci < DIM; ci++ {
+			for _, m := range []Cell{{1, 2}, {2, 1}, {1, -2}, {2, -1}} {
+				if onBoard(ri+m.Row, ci+m.Col) {
+					result = append(result, []Cell{{ri, ci}, {ri + m.Row, ci + m.Col}})
+				}
+			}
+		}
+	}
+	return result
+}
+
+// Problem is a mistake in a description file, located by its Path in the
+// file such as cages[2].cells[0]
+type Problem struct {
+	Path    string
+	Message string
+}
+
+func (p Problem) Error() string {
+	return p.Path + ": " + p.Message
+}
+
+// variantBuild collects the pieces of a puzzle while its description is
+// checked, along with the problems found
+type variantBuild struct {
+	game   *Game
+	groups [][]Cell
+	rules  []Constraint
+	// paths locates each of rules in the description
+	paths    []string
+	problems []Problem
+}
+
+func (b *variantBuild) problem(path, format string, args ...interface{}) {
+	b.problems = append(b.problems, Problem{path, fmt.Sprintf(format, args...)})
+}
+
+func (b *variantBuild) add(path string, rule Constraint) {
+	b.rules = append(b.rules, rule)
+	b.paths = append(b.paths, path)
+}
+
+// cells parses the list of cells at path, false if any is invalid or
+// repeated
+func (b *variantBuild) cells(path string, names []string) ([]Cell, bool) {
+	cells := make([]Cell, len(names))
+	ok := true
+	for i, name := range names {
+		c, err := ParseCell(name)
+		if err != nil {
+			b.problem(fmt.Sprintf("%v[%v]", path, i), "%v", err)
+			ok = false
+			continue
+		}
+		for j := 0; j < i; j++ {
+			if cells[j] == c {
+				b.problem(fmt.Sprintf("%v[%v]", path, i), "%v is repeated", c)
+				ok = false
+			}
+		}
+		cells[i] = c
+	}
+	return cells, ok
+}
+
+// line parses the cells of the line at path, which must have at least 2 and
+// each touch the last, orthogonally or diagonally
+func (b *variantBuild) line(path string, names []string) ([]Cell, bool) {
+	cells, ok := b.cells(path, names)
+	if len(cells) < 2 {
+		b.problem(path, "line needs at least 2 cells, found %v", len(cells))
+		return nil, false
+	}
+	for j := 1; ok && j < len(cells); j++ {
+		if !adjacent(cells[j-1], cells[j], true) {
+			b.problem(fmt.Sprintf("%v[%v]", path, j), "%v doesn't touch %v", cells[j], cells[j-1])
+			ok = false
+		}
+	}
+	return cells, ok
+}
+
+// adjacent is true if the cells touch, orthogonally or diagonally when
+// diagonal is set
+func adjacent(a, b Cell, diagonal bool) bool {
+	dr, dc := abs(a.Row-b.Row), abs(a.Col-b.Col)
+	if diagonal {
+		return max(dr, dc) == 1
+	}
+	return dr+dc == 1
+}
+
+// onBoard is true if row and col, 0 based, are on the board
+func onBoard(row, col int) bool {
+	return checkCell(row, col) == nil
+}
+
+// diagonalGroups returns the two main diagonals
+func diagonalGroups() [][]Cell {
+	var down, up []Cell
+	for i := 0; i < DIM; i++ {
+		down = append(down, Cell{i, i})
+		up = append(up, Cell{DIM - 1 - i, i})
+	}
+	return [][]Cell{down, up}
+}
+
+// build checks the description, collecting every problem rather than
+// stopping at the first
+func (s *VariantSpec) build() *variantBuild {
+	b := &variantBuild{}
+	grid := s.Grid
+	var sym *Symbols
+	if s.Symbols != "" {
+		var err error
+		if sym, err = ParseSymbols(s.Symbols); err != nil {
+			b.problem("symbols", "%v", err)
+		} else {
+			grid = sym.Decode(grid)
+		}
+	}
+	g, err := ParseLine(grid)
+	if err != nil {
+		b.problem("grid", "%v", err)
+	} else {
+		g.symbols = sym
+	}
+	b.game = g
+	b.groups = classicGroups(s.LatinSquare)
+	if s.Diagonals {
+		b.groups = append(b.groups, diagonalGroups()...)
+	}
+	if s.DisjointGroups {
+		b.groups = append(b.groups, disjointGroups()...)
+	}
+	if s.Windoku {
+		b.groups = append(b.groups, windokuGroups()...)
+	}
+	if s.AntiKnight {
+		b.groups = append(b.groups, knightPairs()...)
+	}
+	for i, spec := range s.Cages {
+		path := fmt.Sprintf("cages[%v]", i)
+		cells, ok := b.cells(path+".cells", spec.Cells)
+		if len(cells) == 0 {
+			b.problem(path, "cage has no cells")
+			ok = false
+		}
+		if spec.Sum <= 0 {
+			b.problem(path+".sum", "expected a positive sum, found %v", spec.Sum)
+			ok = false
+		}
+		if ok {
+			b.add(path, &Cage{Sum: spec.Sum, cells: cells})
+		}
+	}
+	for i, names := range s.Thermos {
+		path := fmt.Sprintf("thermos[%v]", i)
+		if cells, ok := b.line(path, names); ok {
+			b.add(path, &Thermo{cells: cells})
+		}
+	}
+	for i, names := range s.Renbans {
+		path := fmt.Sprintf("renbans[%v]", i)
+		if cells, ok := b.line(path, names); ok {
+			b.add(path, &Renban{cells: cells})
+		}
+	}
+	for i, names := range s.Whispers {
+		path := fmt.Sprintf("whispers[%v]", i)
+		if cells, ok := b.line(path, names); ok {
+			b.add(path, &Whisper{cells: cells})
+		}
+	}
+	for i, spec := range s.KenKen {
+		path := fmt.Sprintf("kenken[%v]", i)
+		cells, ok := b.cells(path+".cells", spec.Cells)
+		if len(cells) == 0 {
+			b.problem(path, "cage has no cells")
+			continue
+		}
+		op := spec.Op
+		if op == "" && len(cells) == 1 {
+			op = "+"
+		}
+		switch op {
+		case "+", "*":
+		case "-", "/":
+			if len(cells) != 2 {
+				b.problem(path, "%v needs 2 cells, found %v", op, len(cells))
+				ok = false
+			}
+		default:
+			b.problem(path+".op", "unknown operation %q, expected + - * or /", spec.Op)
+			ok = false
+		}
+		if spec.Target <= 0 {
+			b.problem(path+".target", "expected a positive target, found %v", spec.Target)
+			ok = false
+		}
+		if ok {
+			b.add(path, &Arithmetic{Op: op[0], Target: spec.Target, cells: cells})
+		}
+	}
+	for i, names := range s.Palindromes {
+		path := fmt.Sprintf("palindromes[%v]", i)
+		if cells, ok := b.line(path, names); ok {
+			b.add(path, &Palindrome{cells: cells})
+		}
+	}
+	for i, spec := range s.Dots {
+		path := fmt.Sprintf("dots[%v]", i)
+		cells, ok := b.cells(path+".cells", spec.Cells)
+		if len(cells) != 2 {
+			b.problem(path, "dot needs 2 cells, found %v", len(cells))
+			ok = false
+		} else if ok && !adjacent(cells[0], cells[1], false) {
+			b.problem(path, "%v and %v aren't orthogonally adjacent", cells[0], cells[1])
+			ok = false
+		}
+		var black bool
+		switch strings.ToLower(spec.Color) {
+		case "white":
+		case "black":
+			black = true
+		default:
+			b.problem(path+".color", "unknown color %q, expected white or black", spec.Color)
+			ok = false
+		}
+		if ok {
+			b.add(path, &Dot{Black: black, cells: cells})
+		}
+	}
+	for i, spec := range s.Arrows {
+		path := fmt.Sprintf("arrows[%v]", i)
+		circle, err := ParseCell(spec.Circle)
+		if err != nil {
+			b.problem(path+".circle", "%v", err)
+			continue
+		}
+		arrow, ok := b.cells(path+".arrow", spec.Arrow)
+		if len(arrow) == 0 {
+			b.problem(path+".arrow", "arrow has no cells")
+			continue
+		}
+		cells := append([]Cell{circle}, arrow...)
+		for j := 1; ok && j < len(cells); j++ {
+			if cells[j] == circle {
+				b.problem(fmt.Sprintf("%v.arrow[%v]", path, j-1), "%v is the circle", circle)
+				ok = false
+			} else if !adjacent(cells[j-1], cells[j], true) {
+				b.problem(fmt.Sprintf("%v.arrow[%v]", path, j-1), "%v doesn't touch %v", cells[j], cells[j-1])
+				ok = false
+			}
+		}
+		if ok {
+			b.add(path, &Arrow{cells: cells})
+		}
+	}
+	for i, spec := range s.Quadruples {
+		path := fmt.Sprintf("quadruples[%v]", i)
+		c, err := ParseCell(spec.Cell)
+		if err != nil {
+			b.problem(path+".cell", "%v", err)
+			continue
+		}
+		if c.Row == DIM-1 || c.Col == DIM-1 {
+			b.problem(path+".cell", "%v has no corner below and to the right on the board", c)
+			continue
+		}
+		if n := len(spec.Values); n == 0 || 4 < n {
+			b.problem(path+".values", "expected 1 to 4 values, found %v", n)
+			continue
+		}
+		q := &Quadruple{cells: []Cell{c, {c.Row, c.Col + 1}, {c.Row + 1, c.Col}, {c.Row + 1, c.Col + 1}}}
+		ok := true
+		for j, val := range spec.Values {
+			if val < 1 || DIM < val {
+				b.problem(fmt.Sprintf("%v.values[%v]", path, j), "expected a value from 1 to %v, found %v", DIM, val)
+				ok = false
+			} else if q.needs[val]++; q.needs[val] > 2 {
+				b.problem(fmt.Sprintf("%v.values[%v]", path, j), "%v can appear at most twice in 4 cells", val)
+				ok = false
+			}
+		}
+		if ok {
+			b.add(path, q)
+		}
+	}
+	if cells, ok := b.cells("even", s.Even); ok && len(cells) > 0 {
+		b.add("even", &Parity{cells: cells, values: evenValues})
+	}
+	if cells, ok := b.cells("odd", s.Odd); ok && len(cells) > 0 {
+		b.add("odd", &Parity{cells: cells, values: allValues &^ evenValues})
+	}
+	for i, spec := range s.LittleKillers {
+		path := fmt.Sprintf("little_killers[%v]", i)
+		start, err := ParseCell(spec.Start)
+		if err != nil {
+			b.problem(path+".start", "%v", err)
+			continue
+		}
+		step, ok := diagonalSteps[strings.ToLower(spec.Direction)]
+		if !ok {
+			b.problem(path+".direction", "unknown direction %q, expected down-right, down-left, up-right or up-left",
+				spec.Direction)
+			continue
+		}
+		if onBoard(start.Row-step.Row, start.Col-step.Col) {
+			b.problem(path+".start", "%v isn't on the edge the clue points from", start)
+			continue
+		}
+		var cells []Cell
+		for c := start; onBoard(c.Row, c.Col); c = (Cell{c.Row + step.Row, c.Col + step.Col}) {
+			cells = append(cells, c)
+		}
+		if spec.Sum < len(cells) || DIM*len(cells) < spec.Sum {
+			b.problem(path+".sum", "%v values can't add up to %v, only %v through %v",
+				len(cells), spec.Sum, len(cells), DIM*len(cells))
+			continue
+		}
+		b.add(path, &LittleKiller{Sum: spec.Sum, cells: cells})
+	}
+	return b
+}
+
+// Variant validates the description and builds the puzzle it describes.
+// Errors locate the problem by its path in the description, such as
+// cages[2].cells[0].
+func (s *VariantSpec) Variant() (*Variant, error) {
+	b := s.build()
+	if len(b.problems) > 0 {
+		return nil, b.problems[0]
+	}
+	return NewVariant(b.game, b.groups, b.rules), nil
+}
+
+// variantCommand handles: variant [flags] <description file>, solving a
+// variant puzzle
+func variantCommand(args []string) error {
+	fs := flag.NewFlagSet("variant", flag.ExitOnError)
+	check := fs.Bool("check", false, "warn if the puzzle doesn't have a unique solution")
+	disjoint := fs.Bool("disjoint-groups", false, "add the disjoint groups rule, as if the description set it")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("Usage: variant [flags] <description file>")
+	}
+	spec, err := readVariantSpec(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	spec.DisjointGroups = spec.DisjointGroups || *disjoint
+	v, err := spec.Variant()
+	if err != nil {
+		return fmt.Errorf("%v: %v", fs.Arg(0), err)
+	}
+	fmt.Println("Starting configuration:")
+	fmt.Println(v.Game)
+	if *check {
+		switch v.Clone().CountSolutions(2) {
+		case 0:
+			fmt.Println("\nWarning: puzzle has no solution")
+		case 2:
+			fmt.Println("\nWarning: puzzle has multiple solutions")
+		}
+	}
+	grade := v.Grade()
+	var stats Stats
+	solved := v.Solve(&stats)
+	fmt.Printf("\nSolved? %v\nBacktracks: %v\nDifficulty: %v\n\n", solved, stats.Backtracks, grade)
+	fmt.Println("Ending configuration:")
+	fmt.Println(v.Game)
+	if !solved {
+		return fmt.Errorf("Puzzle has no solution under its variant rules")
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"fmt"
+	"math/bits"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// variantRules maps the names accepted by generate -variant to the rule each
+// adds to a description
+var variantRules = map[string]func(s *VariantSpec){
+	"latin":           func(s *VariantSpec) { s.LatinSquare = true },
+	"x":               func(s *VariantSpec) { s.Diagonals = true },
+	"windoku":         func(s *VariantSpec) { s.Windoku = true },
+	"anti-knight":     func(s *VariantSpec) { s.AntiKnight = true },
+	"disjoint-groups": func(s *VariantSpec) { s.DisjointGroups = true },
+}
+
+// variantRuleNames lists the names accepted by generate -variant
+func variantRuleNames() string {
+	var names []string
+	for name := range variantRules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// parseVariantRules returns an empty description with the comma separated
+// rules of list
+func parseVariantRules(list string) (*VariantSpec, error) {
+	spec := &VariantSpec{Grid: strings.Repeat("0", DIM*DIM)}
+	for _, name := range strings.Split(list, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		rule, ok := variantRules[name]
+		if !ok {
+			return nil, fmt.Errorf("Unknown variant %q, expected one of: %v", name, variantRuleNames())
+		}
+		rule(spec)
+	}
+	return spec, nil
+}
+
+// randomFill completes the board with random values under the variant rules,
+// false if it can't be completed
+func (v *Variant) randomFill(rng *rand.Rand) bool {
+	if v.Game.ValidSolution() {
+		return true
+	}
+	c, mask := v.nextEmptyCell()
+	for _, i := range rng.Perm(DIM) {
+		if val := i + 1; mask&(1<<uint(val)) != 0 {
+			v.Game.MakeMove(c.Row, c.Col, val)
+			if v.randomFill(rng) {
+				return true
+			}
+			v.Game.UnmakeMove(c.Row, c.Col)
+		}
+	}
+	return false
+}
+
+// carve removes clues from the solved board in a random order, keeping the
+// cells of each group of sym together, while the solution under the variant
+// rules stays unique and more than target clues remain
+func (v *Variant) carve(rng *rand.Rand, sym symmetry, target int) {
+	g := v.Game
+	for _, i := range rng.Perm(DIM * DIM) {
+		group := sym(Cell{i / DIM, i % DIM})
+		if DIM*DIM-g.remaining-len(group) < target || g.board[group[0].Row][group[0].Col] == 0 {
+			continue
+		}
+		cp := g.Snapshot()
+		for _, c := range group {
+			g.UnmakeMove(c.Row, c.Col)
+		}
+		if v.CountSolutions(2) != 1 {
+			g.Restore(cp)
+		}
+	}
+	g.MarkGivens()
+	g.backtracks = 0
+}
+
+// GenerateVariant returns a description of a new puzzle with a unique
+// solution under the rules of spec, whose grid is ignored, along with its
+// grade.  sym and clues are as for generatePuzzle, and the clue target may
+// not be reached.  Like generatePuzzle it keeps the puzzle with the fewest
+// clues from up to generateAttempts grids, stopping early once it has no
+// more than clues.  Searching variant rules is slower, so with clues 0 a
+// single grid is carved.
+func GenerateVariant(rng *rand.Rand, spec *VariantSpec, sym symmetry, clues int) (*VariantSpec, Difficulty, error) {
+	rules := *spec
+	rules.Grid = strings.Repeat("0", DIM*DIM)
+	var best *Variant
+	for i := 0; i < generateAttempts; i++ {
+		v, err := rules.Variant()
+		if err != nil {
+			return nil, 0, err
+		}
+		if !v.randomFill(rng) {
+			return nil, 0, fmt.Errorf("No grid satisfies the variant rules")
+		}
+		v.carve(rng, sym, clues)
+		if best == nil || v.Game.remaining > best.Game.remaining {
+			best = v
+		}
+		if DIM*DIM-best.Game.remaining <= clues || clues == 0 {
+			break
+		}
+	}
+	rules.Grid = best.Game.Line()
+	return &rules, best.Grade(), nil
+}
+
+// Grade solves a copy of the puzzle with pencil marks, the way a human would,
+// using naked and hidden singles and locked candidates over every group of
+// DIM cells, including the extra groups, with the candidates of each cell
+// narrowed by the constraints.  The grade is the hardest technique needed,
+// or RequiresGuessing if they aren't enough.
+func (v *Variant) Grade() Difficulty {
+	w := v.Clone()
+	g := w.Game
+	var full [][]Cell
+	for _, group := range w.groups {
+		if len(group) == DIM {
+			full = append(full, group)
+		}
+	}
+	var cands [DIM][DIM]uint16
+	for ri := range cands {
+		for ci := range cands[ri] {
+			cands[ri][ci] = allValues
+		}
+	}
+	hardest := Easy
+	for !g.ValidSolution() {
+		for ri, cols := range g.board {
+			for ci, val := range cols {
+				if val == 0 {
+					cands[ri][ci] &= w.candidates(Cell{ri, ci})
+				}
+			}
+		}
+		if placeSingle(g, &cands, full) {
+			continue
+		}
+		if eliminateLocked(g, &cands, full) {
+			hardest = max(hardest, Medium)
+			continue
+		}
+		return RequiresGuessing
+	}
+	return hardest
+}
+
+// placeSingle fills a cell with only one candidate, or the only place left
+// for a value in a group, false if there is none
+func placeSingle(g *Game, cands *[DIM][DIM]uint16, groups [][]Cell) bool {
+	for ri, cols := range g.board {
+		for ci, val := range cols {
+			if val == 0 && bits.OnesCount16(cands[ri][ci]) == 1 {
+				g.MakeMove(ri, ci, bits.TrailingZeros16(cands[ri][ci]))
+				return true
+			}
+		}
+	}
+	for _, group := range groups {
+		for val := 1; val <= DIM; val++ {
+			var only []Cell
+			for _, c := range group {
+				if g.board[c.Row][c.Col] == 0 && cands[c.Row][c.Col]&(1<<uint(val)) != 0 {
+					only = append(only, c)
+				}
+			}
+			if len(only) == 1 {
+				g.MakeMove(only[0].Row, only[0].Col, val)
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// eliminateLocked finds a value whose candidates in one group all lie in
+// another, and removes it from the rest of the other group, false if nothing
+// was removed
+func eliminateLocked(g *Game, cands *[DIM][DIM]uint16, groups [][]Cell) bool {
+	members := make([][DIM][DIM]bool, len(groups))
+	for i, group := range groups {
+		for _, c := range group {
+			members[i][c.Row][c.Col] = true
+		}
+	}
+	for i, group := range groups {
+		for val := 1; val <= DIM; val++ {
+			bit := uint16(1) << uint(val)
+			var cells []Cell
+			for _, c := range group {
+				if g.board[c.Row][c.Col] == 0 && cands[c.Row][c.Col]&bit != 0 {
+					cells = append(cells, c)
+				}
+			}
+			if len(cells) < 2 {
+				continue
+			}
+			for j, other := range groups {
+				inside := j != i
+				for _, c := range cells {
+					inside = inside && members[j][c.Row][c.Col]
+				}
+				if !inside {
+					continue
+				}
+				removed := false
+				for _, c := range other {
+					if !members[i][c.Row][c.Col] && cands[c.Row][c.Col]&bit != 0 {
+						cands[c.Row][c.Col] &^= bit
+						removed = true
+					}
+				}
+				if removed {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// verifiers are the independent solvers whose answers Verify compares
+var verifiers = []string{"backtrack", "propagate"}
+
+// Verify solves copies of the board with each of the verifiers, returning an
+// error if they disagree on whether it can be solved, on whether the solution
+// is unique, or on the solution of a unique puzzle.  Not part of solving, but
+// used to catch bugs in the solvers, so a puzzle whose givens conflict is
+// rejected before any solver is blamed.
+func Verify(g *Game) error {
+	if v := Violations(g, false); len(v) > 0 {
+		return fmt.Errorf("Puzzle givens conflict: %v and %v both hold %v in %v", v[0].Cell, v[0].Peer,
+			v[0].Digit, v[0].Unit)
+	}
+	solutions := make([]*Game, len(verifiers))
+	for i, name := range verifiers {
+		solutions[i] = g.Clone()
+		var stats Stats
+		solved := solvers[name].Solve(solutions[i], &stats)
+		if !solved {
+			solutions[i] = nil
+		} else if len(invalidCells(*solutions[i])) > 0 {
+			return fmt.Errorf("Verify failed: %v returned an invalid solution %v",
+				name, solutions[i].Line())
+		}
+		if (solutions[i] == nil) != (solutions[0] == nil) {
+			return fmt.Errorf("Verify failed: %v solved? %v, but %v solved? %v",
+				verifiers[0], solutions[0] != nil, name, solved)
+		}
+	}
+
+	backtrackCount := g.CountSolutions(2)
+	propagateCount := 0
+	if d, ok := newDomains(g); ok {
+		propagateCount = propagatingCount(d, 2)
+	}
+	if backtrackCount != propagateCount {
+		return fmt.Errorf("Verify failed: backtrack counts %v, but propagate counts %v solutions (limit 2)",
+			backtrackCount, propagateCount)
+	}
+	if backtrackCount != 1 {
+		// Solvers may legitimately find different solutions
+		return nil
+	}
+	for i, name := range verifiers {
+		if !solutions[i].Equal(solutions[0]) {
+			return fmt.Errorf("Verify failed: unique puzzle solved as %v by %v, but %v by %v",
+				solutions[0].Line(), verifiers[0], solutions[i].Line(), name)
+		}
+	}
+	return nil
+}
+
+// propagatingCount counts the solutions of the domains up to limit, searching
+// as propagatingSearch does
+func propagatingCount(d *Domains, limit int) (count int) {
+	best, min := Cell{-1, -1}, DIM+1
+	for ri := range d {
+		for ci := range d[ri] {
+			if n := bits.OnesCount16(d[ri][ci]); n > 1 && n < min {
+				best, min = Cell{ri, ci}, n
+			}
+		}
+	}
+	if min == DIM+1 {
+		return 1
+	}
+	for val := 1; val <= DIM && count < limit; val++ {
+		if d[best.Row][best.Col]&(1<<uint(val)) == 0 {
+			continue
+		}
+		guess := *d
+		guess[best.Row][best.Col] = 1 << uint(val)
+		if _, ok := guess.propagate([]Cell{best}); ok {
+			count += propagatingCount(&guess, limit-count)
+		}
+	}
+	return count
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Violation is a rule broken by a board, for tools to highlight
+type Violation struct {
+	// Cell is in r1c2 notation
+	Cell string `json:"cell"`
+	// Digit is the value of the cell, 0 if it is empty
+	Digit int `json:"digit"`
+	// Peer is the cell in the same unit holding the same digit, empty for an
+	// empty cell
+	Peer string `json:"peer,omitempty"`
+	// Constraint is the kind of unit broken, row, column or box, or "empty"
+	// for a cell left empty in a board meant to be solved
+	Constraint string `json:"constraint"`
+	// Unit names the unit, such as "box 4"
+	Unit string `json:"unit,omitempty"`
+}
+
+// Violations lists every pair of cells holding the same digit in a unit,
+// once for each unit they share, in board order.  If complete is true each
+// empty cell is a violation too.
+func Violations(g *Game, complete bool) []Violation {
+	result := []Violation{}
+	for ri, row := range g.board {
+		for ci, val := range row {
+			c := Cell{ri, ci}
+			if val == 0 {
+				if complete {
+					result = append(result, Violation{Cell: c.String(), Constraint: "empty"})
+				}
+				continue
+			}
+			for ui, unit := range units {
+				if !unitHas(unit, c) {
+					continue
+				}
+				for _, p := range unit {
+					// Each pair is reported once, from the first cell
+					if p.Row*DIM+p.Col <= ri*DIM+ci || g.board[p.Row][p.Col] != val {
+						continue
+					}
+					name := unitName(ui)
+					result = append(result, Violation{
+						Cell:       c.String(),
+						Digit:      val,
+						Peer:       p.String(),
+						Constraint: strings.Fields(name)[0],
+						Unit:       name,
+					})
+				}
+			}
+		}
+	}
+	return result
+}
+
+// unitHas is true if c is one of the cells of unit
+func unitHas(unit []Cell, c Cell) bool {
+	for _, u := range unit {
+		if u == c {
+			return true
+		}
+	}
+	return false
+}
+
+// JSONOutput is the document written by -o json
+type JSONOutput struct {
+	Puzzle   string `json:"puzzle"`
+	Solution string `json:"solution"`
+	Solved   bool   `json:"solved"`
+	// Checksum is the Checksum of the solution, empty if unsolved
+	Checksum   string    `json:"checksum,omitempty"`
+	Difficulty string    `json:"difficulty"`
+	Stats      Stats     `json:"stats"`
+	Metadata   *Metadata `json:"metadata,omitempty"`
+	// Violations are the rules broken by the ending board, such as givens
+	// which conflict or cells the solver couldn't fill
+	Violations []Violation `json:"violations"`
+	// Warnings are the problems found by -check, if set
+	Warnings []string `json:"warnings,omitempty"`
+	// Conflicting and Remove are the givens of the Conflict of a puzzle
+	// without a solution, formatted as r1c2=5
+	Conflicting []string `json:"conflicting,omitempty"`
+	Remove      []string `json:"remove,omitempty"`
+}
+
+// writeJSONOutput solves the puzzle, writing the result and the violations
+// of the ending board as a JSONOutput.  If check is true the puzzle is
+// checked as by -check first.
+func writeJSONOutput(w io.Writer, p *Puzzle, algo string, check bool) error {
+	solver, ok := solvers[algo]
+	if !ok {
+		return fmt.Errorf("Unknown algorithm %q", algo)
+	}
+	out := JSONOutput{Puzzle: p.Game.Line()}
+	if !p.Metadata.IsZero() {
+		out.Metadata = &p.Metadata
+	}
+	if check {
+		out.Warnings = puzzleWarnings(p.Game)
+	}
+	// Conflicting givens would be hidden by a solver which leaves the board
+	// half filled
+	if len(Violations(p.Game, false)) == 0 {
+		r := solvePuzzle(p.Game, solver, 0)
+		out.Solved, out.Checksum, out.Difficulty, out.Stats = r.Solved, r.Checksum, r.Difficulty, r.Stats
+	}
+	if c := AnalyzeConflict(boardOf(givensOf(p.Game))); !out.Solved && c != nil {
+		out.Conflicting = strings.Fields(givenList(c.Core))
+		out.Remove = strings.Fields(givenList(c.Remove))
+	}
+	out.Solution = p.Game.Line()
+	out.Violations = Violations(p.Game, true)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
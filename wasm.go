@@ -0,0 +1,95 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"syscall/js"
+)
+
+// The WebAssembly build exposes the solver to JavaScript as a global sudoku
+// object rather than running the command line interface.  Boards are passed
+// in the 81 digit line format, and failures are reported as an object with
+// an error property.
+func init() {
+	platformMain = wasmMain
+}
+
+func wasmMain() {
+	js.Global().Set("sudoku", js.ValueOf(map[string]interface{}{
+		"solve":    js.FuncOf(jsSolve),
+		"generate": js.FuncOf(jsGenerate),
+		"hint":     js.FuncOf(jsHint),
+	}))
+	// Keep the exported functions alive
+	select {}
+}
+
+func jsError(err error) interface{} {
+	return map[string]interface{}{"error": err.Error()}
+}
+
+// jsSolve handles sudoku.solve(puzzle, algo), algo is optional
+func jsSolve(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return map[string]interface{}{"error": "Usage: solve(puzzle, algo)"}
+	}
+	g, err := ParseLine(args[0].String())
+	if err != nil {
+		return jsError(err)
+	}
+	params := rpcPuzzleParams{}
+	if len(args) > 1 && args[1].Type() == js.TypeString {
+		params.Algo = args[1].String()
+	}
+	result, err := rpcSolve(params, g)
+	if err != nil {
+		return jsError(err)
+	}
+	r := result.(rpcSolveResult)
+	return map[string]interface{}{"solved": r.Solved, "solution": r.Solution, "backtracks": r.Backtracks}
+}
+
+// jsGenerate handles sudoku.generate({seed, symmetry, clues}), each option
+// may be left out
+func jsGenerate(this js.Value, args []js.Value) interface{} {
+	seed, symmetry, clues := int64(js.Global().Get("Date").Call("now").Int()), "none", 0
+	if len(args) > 0 && args[0].Type() == js.TypeObject {
+		opts := args[0]
+		if v := opts.Get("seed"); v.Type() == js.TypeNumber {
+			seed = int64(v.Int())
+		}
+		if v := opts.Get("symmetry"); v.Type() == js.TypeString {
+			symmetry = v.String()
+		}
+		if v := opts.Get("clues"); v.Type() == js.TypeNumber {
+			clues = v.Int()
+		}
+	}
+	g, err := Generate(seed, symmetry, clues)
+	if err != nil {
+		return jsError(err)
+	}
+	return map[string]interface{}{"puzzle": g.Line(), "seed": seed}
+}
+
+// jsHint handles sudoku.hint(puzzle), returning null if the strategy engine
+// is stuck
+func jsHint(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return map[string]interface{}{"error": "Usage: hint(puzzle)"}
+	}
+	g, err := ParseLine(args[0].String())
+	if err != nil {
+		return jsError(err)
+	}
+	step := Hint(g)
+	if step == nil {
+		return nil
+	}
+	hint := map[string]interface{}{"technique": step.Technique, "description": step.String()}
+	if step.Value != 0 {
+		hint["cell"] = step.Cell.String()
+		hint["value"] = step.Value
+	}
+	return hint
+}
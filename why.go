@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Peer is a filled cell ruling out its value for another cell, Unit names the
+// kind of unit they share: row, column or box
+type Peer struct {
+	Cell
+	Unit string
+}
+
+// CellReport explains why a cell has the candidates it does
+type CellReport struct {
+	Cell Cell
+	// Value is non-zero if the cell is already filled
+	Value      int
+	Candidates []int
+	// Eliminated maps each ruled out value to the peers holding it
+	Eliminated map[int][]Peer
+	// Resolution is the step of the strategy engine which fills the cell, and
+	// StepsBefore the number of steps it needs first.  Resolution is nil if
+	// the engine gets stuck before reaching the cell.
+	Resolution  *Step
+	StepsBefore int
+}
+
+// ParseCell reads a cell in r1c1 notation, which is 1 based
+func ParseCell(s string) (Cell, error) {
+	var row, col int
+	var extra string
+	n, _ := fmt.Sscanf(strings.ToLower(s), "r%dc%d%s", &row, &col, &extra)
+	if n != 2 || row < 1 || DIM < row || col < 1 || DIM < col {
+		return Cell{}, fmt.Errorf("Invalid cell %q, expected r1c1 through r%vc%v", s, DIM, DIM)
+	}
+	return Cell{row - 1, col - 1}, nil
+}
+
+// Why reports which peers rule out each value of cell c, and which technique
+// of the strategy engine would eventually fill it.  The board is unchanged.
+func Why(g *Game, c Cell) *CellReport {
+	r := &CellReport{Cell: c, Value: g.board[c.Row][c.Col], Eliminated: make(map[int][]Peer)}
+	if r.Value != 0 {
+		return r
+	}
+	for val, ok := range g.CellCandidates(c.Row, c.Col) {
+		if ok {
+			r.Candidates = append(r.Candidates, val)
+		}
+	}
+	for _, p := range peers[c.Row][c.Col] {
+		val := g.board[p.Row][p.Col]
+		if val == 0 {
+			continue
+		}
+		unit := "box"
+		if p.Row == c.Row {
+			unit = "row"
+		} else if p.Col == c.Col {
+			unit = "column"
+		}
+		r.Eliminated[val] = append(r.Eliminated[val], Peer{p, unit})
+	}
+
+	s := NewStrategist(g).clone()
+	for s.Advance() {
+		step := s.Steps[len(s.Steps)-1]
+		if step.Value != 0 && step.Cell == c {
+			r.Resolution = &step
+			r.StepsBefore = len(s.Steps) - 1
+			break
+		}
+	}
+	return r
+}
+
+// String formats the report for human consumption
+func (r *CellReport) String() string {
+	if r.Value != 0 {
+		return fmt.Sprintf("%v is filled with %v", r.Cell, r.Value)
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%v candidates: %v\n", r.Cell, strings.Trim(fmt.Sprint(r.Candidates), "[]"))
+	for val := 1; val <= DIM; val++ {
+		peers := r.Eliminated[val]
+		if len(peers) == 0 {
+			continue
+		}
+		names := make([]string, len(peers))
+		for i, p := range peers {
+			names[i] = fmt.Sprintf("%v (%v)", p.Cell, p.Unit)
+		}
+		fmt.Fprintf(&sb, "  %v ruled out by %v\n", val, strings.Join(names, ", "))
+	}
+	if r.Resolution == nil {
+		sb.WriteString("No technique resolves this cell, guessing is required")
+	} else {
+		fmt.Fprintf(&sb, "Resolved after %v other steps by %v", r.StepsBefore, *r.Resolution)
+	}
+	return sb.String()
+}
+
+// whyCommand handles: why <cell> <puzzle file>
+func whyCommand(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("Usage: why <cell> <puzzle file>")
+	}
+	c, err := ParseCell(args[0])
+	if err != nil {
+		return err
+	}
+	board, err := readGame(args[1])
+	if err != nil {
+		return err
+	}
+	fmt.Println(Why(board, c))
+	return nil
+}
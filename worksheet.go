@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"strings"
+)
+
+// worksheetItem is one puzzle of a worksheet and its solution
+type worksheetItem struct {
+	number     int
+	puzzle     *Puzzle
+	solution   *Game
+	difficulty string
+}
+
+// worksheetPage is a page of puzzles or of their solutions
+type worksheetPage struct {
+	header, footer string
+	items          []worksheetItem
+	answers        bool
+}
+
+// worksheet lays out puzzles in pages of columns by rows, optionally followed
+// by pages of answers in the same layout
+type worksheet struct {
+	columns, rows int
+	// header and footer are printed on each page, with {page} and {pages}
+	// replaced by the page number and count
+	header, footer string
+	labels         bool
+	answers        bool
+	style          BoardStyle
+}
+
+// paginate splits items into pages of the puzzles, followed by the answer
+// pages if requested
+func (ws *worksheet) paginate(items []worksheetItem) []worksheetPage {
+	perPage := ws.columns * ws.rows
+	var pages []worksheetPage
+	add := func(answers bool) {
+		for i := 0; i < len(items); i += perPage {
+			pages = append(pages, worksheetPage{items: items[i:min(i+perPage, len(items))], answers: answers})
+		}
+	}
+	add(false)
+	if ws.answers {
+		add(true)
+	}
+	for i := range pages {
+		r := strings.NewReplacer("{page}", fmt.Sprint(i+1), "{pages}", fmt.Sprint(len(pages)))
+		pages[i].header, pages[i].footer = r.Replace(ws.header), r.Replace(ws.footer)
+		if pages[i].answers && pages[i].header != "" {
+			pages[i].header += " - Answers"
+		} else if pages[i].answers {
+			pages[i].header = "Answers"
+		}
+	}
+	return pages
+}
+
+// label is the caption of an item, its number followed by its difficulty if
+// labels are on
+func (ws *worksheet) label(item worksheetItem) string {
+	if ws.labels {
+		return fmt.Sprintf("%v. %v", item.number, item.difficulty)
+	}
+	return fmt.Sprintf("%v.", item.number)
+}
+
+// writeHTML writes the worksheet as an HTML page breaking between sheets when
+// printed, which browsers can also save as PDF
+func (ws *worksheet) writeHTML(w io.Writer, items []worksheetItem) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprint(bw, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>Sudoku worksheet</title>\n")
+	fmt.Fprintf(bw, `<style>
+@page { margin: 1.5cm; }
+body { font-family: sans-serif; margin: 0; background: %v; color: %v; }
+.page { break-after: page; }
+.page header, .page footer { text-align: center; margin: 0.5em 0; }
+.sheet { display: grid; grid-template-columns: repeat(%v, 1fr); gap: 1.5em; }
+.sheet svg { width: 100%%; height: auto; }
+.page header, .page footer { font-family: %v; }
+</style>
+</head>
+<body>
+`, htmlColor(ws.style.Background), htmlColor(ws.style.Given), ws.columns, ws.style.Font)
+	for _, page := range ws.paginate(items) {
+		fmt.Fprint(bw, "<section class=\"page\">\n")
+		if page.header != "" {
+			fmt.Fprintf(bw, "<header>%v</header>\n", html.EscapeString(page.header))
+		}
+		fmt.Fprint(bw, "<div class=\"sheet\">\n")
+		for _, item := range page.items {
+			grid := item.puzzle.Game
+			if page.answers {
+				grid = item.solution
+			}
+			writeBoardSVG(bw, item.puzzle.Game, grid, ws.label(item), ws.style)
+		}
+		fmt.Fprint(bw, "</div>\n")
+		if page.footer != "" {
+			fmt.Fprintf(bw, "<footer>%v</footer>\n", html.EscapeString(page.footer))
+		}
+		fmt.Fprint(bw, "</section>\n")
+	}
+	fmt.Fprint(bw, "</body>\n</html>\n")
+	return bw.Flush()
+}
+
+// latexEscape escapes the characters with special meaning to LaTeX
+var latexEscape = strings.NewReplacer(
+	`\`, `\textbackslash{}`, "&", `\&`, "%", `\%`, "$", `\$`, "#", `\#`, "_", `\_`,
+	"{", `\{`, "}", `\}`, "~", `\textasciitilde{}`, "^", `\textasciicircum{}`,
+)
+
+// latexFont returns the preamble loading the font family of a style and the
+// command selecting it.  The generic CSS families are the standard fonts of
+// LaTeX, others are the first of the list loaded with fontspec, which needs
+// xelatex or lualatex.
+func latexFont(font string) (preamble, family string) {
+	first, _, _ := strings.Cut(font, ",")
+	name := strings.Trim(strings.TrimSpace(first), `"'`)
+	switch name {
+	case "", "sans-serif":
+		return "", `\sffamily`
+	case "serif":
+		return "", `\rmfamily`
+	case "monospace":
+		return "", `\ttfamily`
+	}
+	return fmt.Sprintf("\\usepackage{fontspec}\n\\setsansfont{%v}\n", latexEscape.Replace(name)), `\sffamily`
+}
+
+// writeLaTeX writes the worksheet as a LaTeX document drawing each board
+// with TikZ, for pdflatex unless the font needs fontspec
+func (ws *worksheet) writeLaTeX(w io.Writer, items []worksheetItem) error {
+	bw := bufio.NewWriter(w)
+	// Fit the boards to an A4 or letter page within the margins, leaving room
+	// for each label
+	cell := min(17.0/float64(ws.columns*DIM), 22.0/float64(ws.rows)/(DIM+2))
+	preamble, family := latexFont(ws.style.Font)
+	fmt.Fprintf(bw, `\documentclass{article}
+\usepackage[margin=1.5cm]{geometry}
+\usepackage{tikz}
+\usepackage{fancyhdr}
+%v\pagestyle{fancy}
+\renewcommand{\headrulewidth}{0pt}
+\begin{document}
+%v
+`, preamble, family)
+	for _, page := range ws.paginate(items) {
+		fmt.Fprint(bw, "\\fancyhf{}\n")
+		fmt.Fprintf(bw, "\\fancyhead[C]{%v}\n\\fancyfoot[C]{%v}\n", latexEscape.Replace(page.header),
+			latexEscape.Replace(page.footer))
+		for i, item := range page.items {
+			if i > 0 && i%ws.columns == 0 {
+				fmt.Fprint(bw, "\n\\vspace{1em}\n\n")
+			}
+			grid := item.puzzle.Game
+			if page.answers {
+				grid = item.solution
+			}
+			fmt.Fprintf(bw, "\\begin{minipage}[t]{%.3f\\linewidth}\\centering\n%v\\\\[0.3em]\n",
+				0.98/float64(ws.columns), latexEscape.Replace(ws.label(item)))
+			fmt.Fprintf(bw, "\\begin{tikzpicture}[x=%.3fcm,y=%.3fcm]\n", cell, cell)
+			fmt.Fprintf(bw, "\\draw[step=1,line width=%.1fpt] (0,0) grid (%v,%v);\n", 0.4*float64(ws.style.Line), DIM, DIM)
+			fmt.Fprintf(bw, "\\draw[step=3,line width=%.1fpt] (0,0) grid (%v,%v);\n", 0.4*float64(ws.style.BoxLine),
+				DIM, DIM)
+			for ri, row := range grid.board {
+				for ci, val := range row {
+					if val == 0 {
+						continue
+					}
+					text := fmt.Sprint(val)
+					if item.puzzle.Game.board[ri][ci] != 0 {
+						text = `\textbf{` + text + "}"
+					}
+					fmt.Fprintf(bw, "\\node at (%v.5,%v.5) {%v};\n", ci, DIM-1-ri, text)
+				}
+			}
+			fmt.Fprint(bw, "\\end{tikzpicture}\n\\end{minipage}\\hfill\n")
+		}
+		fmt.Fprint(bw, "\\clearpage\n")
+	}
+	fmt.Fprint(bw, "\\end{document}\n")
+	return bw.Flush()
+}
+
+// readWorksheetItems reads the puzzles of fname matching the tag filters,
+// grading and solving each
+func readWorksheetItems(fname, filters string) ([]worksheetItem, error) {
+	file, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var items []worksheetItem
+	dec := NewDecoder(file)
+	for {
+		g, err := dec.Next()
+		if err == io.EOF {
+			return items, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%v line %v: %v", fname, dec.Line(), err)
+		}
+		facets := gradeFacets(g, fmt.Sprintf("%v:%v", fname, dec.Line()), dec.Metadata())
+		if !facets.matchesAll(filters) {
+			continue
+		}
+		solution := g.Clone()
+		var stats Stats
+		if !solvers["backtrack"].Solve(solution, &stats) {
+			return nil, fmt.Errorf("%v line %v: puzzle has no solution", fname, dec.Line())
+		}
+		items = append(items, worksheetItem{
+			number:     len(items) + 1,
+			puzzle:     &Puzzle{Game: g, Metadata: dec.Metadata()},
+			solution:   solution,
+			difficulty: facets.grade,
+		})
+	}
+}
+
+// worksheetCommand handles: worksheet [flags] <puzzle file>, laying out the
+// puzzles of a collection as printable pages
+func worksheetCommand(args []string) error {
+	fs := flag.NewFlagSet("worksheet", flag.ExitOnError)
+	to := fs.String("to", "html", "worksheet `format`: html or latex")
+	ws := &worksheet{}
+	fs.IntVar(&ws.columns, "columns", 2, "puzzles across each page")
+	fs.IntVar(&ws.rows, "rows", 3, "puzzles down each page")
+	fs.StringVar(&ws.header, "header", "Sudoku", "`text` at the top of each page, {page} and {pages} are replaced")
+	fs.StringVar(&ws.footer, "footer", "Page {page} of {pages}", "`text` at the bottom of each page")
+	fs.BoolVar(&ws.labels, "labels", true, "label each puzzle with its difficulty")
+	fs.BoolVar(&ws.answers, "answers", false, "add pages of the solutions at the end")
+	tag := fs.String("tag", "", "only lay out the puzzles matching the comma separated tag `filters`")
+	styleFlags := addBoardStyleFlags(fs)
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("Usage: worksheet [flags] <puzzle file>")
+	}
+	var err error
+	if ws.style, err = styleFlags.Style(); err != nil {
+		return err
+	}
+	if ws.columns < 1 || ws.rows < 1 {
+		return fmt.Errorf("-columns and -rows must be at least 1")
+	}
+	items, err := readWorksheetItems(fs.Arg(0), *tag)
+	if err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return fmt.Errorf("No puzzles to lay out")
+	}
+	switch *to {
+	case "html":
+		return ws.writeHTML(os.Stdout, items)
+	case "latex":
+		return ws.writeLaTeX(os.Stdout, items)
+	}
+	return fmt.Errorf("Unknown worksheet format %q, expected html or latex", *to)
+}